@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// DeviceCode 设备码授权信息
+type DeviceCode struct {
+	DeviceCode      string // 用于轮询令牌的设备码
+	UserCode        string // 用户需要输入的验证码
+	VerificationURL string // 用户访问的验证地址
+	QRCodeURL       string // 验证二维码地址
+	ExpiresIn       int64  // 有效期（秒）
+	Interval        int64  // 轮询间隔（秒）
+}
+
+// RequestDeviceCode 申请设备码，适用于没有浏览器的无头服务器
+func (c *Client) RequestDeviceCode() (*DeviceCode, error) {
+	if c.Endpoint.DeviceURL == "" {
+		return nil, fmt.Errorf("该云盘不支持设备码授权")
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "device_code")
+	params.Set("client_id", c.ClientID)
+	params.Set("scope", c.Endpoint.Scope)
+
+	resp, err := c.httpClient.Get(c.Endpoint.DeviceURL + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result := gjson.ParseBytes(body)
+
+	if resp.StatusCode != http.StatusOK || result.Get("device_code").String() == "" {
+		return nil, tokenError(result)
+	}
+
+	dc := &DeviceCode{
+		DeviceCode:      result.Get("device_code").String(),
+		UserCode:        result.Get("user_code").String(),
+		VerificationURL: result.Get("verification_url").String(),
+		QRCodeURL:       result.Get("qrcode_url").String(),
+		ExpiresIn:       result.Get("expires_in").Int(),
+		Interval:        result.Get("interval").Int(),
+	}
+	if dc.Interval <= 0 {
+		dc.Interval = 5
+	}
+
+	return dc, nil
+}
+
+// 轮询时网络错误的重试间隔上限；设备码没有有效期时，连续失败 maxPollRetries 次后放弃
+const (
+	maxPollBackoff = time.Minute
+	maxPollRetries = 5
+)
+
+// PollDeviceToken 轮询等待用户完成授权，返回令牌。网络错误按指数退避重试，直到设备码过期
+func (c *Client) PollDeviceToken(dc *DeviceCode) (*Token, error) {
+	interval := time.Duration(dc.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(dc.ExpiresIn) * time.Second)
+	backoff := time.Duration(0)
+	failures := 0
+
+	for {
+		if backoff > 0 {
+			time.Sleep(backoff)
+		} else {
+			time.Sleep(interval)
+		}
+
+		token, err := c.requestToken(map[string]string{
+			"grant_type": "device_token",
+			"code":       dc.DeviceCode,
+		})
+		if err == nil {
+			return token, nil
+		}
+
+		var authErr *Error
+		if errors.As(err, &authErr) {
+			backoff, failures = 0, 0
+			switch authErr.Code {
+			case "authorization_pending":
+			case "slow_down":
+				interval += 5 * time.Second
+			default:
+				return nil, err
+			}
+		} else {
+			// 网络错误，等待更长时间后重试
+			failures++
+			if dc.ExpiresIn <= 0 && failures >= maxPollRetries {
+				return nil, err
+			}
+			backoff = maxPollBackoff
+			if failures < 8 && interval<<failures < maxPollBackoff {
+				backoff = interval << failures
+			}
+		}
+
+		if dc.ExpiresIn > 0 && time.Now().Add(backoff).After(deadline) {
+			if backoff > 0 {
+				return nil, fmt.Errorf("设备码已过期，最后一次轮询失败: %w", err)
+			}
+			return nil, fmt.Errorf("设备码已过期，请重新授权")
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Endpoint OAuth 授权端点
+type Endpoint struct {
+	AuthURL   string // 授权页面地址
+	TokenURL  string // 换取 token 地址
+	DeviceURL string // 设备码授权地址，不支持时为空
+	Scope     string // 申请的权限
+	PKCE      bool   // 是否支持 PKCE
+	JSONBody  bool   // token 接口是否使用 JSON 请求体（否则使用表单）
+}
+
+// Endpoints 各云盘的 OAuth 端点
+var Endpoints = map[string]Endpoint{
+	"aliyun": {
+		AuthURL:  "https://openapi.alipan.com/oauth/authorize",
+		TokenURL: "https://openapi.alipan.com/oauth/access_token",
+		Scope:    "user:base,file:all:read,file:all:write",
+		PKCE:     true,
+		JSONBody: true,
+	},
+	"baidu": {
+		AuthURL:   "https://openapi.baidu.com/oauth/2.0/authorize",
+		TokenURL:  "https://openapi.baidu.com/oauth/2.0/token",
+		DeviceURL: "https://openapi.baidu.com/oauth/2.0/device/code",
+		Scope:     "basic,netdisk",
+	},
+	"onedrive": {
+		AuthURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+		TokenURL: "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+		Scope:    "Files.ReadWrite.All offline_access User.Read",
+		PKCE:     true,
+	},
+}
+
+// Token OAuth 令牌
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Apply 将令牌写入云盘配置的 Tokens
+func (t *Token) Apply(tokens map[string]string) {
+	tokens["access_token"] = t.AccessToken
+	if t.RefreshToken != "" {
+		tokens["refresh_token"] = t.RefreshToken
+	}
+	if t.ExpiresIn > 0 {
+		tokens["expires_at"] = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second).Format(time.RFC3339)
+	}
+}
+
+// Client OAuth 客户端
+type Client struct {
+	Endpoint     Endpoint
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	httpClient   *http.Client
+}
+
+// NewClient 根据云盘类型和 Tokens 中的 client_id、client_secret 创建 OAuth 客户端
+func NewClient(providerType string, tokens map[string]string, redirectURL string) (*Client, error) {
+	endpoint, ok := Endpoints[providerType]
+	if !ok {
+		return nil, fmt.Errorf("云盘类型 %s 不支持 OAuth 授权", providerType)
+	}
+
+	clientID := tokens["client_id"]
+	if clientID == "" {
+		return nil, fmt.Errorf("缺少 client_id")
+	}
+
+	return &Client{
+		Endpoint:     endpoint,
+		ClientID:     clientID,
+		ClientSecret: tokens["client_secret"],
+		RedirectURL:  redirectURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// AuthCodeURL 生成授权页面地址，verifier 仅在支持 PKCE 时使用
+func (c *Client) AuthCodeURL(state, verifier string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.ClientID)
+	params.Set("redirect_uri", c.RedirectURL)
+	params.Set("scope", c.Endpoint.Scope)
+	params.Set("state", state)
+
+	if c.Endpoint.PKCE && verifier != "" {
+		params.Set("code_challenge", CodeChallenge(verifier))
+		params.Set("code_challenge_method", "S256")
+	}
+
+	return c.Endpoint.AuthURL + "?" + params.Encode()
+}
+
+// Exchange 使用授权码换取令牌
+func (c *Client) Exchange(code, verifier string) (*Token, error) {
+	params := map[string]string{
+		"grant_type":   "authorization_code",
+		"code":         code,
+		"redirect_uri": c.RedirectURL,
+	}
+	if c.Endpoint.PKCE && verifier != "" {
+		params["code_verifier"] = verifier
+	}
+
+	return c.requestToken(params)
+}
+
+// Refresh 使用刷新令牌换取新的令牌
+func (c *Client) Refresh(refreshToken string) (*Token, error) {
+	return c.requestToken(map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+	})
+}
+
+// requestToken 请求 token 接口
+func (c *Client) requestToken(params map[string]string) (*Token, error) {
+	params["client_id"] = c.ClientID
+	if c.ClientSecret != "" {
+		params["client_secret"] = c.ClientSecret
+	}
+
+	var req *http.Request
+	var err error
+	if c.Endpoint.JSONBody {
+		jsonData, _ := json.Marshal(params)
+		req, err = http.NewRequest("POST", c.Endpoint.TokenURL, bytes.NewReader(jsonData))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	} else {
+		form := url.Values{}
+		for k, v := range params {
+			form.Set(k, v)
+		}
+		req, err = http.NewRequest("POST", c.Endpoint.TokenURL, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return parseToken(resp.StatusCode, body)
+}
+
+// parseToken 解析 token 接口返回结果
+func parseToken(statusCode int, body []byte) (*Token, error) {
+	result := gjson.ParseBytes(body)
+
+	if statusCode != http.StatusOK || result.Get("access_token").String() == "" {
+		return nil, tokenError(result)
+	}
+
+	return &Token{
+		AccessToken:  result.Get("access_token").String(),
+		RefreshToken: result.Get("refresh_token").String(),
+		ExpiresIn:    result.Get("expires_in").Int(),
+	}, nil
+}
+
+// Error 授权接口返回的错误
+type Error struct {
+	Code    string // 错误码，如 authorization_pending
+	Message string // 错误描述
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return "获取令牌失败: " + e.Message
+}
+
+// tokenError 提取接口返回的错误信息（兼容阿里云盘 code/message 和百度 error/error_description）
+func tokenError(result gjson.Result) error {
+	e := &Error{Code: result.Get("error").String()}
+	if e.Code == "" {
+		e.Code = result.Get("code").String()
+	}
+
+	for _, key := range []string{"error_description", "message", "error", "code"} {
+		if msg := result.Get(key).String(); msg != "" {
+			e.Message = msg
+			return e
+		}
+	}
+
+	e.Message = result.String()
+	return e
+}
+
+// NewState 生成随机 state 参数
+func NewState() string {
+	return randomString(16)
+}
+
+// NewVerifier 生成 PKCE code_verifier
+func NewVerifier() string {
+	return randomString(32)
+}
+
+// CodeChallenge 根据 code_verifier 计算 S256 code_challenge
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString 生成 n 字节随机数的十六进制字符串
+func randomString(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		// 随机源不可用时退化为时间戳，仅影响不可预测性
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
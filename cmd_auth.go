@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"CloudFileSync/auth"
+	"CloudFileSync/config"
+)
+
+// clientSecretEnv 传入 client_secret 的环境变量，避免密钥出现在命令行参数和进程列表中
+const clientSecretEnv = "CLOUDFILESYNC_CLIENT_SECRET"
+
+// runAuthCommand 执行 auth 子命令：auth baidu --device
+func runAuthCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "用法: auth <云盘类型> --device [--name 名称] [--client-id ID] [--client-secret-stdin]")
+		return 2
+	}
+	providerType := args[0]
+
+	fs, cfgPath := newCommandFlags("auth")
+	device := fs.Bool("device", false, "使用设备码授权（适用于没有浏览器的服务器）")
+	name := fs.String("name", "", "写入令牌的云盘配置名称，默认为该类型的第一个配置")
+	clientID := fs.String("client-id", "", "开放平台应用的 client_id（AppKey）")
+	secretStdin := fs.Bool("client-secret-stdin", false, "从标准输入读取开放平台应用的 client_secret（SecretKey），也可通过环境变量 "+clientSecretEnv+" 传入")
+	fs.Parse(args[1:])
+
+	if !*device {
+		fmt.Fprintln(os.Stderr, "命令行目前仅支持 --device 设备码授权，浏览器授权请使用 Web 管理界面")
+		return 2
+	}
+
+	// 授权正是为了补全令牌，配置校验未通过时继续
+	cfg, err := config.LoadConfig(*cfgPath)
+	var invalid config.ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+
+	index := findProviderIndex(cfg, providerType, *name)
+	if index < 0 {
+		// 没有对应配置时新建一个
+		displayName := *name
+		if displayName == "" {
+			displayName = providerType
+		}
+		cfg.Providers = append(cfg.Providers, config.ProviderConfig{
+			Type:   providerType,
+			Name:   displayName,
+			Enable: true,
+			Tokens: make(map[string]string),
+			Target: "/CloudFileSync",
+		})
+		index = len(cfg.Providers) - 1
+	}
+
+	providerCfg := &cfg.Providers[index]
+	if providerCfg.Tokens == nil {
+		providerCfg.Tokens = make(map[string]string)
+	}
+	if *clientID != "" {
+		providerCfg.Tokens["client_id"] = *clientID
+	}
+
+	// client_secret 不通过命令行参数传入，避免出现在命令行历史和进程列表中
+	clientSecret := os.Getenv(clientSecretEnv)
+	if *secretStdin {
+		fmt.Fprint(os.Stderr, "请输入 client_secret: ")
+		value, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		clientSecret = strings.TrimRight(value, "\r\n")
+		if clientSecret == "" {
+			fmt.Fprintln(os.Stderr, "\nclient_secret 不能为空")
+			return 1
+		}
+	}
+	if clientSecret != "" {
+		// 未启用令牌存储时 SaveConfig 会把 client_secret 明文写入配置文件
+		if config.Secrets() == nil && !config.IsTokenReference(clientSecret) {
+			fmt.Fprintf(os.Stderr, "警告: 未设置 %s，client_secret 将以明文保存在配置文件中；"+
+				"可以设置主密码启用令牌存储，或改用 ${ENV}、file: 引用\n", config.PassphraseEnv)
+		}
+		providerCfg.Tokens["client_secret"] = clientSecret
+	}
+
+	tokens, err := providerCfg.ResolvedTokens()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析令牌失败: %v\n", err)
+		return 1
+	}
+
+	client, err := auth.NewClient(providerType, tokens, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建授权客户端失败: %v\n", err)
+		return 1
+	}
+
+	dc, err := client.RequestDeviceCode()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "申请设备码失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("请在任意设备的浏览器中访问: %s\n", dc.VerificationURL)
+	fmt.Printf("并输入验证码: %s\n", dc.UserCode)
+	if dc.QRCodeURL != "" {
+		fmt.Printf("也可以扫描二维码: %s\n", dc.QRCodeURL)
+	}
+	fmt.Printf("等待授权完成（%d 秒内有效）...\n", dc.ExpiresIn)
+
+	token, err := client.PollDeviceToken(dc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "授权失败: %v\n", err)
+		return 1
+	}
+
+	token.Apply(providerCfg.Tokens)
+	if err := config.SaveConfig(*cfgPath, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "保存配置失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("授权成功，令牌已写入云盘配置「%s」: %s\n", providerCfg.Name, *cfgPath)
+	return 0
+}
+
+// findProviderIndex 按类型和名称查找云盘配置下标，名称为空时返回该类型的第一个配置
+func findProviderIndex(cfg *config.Config, providerType, name string) int {
+	for i, p := range cfg.Providers {
+		if p.Type == providerType && (name == "" || p.Name == name) {
+			return i
+		}
+	}
+	return -1
+}
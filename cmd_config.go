@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"CloudFileSync/config"
+)
+
+// runConfigCommand 执行 config 子命令：config check|migrate
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "用法: config <check|migrate> [-config 配置文件]")
+		return 2
+	}
+
+	switch args[0] {
+	case "check":
+		return runConfigCheck(args[1:])
+	case "migrate":
+		return runConfigMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知的 config 子命令: %s\n", args[0])
+		return 2
+	}
+}
+
+// runConfigCheck 校验配置文件
+func runConfigCheck(args []string) int {
+	fs, cfgPath := newCommandFlags("config check")
+	fs.Parse(args)
+
+	_, err := config.LoadConfig(*cfgPath)
+	var invalid config.ValidationErrors
+	if errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "%s 有 %d 处错误:\n", *cfgPath, len(invalid))
+		for _, fe := range invalid {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", fe.Field, fe.Message)
+		}
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("%s 配置有效\n", *cfgPath)
+	return 0
+}
+
+// runConfigMigrate 把配置文件升级到当前版本并写回，-to 指定时同时转换为该文件扩展名对应的格式
+func runConfigMigrate(args []string) int {
+	fs, cfgPath := newCommandFlags("config migrate")
+	to := fs.String("to", "", "写入的配置文件路径，默认覆盖原文件")
+	fs.Parse(args)
+
+	// 升级不改变配置内容，校验未通过时同样写回，便于之后修改
+	cfg, err := config.LoadConfig(*cfgPath)
+	var invalid config.ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+
+	out := *to
+	if out == "" {
+		out = *cfgPath
+	}
+	if err := config.SaveConfig(out, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "保存配置文件失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("已写入 %s（版本 %d）\n", out, config.CurrentVersion)
+	if len(invalid) > 0 {
+		fmt.Fprintf(os.Stderr, "配置有 %d 处错误，请运行 config check 查看\n", len(invalid))
+	}
+	return 0
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+)
+
+// remoteSpec 远程路径，格式为 <云盘名称>:<路径>
+type remoteSpec struct {
+	cfg      config.ProviderConfig
+	provider provider.Provider
+	path     string
+}
+
+// runFsCommand 执行文件操作子命令：ls、get、put、rm、mv、mkdir、verify
+func runFsCommand(name string, args []string) int {
+	fs, cfgPath := newCommandFlags(name)
+	fs.Parse(args)
+	saveRefreshedTokens(*cfgPath)
+
+	// 文件操作不需要监听目录，只检查用到的云盘配置（见 parseRemote）
+	cfg, err := config.LoadConfig(*cfgPath)
+	var invalid config.ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+
+	switch name {
+	case "ls":
+		err = cmdList(cfg, fs.Args())
+	case "get":
+		err = cmdGet(cfg, fs.Args())
+	case "put":
+		err = cmdPut(cfg, fs.Args())
+	case "rm":
+		err = cmdRemove(cfg, fs.Args())
+	case "mv":
+		err = cmdMove(cfg, fs.Args())
+	case "mkdir":
+		err = cmdMkdir(cfg, fs.Args())
+	case "verify":
+		err = cmdVerify(cfg, fs.Args())
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// cmdList 列举远程目录：ls <云盘>:<路径>
+func cmdList(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: ls <云盘>:<路径>")
+	}
+
+	remote, err := parseRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	lister, ok := remote.provider.(provider.Lister)
+	if !ok {
+		return fmt.Errorf("[%s] 不支持列举目录", remote.cfg.Name)
+	}
+
+	files, err := lister.List(remote.path)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	for _, f := range files {
+		kind := "-"
+		name := f.Name
+		if f.IsDir {
+			kind = "d"
+			name += "/"
+		}
+		fmt.Printf("%s %10s  %s  %s\n", kind, formatSize(f.Size), f.ModTime.Format("2006-01-02 15:04:05"), name)
+	}
+	return nil
+}
+
+// cmdGet 下载文件：get <云盘>:<路径> [本地路径]
+func cmdGet(cfg *config.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("用法: get <云盘>:<路径> [本地路径]")
+	}
+
+	remote, err := parseRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	downloader, ok := remote.provider.(provider.Downloader)
+	if !ok {
+		return fmt.Errorf("[%s] 不支持下载", remote.cfg.Name)
+	}
+
+	localPath := path.Base(remote.path)
+	if len(args) == 2 {
+		localPath = args[1]
+		if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+			localPath = filepath.Join(localPath, path.Base(remote.path))
+		}
+	}
+
+	return downloader.DownloadFile(remote.path, localPath)
+}
+
+// cmdPut 上传文件：put <本地路径> <云盘>:<路径>
+func cmdPut(cfg *config.Config, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("用法: put <本地路径> <云盘>:<路径>")
+	}
+
+	remote, err := parseRemote(cfg, args[1])
+	if err != nil {
+		return err
+	}
+
+	// 目标以 / 结尾时上传到该目录下
+	remotePath := remote.path
+	if strings.HasSuffix(args[1], "/") {
+		remotePath = path.Join(remotePath, filepath.Base(args[0]))
+	}
+
+	return remote.provider.UploadFile(args[0], remotePath)
+}
+
+// cmdRemove 删除远程文件：rm <云盘>:<路径>
+func cmdRemove(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: rm <云盘>:<路径>")
+	}
+
+	remote, err := parseRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	// 路径为空或 / 时会删除整个云盘目录
+	if remote.path == "/" {
+		return fmt.Errorf("不能删除根目录")
+	}
+
+	return remote.provider.DeleteFile(remote.path)
+}
+
+// cmdMove 移动远程文件：mv <云盘>:<路径> [<云盘>:]<新路径>
+func cmdMove(cfg *config.Config, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("用法: mv <云盘>:<路径> [<云盘>:]<新路径>")
+	}
+
+	remote, err := parseRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	// 前缀是已配置的云盘名称或类型时才视为云盘，否则冒号属于路径本身
+	newPath := args[1]
+	if name, p, ok := strings.Cut(newPath, ":"); ok && isProviderName(cfg, name) {
+		if name != remote.cfg.Name && name != remote.cfg.Type {
+			return fmt.Errorf("不支持跨云盘移动")
+		}
+		newPath = p
+	}
+
+	mover, ok := remote.provider.(provider.Mover)
+	if !ok {
+		return fmt.Errorf("[%s] 不支持移动", remote.cfg.Name)
+	}
+
+	return mover.MoveFile(remote.path, "/"+strings.Trim(newPath, "/"))
+}
+
+// cmdMkdir 创建远程目录：mkdir <云盘>:<路径>
+func cmdMkdir(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: mkdir <云盘>:<路径>")
+	}
+
+	remote, err := parseRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	return remote.provider.CreateDir(remote.path)
+}
+
+// cmdVerify 校验凭证并显示账号与容量信息：verify <云盘>
+func cmdVerify(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: verify <云盘>")
+	}
+
+	remote, err := parseRemote(cfg, strings.TrimSuffix(args[0], ":")+":")
+	if err != nil {
+		return err
+	}
+
+	verifier, ok := remote.provider.(provider.Verifier)
+	if !ok {
+		return fmt.Errorf("[%s] 不支持校验", remote.cfg.Name)
+	}
+
+	info, err := verifier.Verify()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("云盘: %s (%s)\n", remote.cfg.Name, remote.provider.Name())
+	fmt.Printf("账号: %s\n", info.UserName)
+	for _, key := range sortedKeys(info.DriveIDs) {
+		fmt.Printf("%s: %s\n", key, info.DriveIDs[key])
+	}
+	fmt.Printf("容量: %s / %s\n", formatSize(info.UsedSpace), formatSize(info.TotalSpace))
+	return nil
+}
+
+// parseRemote 解析 <云盘>:<路径> 并初始化对应的云盘提供商
+func parseRemote(cfg *config.Config, spec string) (*remoteSpec, error) {
+	name, remotePath, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("无效的远程路径 %q，格式应为 <云盘>:<路径>", spec)
+	}
+
+	index, err := findProvider(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	providerCfg := cfg.Providers[index]
+
+	var invalid config.ValidationErrors
+	if errors.As(cfg.Validate(), &invalid) {
+		if errs := invalid.Provider(index); len(errs) > 0 {
+			return nil, errs
+		}
+	}
+
+	pvd, err := provider.NewProvider(providerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化云盘提供商失败 [%s]: %w", providerCfg.Name, err)
+	}
+
+	return &remoteSpec{
+		cfg:      providerCfg,
+		provider: pvd,
+		path:     "/" + strings.Trim(remotePath, "/"),
+	}, nil
+}
+
+// findProvider 按名称查找云盘配置，名称不匹配时按类型查找（类型唯一时），返回其在配置中的序号
+func findProvider(cfg *config.Config, name string) (int, error) {
+	var byType []int
+	for i, p := range cfg.Providers {
+		if p.Name == name {
+			return i, nil
+		}
+		if p.Type == name {
+			byType = append(byType, i)
+		}
+	}
+
+	switch len(byType) {
+	case 1:
+		return byType[0], nil
+	case 0:
+		return -1, fmt.Errorf("未找到云盘配置: %s", name)
+	default:
+		return -1, fmt.Errorf("存在多个 %s 类型的云盘，请使用配置名称", name)
+	}
+}
+
+// isProviderName 判断名称是否为已配置云盘的名称或类型
+func isProviderName(cfg *config.Config, name string) bool {
+	for _, p := range cfg.Providers {
+		if p.Name == name || p.Type == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newCommandFlags 创建子命令参数解析器，所有子命令都支持 -config
+func newCommandFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	cfgPath := fs.String("config", *configFile, "配置文件路径")
+	return fs, cfgPath
+}
+
+// formatSize 格式化文件大小
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// sortedKeys 返回排序后的键
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/config"
+)
+
+// testEnv 使用本地目录云盘的命令行测试环境
+type testEnv struct {
+	cfgPath  string
+	watchDir string
+	roots    map[string]string
+}
+
+// newTestEnv 创建配置文件，每个名称对应一个 local 类型的云盘
+func newTestEnv(t *testing.T, names ...string) *testEnv {
+	t.Helper()
+
+	env := &testEnv{
+		cfgPath:  filepath.Join(t.TempDir(), "config.json"),
+		watchDir: t.TempDir(),
+		roots:    map[string]string{},
+	}
+	cfg := &config.Config{WatchDir: env.watchDir, DelayTime: 1}
+	for _, name := range names {
+		env.roots[name] = t.TempDir()
+		cfg.Providers = append(cfg.Providers, config.ProviderConfig{
+			Type:   "local",
+			Name:   name,
+			Enable: true,
+			Tokens: map[string]string{"root": env.roots[name]},
+			Target: "/",
+		})
+	}
+	env.save(t, cfg)
+	return env
+}
+
+// save 写入配置文件
+func (env *testEnv) save(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	if err := config.SaveConfig(env.cfgPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// run 执行子命令，返回退出码
+func (env *testEnv) run(args ...string) int {
+	return runCommand(append([]string{args[0], "-config", env.cfgPath}, args[1:]...))
+}
+
+// remote 返回云盘目录中的文件路径
+func (env *testEnv) remote(name, rel string) string {
+	return filepath.Join(env.roots[name], filepath.FromSlash(rel))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s 内容为 %q，期望 %q", path, data, want)
+	}
+}
+
+func TestFsCommandUsage(t *testing.T) {
+	env := newTestEnv(t, "backup")
+
+	for _, args := range [][]string{
+		{"ls"},
+		{"ls", "backup:/", "backup:/a"},
+		{"get"},
+		{"get", "backup:/a", "a", "b"},
+		{"put", "a"},
+		{"rm"},
+		{"mv", "backup:/a"},
+		{"mkdir"},
+		{"verify"},
+		{"ls", "backup"},          // 缺少冒号
+		{"ls", "missing:/"},       // 未配置的云盘
+		{"rm", "backup:/"},        // 不能删除根目录
+		{"get", "backup:/absent"}, // 文件不存在
+	} {
+		if code := env.run(args...); code != 1 {
+			t.Errorf("%v 退出码 %d，期望 1", args, code)
+		}
+	}
+
+	if code := runCommand([]string{"unknown"}); code != 2 {
+		t.Errorf("未知命令退出码 %d，期望 2", code)
+	}
+}
+
+func TestFsCommands(t *testing.T) {
+	env := newTestEnv(t, "backup")
+	local := filepath.Join(t.TempDir(), "a.txt")
+	writeFile(t, local, "a")
+
+	for _, args := range [][]string{
+		{"mkdir", "backup:/dir"},
+		{"put", local, "backup:/dir/"},
+		{"ls", "backup:/dir"},
+		{"verify", "backup"},
+	} {
+		if code := env.run(args...); code != 0 {
+			t.Fatalf("%v 退出码 %d", args, code)
+		}
+	}
+	assertFile(t, env.remote("backup", "dir/a.txt"), "a")
+
+	// 类型唯一时也可使用类型
+	got := filepath.Join(t.TempDir(), "got.txt")
+	if code := env.run("get", "local:/dir/a.txt", got); code != 0 {
+		t.Fatalf("get 退出码 %d", code)
+	}
+	assertFile(t, got, "a")
+
+	if code := env.run("rm", "backup:/dir/a.txt"); code != 0 {
+		t.Fatalf("rm 退出码 %d", code)
+	}
+	if _, err := os.Stat(env.remote("backup", "dir/a.txt")); !os.IsNotExist(err) {
+		t.Errorf("dir/a.txt 应已删除: %v", err)
+	}
+}
+
+func TestMoveCommand(t *testing.T) {
+	env := newTestEnv(t, "backup", "other")
+	writeFile(t, env.remote("backup", "a.txt"), "a")
+
+	// 新路径可以省略云盘，也可以使用同一云盘的名称
+	if code := env.run("mv", "backup:/a.txt", "b.txt"); code != 0 {
+		t.Fatalf("mv 退出码 %d", code)
+	}
+	if code := env.run("mv", "backup:/b.txt", "backup:/dir/c.txt"); code != 0 {
+		t.Fatalf("mv 退出码 %d", code)
+	}
+	assertFile(t, env.remote("backup", "dir/c.txt"), "a")
+
+	// 不是云盘名称或类型的前缀属于文件名
+	if code := env.run("mv", "backup:/dir/c.txt", "notes:2024.txt"); code != 0 {
+		t.Fatalf("mv 退出码 %d", code)
+	}
+	assertFile(t, env.remote("backup", "notes:2024.txt"), "a")
+
+	if code := env.run("mv", "backup:/notes:2024.txt", "other:/a.txt"); code != 1 {
+		t.Errorf("跨云盘移动退出码 %d，期望 1", code)
+	}
+	assertFile(t, env.remote("backup", "notes:2024.txt"), "a")
+}
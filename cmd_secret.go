@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"CloudFileSync/config"
+)
+
+// runSecretCommand 执行 secret 子命令：管理加密令牌存储
+func runSecretCommand(args []string) int {
+	if len(args) == 0 {
+		printSecretUsage()
+		return 2
+	}
+
+	fs, cfgPath := newCommandFlags("secret " + args[0])
+	fs.Parse(args[1:])
+
+	if args[0] == "seal" {
+		return sealConfig(*cfgPath)
+	}
+
+	store, err := config.OpenSecrets(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开令牌存储失败: %v\n", err)
+		return 1
+	}
+	if store == nil {
+		fmt.Fprintf(os.Stderr, "请先通过环境变量 %s 设置主密码\n", config.PassphraseEnv)
+		return 1
+	}
+
+	switch {
+	case args[0] == "list" && fs.NArg() == 0:
+		for _, name := range store.Names() {
+			fmt.Println(name)
+		}
+	case args[0] == "set" && fs.NArg() == 1:
+		// 从标准输入读取，避免令牌出现在命令行历史和进程列表中
+		fmt.Fprintf(os.Stderr, "请输入 %s 的值: ", fs.Arg(0))
+		value, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimRight(value, "\r\n")
+		if value == "" {
+			fmt.Fprintln(os.Stderr, "\n令牌不能为空")
+			return 1
+		}
+		err := store.Set(fs.Arg(0), value)
+		if err == nil {
+			fmt.Printf("已保存，在配置中使用 secret:%s 引用\n", fs.Arg(0))
+		}
+		return exitCode(err)
+	case args[0] == "rm" && fs.NArg() == 1:
+		return exitCode(store.Delete(fs.Arg(0)))
+	default:
+		printSecretUsage()
+		return 2
+	}
+	return 0
+}
+
+// sealConfig 把配置文件中明文的敏感令牌移入令牌存储
+func sealConfig(path string) int {
+	if os.Getenv(config.PassphraseEnv) == "" {
+		fmt.Fprintf(os.Stderr, "请先通过环境变量 %s 设置主密码\n", config.PassphraseEnv)
+		return 1
+	}
+
+	cfg, err := config.LoadConfig(path)
+	var invalid config.ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+
+	if err := config.SaveConfig(path, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "保存配置失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("敏感令牌已移入 %s\n", config.SecretsFile(path))
+	return 0
+}
+
+// exitCode 打印错误并返回退出码
+func exitCode(err error) int {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// printSecretUsage 打印 secret 子命令用法
+func printSecretUsage() {
+	fmt.Fprintln(os.Stderr, `用法: secret <seal|list|set|rm> [-config 配置文件] [名称]
+
+  seal          把配置文件中明文的敏感令牌移入令牌存储
+  list          列出令牌存储中的名称
+  set <名称>     从标准输入读取令牌并保存
+  rm <名称>      删除令牌
+
+令牌存储为配置文件同目录下的 secrets.enc，主密码通过环境变量 `+config.PassphraseEnv+` 设置`)
+}
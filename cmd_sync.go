@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+	"CloudFileSync/syncer"
+)
+
+// runSyncCommand 执行 sync 子命令：完整对比监听目录与各云盘并同步
+func runSyncCommand(args []string) int {
+	fs, cfgPath := newCommandFlags("sync")
+	once := fs.Bool("once", false, "同步一次后退出（适用于 cron 和脚本）")
+	dryRun := fs.Bool("dry-run", false, "只显示计划执行的操作，不实际执行")
+	fs.Parse(args)
+	saveRefreshedTokens(*cfgPath)
+
+	cfg, err := config.LoadConfig(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+		return 1
+	}
+
+	targets, failed := loadTargets(cfg)
+
+	state, err := syncer.LoadState(cfg.GetStateFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载同步状态失败: %v\n", err)
+		return 1
+	}
+
+	s, err := syncer.New(cfg, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建同步器失败: %v\n", err)
+		return 1
+	}
+
+	snap, err := s.Scan()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	for _, t := range targets {
+		plan, err := s.Plan(t, snap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%s] 生成同步计划失败: %v\n", t.Config.Name, err)
+			failed++
+			continue
+		}
+
+		if *dryRun {
+			fmt.Printf("[%s] 计划执行 %d 项操作:\n", t.Config.Name, len(plan.Actions))
+			for _, action := range plan.Actions {
+				fmt.Printf("  %s\n", action)
+			}
+			continue
+		}
+
+		result := s.Execute(plan)
+		fmt.Printf("[%s] 创建目录 %d，上传 %d，移动 %d，删除 %d，处理冲突 %d，失败 %d\n",
+			t.Config.Name,
+			result.Done[syncer.ActionMkdir],
+			result.Done[syncer.ActionUpload],
+			result.Done[syncer.ActionMove],
+			result.Done[syncer.ActionDelete],
+			result.Done[syncer.ActionConflict],
+			len(result.Errors))
+		for _, err := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  失败: %v\n", err)
+		}
+		failed += len(result.Errors)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+
+	// 未指定 --once 时，完成首次同步后继续监听
+	if !*once && !*dryRun {
+		runCLIMode(cfg, *cfgPath)
+	}
+	return 0
+}
+
+// loadTargets 初始化所有已启用的云盘，返回初始化失败的数量
+func loadTargets(cfg *config.Config) ([]syncer.Target, int) {
+	targets := make([]syncer.Target, 0, len(cfg.Providers))
+	failed := 0
+
+	for _, p := range cfg.Providers {
+		if !p.Enable {
+			continue
+		}
+
+		pvd, err := provider.NewProvider(p)
+		if err != nil {
+			log.Printf("初始化云盘提供商失败 [%s]: %v", p.Name, err)
+			failed++
+			continue
+		}
+
+		targets = append(targets, syncer.Target{Config: p, Provider: pvd})
+		log.Printf("云盘提供商已加载: %s (目标目录: %s)", pvd.Name(), p.Target)
+	}
+
+	return targets, failed
+}
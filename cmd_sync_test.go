@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/config"
+)
+
+func TestSyncOnce(t *testing.T) {
+	env := newTestEnv(t, "backup")
+	writeFile(t, filepath.Join(env.watchDir, "dir", "a.txt"), "a")
+
+	if code := env.run("sync", "--once"); code != 0 {
+		t.Fatalf("sync 退出码 %d", code)
+	}
+	assertFile(t, env.remote("backup", "dir/a.txt"), "a")
+
+	// 再次同步没有需要执行的操作
+	if code := env.run("sync", "--once"); code != 0 {
+		t.Fatalf("sync 退出码 %d", code)
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	env := newTestEnv(t, "backup")
+	writeFile(t, filepath.Join(env.watchDir, "a.txt"), "a")
+
+	// --dry-run 不会上传，也不会进入监听模式
+	if code := env.run("sync", "--dry-run"); code != 0 {
+		t.Fatalf("sync 退出码 %d", code)
+	}
+	if _, err := os.Stat(env.remote("backup", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("a.txt 不应被上传: %v", err)
+	}
+}
+
+func TestSyncFailures(t *testing.T) {
+	// 配置文件不存在
+	if code := runCommand([]string{"sync", "-config", filepath.Join(t.TempDir(), "missing.json"), "--once"}); code != 1 {
+		t.Errorf("配置文件不存在时退出码 %d，期望 1", code)
+	}
+
+	// 云盘初始化失败时其余云盘照常同步，但退出码为 1
+	env := newTestEnv(t, "backup")
+	writeFile(t, filepath.Join(env.watchDir, "a.txt"), "a")
+	cfg, err := config.LoadConfig(env.cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Providers = append(cfg.Providers, config.ProviderConfig{
+		Type:   "local",
+		Name:   "unmounted",
+		Enable: true,
+		Tokens: map[string]string{"root": filepath.Join(t.TempDir(), "missing")},
+		Target: "/",
+	})
+	env.save(t, cfg)
+
+	if code := env.run("sync", "--once"); code != 1 {
+		t.Errorf("云盘初始化失败时退出码 %d，期望 1", code)
+	}
+	assertFile(t, env.remote("backup", "a.txt"), "a")
+}
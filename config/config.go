@@ -1,44 +1,100 @@
 package config
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 )
 
 // Config 主配置结构
 type Config struct {
-	WatchDir    string            `json:"watch_dir"`    // 监听的目录
-	DelayTime   int               `json:"delay_time"`   // 延迟上传时间（秒）
-	Providers   []ProviderConfig  `json:"providers"`    // 云盘配置列表
+	Version   int              `json:"version" yaml:"version" toml:"version"`          // 配置格式版本，见 CurrentVersion
+	WatchDir  string           `json:"watch_dir" yaml:"watch_dir" toml:"watch_dir"`    // 监听的目录
+	DelayTime int              `json:"delay_time" yaml:"delay_time" toml:"delay_time"` // 延迟上传时间（秒）
+	Providers []ProviderConfig `json:"providers" yaml:"providers" toml:"providers"`    // 云盘配置列表
+
+	ConflictStrategy string `json:"conflict_strategy,omitempty" yaml:"conflict_strategy,omitempty" toml:"conflict_strategy,omitempty"` // 冲突处理策略，默认 keep_both
+	StateFile        string `json:"state_file,omitempty" yaml:"state_file,omitempty" toml:"state_file,omitempty"`                      // 同步状态文件，默认位于监听目录的 .cloudfilesync 下
+	HistoryFile      string `json:"history_file,omitempty" yaml:"history_file,omitempty" toml:"history_file,omitempty"`                // 同步历史文件，默认与同步状态文件位于同一目录
+
+	Web *WebConfig `json:"web,omitempty" yaml:"web,omitempty" toml:"web,omitempty"` // Web 管理界面配置
 }
 
 // ProviderConfig 云盘提供商配置
 type ProviderConfig struct {
-	Type   string            `json:"type"`   // 类型: "aliyun" 或 "baidu"
-	Name   string            `json:"name"`   // 配置名称
-	Enable bool              `json:"enable"` // 是否启用
-	Tokens map[string]string `json:"tokens"` // 认证令牌
-	Target string            `json:"target"` // 目标目录
+	Type   string            `json:"type" yaml:"type" toml:"type"`       // 类型，见 provider.Types()，如 "aliyun"、"baidu"
+	Name   string            `json:"name" yaml:"name" toml:"name"`       // 配置名称
+	Enable bool              `json:"enable" yaml:"enable" toml:"enable"` // 是否启用
+	Tokens map[string]string `json:"tokens" yaml:"tokens" toml:"tokens"` // 认证令牌
+	Target string            `json:"target" yaml:"target" toml:"target"` // 目标目录
 }
 
-// LoadConfig 从文件加载配置
+// WebConfig Web 管理界面的登录配置。Username 与 Password、Token 至少设置一种时启用登录，
+// Password 和 Token 支持令牌引用（${ENV}、file:、secret:），Password 也可以是 bcrypt 哈希
+type WebConfig struct {
+	Username string `json:"username,omitempty" yaml:"username,omitempty" toml:"username,omitempty"` // 登录用户名
+	Password string `json:"password,omitempty" yaml:"password,omitempty" toml:"password,omitempty"` // 登录密码或 bcrypt 哈希
+	Token    string `json:"token,omitempty" yaml:"token,omitempty" toml:"token,omitempty"`          // 访问令牌，可在登录页使用，也可放在 Authorization: Bearer 请求头中
+}
+
+// LoadConfig 从文件加载并校验配置。按扩展名识别 JSON、YAML、TOML 格式（见 FormatOf），
+// 旧版本的配置自动升级到 CurrentVersion；设置了主密码时同时打开令牌存储（见 OpenSecrets）。
+// 校验失败时同时返回解析出的配置和 ValidationErrors，调用方可以选择继续使用（如 Web 模式需要启动后在界面中修改）
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var config Config
-	err = json.Unmarshal(data, &config)
+	config, err := decode(FormatOf(path), data)
 	if err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	if _, err := OpenSecrets(path); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return config, config.Validate()
+}
+
+// SaveConfig 按扩展名对应的格式保存配置到文件（权限 0600），写入当前版本号。启用令牌存储时先把明文的敏感令牌移入存储，config 中改为引用
+func SaveConfig(path string, config *Config) error {
+	if err := SealTokens(config); err != nil {
+		return err
+	}
+
+	config.Version = CurrentVersion
+	data, err := encode(FormatOf(path), config)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile 不会修改已有文件的权限
+	return os.Chmod(path, 0600)
 }
 
 // GetDelayDuration 获取延迟时间
 func (c *Config) GetDelayDuration() time.Duration {
 	return time.Duration(c.DelayTime) * time.Second
 }
+
+// GetStateFile 获取同步状态文件路径
+func (c *Config) GetStateFile() string {
+	if c.StateFile != "" {
+		return c.StateFile
+	}
+	return filepath.Join(c.WatchDir, ".cloudfilesync", "state.json")
+}
+
+// GetHistoryFile 获取同步历史文件路径
+func (c *Config) GetHistoryFile() string {
+	if c.HistoryFile != "" {
+		return c.HistoryFile
+	}
+	return filepath.Join(filepath.Dir(c.GetStateFile()), "history.jsonl")
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format 配置文件格式
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatOf 按扩展名识别配置文件格式：.yaml/.yml 为 YAML，.toml 为 TOML，其他为 JSON
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// decode 解析配置文件并升级到当前版本。
+// 各格式先解析为通用的 map，升级后再转换为 Config，迁移只需处理一种结构
+func decode(format Format, data []byte) (*Config, error) {
+	var raw interface{}
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &raw)
+	case FormatTOML:
+		err = toml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{} // 空文件
+	}
+
+	// 经过一次 JSON 转换，统一各格式解析出的类型（如 TOML 的 []map[string]interface{}、int64）
+	m, err := normalize(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate(m); err != nil {
+		return nil, err
+	}
+	stringifyTokens(m)
+
+	data, err = json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// encode 按格式序列化配置
+func encode(format Format, config *Config) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(config); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return json.MarshalIndent(config, "", "  ")
+	}
+}
+
+// normalize 把解析结果转换为 JSON 类型的 map
+func normalize(raw interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("配置必须是键值结构: %w", err)
+	}
+	return m, nil
+}
+
+// stringifyTokens 把 tokens 中的数字、布尔值转换为字符串，YAML、TOML 中可以直接写 port: 22、path_style: true
+func stringifyTokens(m map[string]interface{}) {
+	providers, _ := m["providers"].([]interface{})
+	for _, p := range providers {
+		provider, _ := p.(map[string]interface{})
+		tokens, _ := provider["tokens"].(map[string]interface{})
+		for key, value := range tokens {
+			switch v := value.(type) {
+			case string, nil:
+			case float64:
+				tokens[key] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				tokens[key] = fmt.Sprint(v)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"CloudFileSync/config"
+)
+
+func TestLoadFormats(t *testing.T) {
+	watchDir := t.TempDir()
+
+	files := map[string]string{
+		"config.yaml": `
+watch_dir: ` + watchDir + `
+delay_time: 3
+providers:
+  - type: sftp
+    name: 服务器
+    enable: true
+    tokens:
+      host: example.com
+      port: 22
+      username: root
+      password: secret
+    target: /backup
+`,
+		"config.toml": `
+watch_dir = "` + watchDir + `"
+delay_time = 3
+
+[[providers]]
+type = "sftp"
+name = "服务器"
+enable = true
+target = "/backup"
+
+[providers.tokens]
+host = "example.com"
+port = 22
+username = "root"
+password = "secret"
+`,
+		"config.json": `{
+  "watch_dir": "` + watchDir + `",
+  "delay_time": 3,
+  "providers": [{
+    "type": "sftp",
+    "name": "服务器",
+    "enable": true,
+    "tokens": {"host": "example.com", "port": "22", "username": "root", "password": "secret"},
+    "target": "/backup"
+  }]
+}`,
+	}
+
+	want := &config.Config{
+		Version:   config.CurrentVersion,
+		WatchDir:  watchDir,
+		DelayTime: 3,
+		Providers: []config.ProviderConfig{{
+			Type:   "sftp",
+			Name:   "服务器",
+			Enable: true,
+			Tokens: map[string]string{"host": "example.com", "port": "22", "username": "root", "password": "secret"},
+			Target: "/backup",
+		}},
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0600)
+
+		// 没有 version 的配置视为版本 0，加载时升级
+		cfg, err := config.LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("%s: 解析结果为 %+v，期望 %+v", name, cfg, want)
+		}
+
+		// 按原格式保存后重新加载，内容不变
+		if err := config.SaveConfig(path, cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		saved, err := config.LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(saved, want) {
+			t.Errorf("%s: 保存后重新加载为 %+v，期望 %+v", name, saved, want)
+		}
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("version: 99\nwatch_dir: /tmp\n"), 0600)
+
+	_, err := config.LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "请升级程序") {
+		t.Errorf("期望返回版本过高的错误，实际为 %v", err)
+	}
+}
+
+func TestSaveWritesVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := config.SaveConfig(path, &config.Config{WatchDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "version = 1") {
+		t.Errorf("保存的配置缺少版本号:\n%s", data)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	cases := map[string]config.Format{
+		"config.json":     config.FormatJSON,
+		"config.YAML":     config.FormatYAML,
+		"conf/config.yml": config.FormatYAML,
+		"config.toml":     config.FormatTOML,
+		"config":          config.FormatJSON,
+	}
+	for path, want := range cases {
+		if got := config.FormatOf(path); got != want {
+			t.Errorf("FormatOf(%q) = %s，期望 %s", path, got, want)
+		}
+	}
+}
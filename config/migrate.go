@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"log"
+)
+
+// CurrentVersion 当前配置格式版本。修改配置结构时递增，并在 migrations 中添加升级步骤
+const CurrentVersion = 1
+
+// migration 把配置从上一个版本升级到下一个版本，配置以解析后的通用 map 形式传入
+type migration func(m map[string]interface{}) error
+
+// migrations 升级步骤，migrations[i] 把版本 i 升级到 i+1
+var migrations = []migration{
+	// 0 -> 1：没有 version 字段的早期配置，字段与版本 1 相同
+	func(m map[string]interface{}) error { return nil },
+}
+
+// migrate 把配置升级到 CurrentVersion，没有 version 字段时视为版本 0
+func migrate(m map[string]interface{}) error {
+	version := 0
+	if v, ok := m["version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			return fmt.Errorf("无效的配置版本: %v", v)
+		}
+		version = int(f)
+	}
+
+	if version > CurrentVersion {
+		return fmt.Errorf("配置文件版本 %d 高于程序支持的版本 %d，请升级程序", version, CurrentVersion)
+	}
+
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v](m); err != nil {
+			return fmt.Errorf("配置从版本 %d 升级到 %d 失败: %w", v, v+1, err)
+		}
+	}
+	if version < CurrentVersion {
+		log.Printf("配置已从版本 %d 升级到 %d，保存配置时写入新版本", version, CurrentVersion)
+	}
+
+	m["version"] = CurrentVersion
+	return nil
+}
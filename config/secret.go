@@ -0,0 +1,409 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv 令牌存储主密码的环境变量，设置后启用加密令牌存储
+const PassphraseEnv = "CLOUDFILESYNC_PASSPHRASE"
+
+// 令牌值中的引用前缀
+const (
+	envRefPrefix    = "${"
+	fileRefPrefix   = "file:"
+	secretRefPrefix = "secret:"
+)
+
+// ErrWrongPassphrase 主密码错误或令牌存储文件已损坏
+var ErrWrongPassphrase = errors.New("主密码错误或令牌存储已损坏")
+
+// IsTokenReference 令牌值是否为引用（${ENV}、file:/path、secret:name），引用本身不是敏感信息
+func IsTokenReference(value string) bool {
+	return (strings.HasPrefix(value, envRefPrefix) && strings.HasSuffix(value, "}")) ||
+		strings.HasPrefix(value, fileRefPrefix) ||
+		strings.HasPrefix(value, secretRefPrefix)
+}
+
+// ResolveToken 解析令牌值：${NAME} 读取环境变量，file:/path 读取文件内容（去掉首尾空白），
+// secret:name 读取加密令牌存储，其他值原样返回
+func ResolveToken(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envRefPrefix) && strings.HasSuffix(value, "}"):
+		name := value[len(envRefPrefix) : len(value)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return v, nil
+
+	case strings.HasPrefix(value, fileRefPrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, fileRefPrefix))
+		if err != nil {
+			return "", fmt.Errorf("读取令牌文件失败: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+
+	case strings.HasPrefix(value, secretRefPrefix):
+		name := strings.TrimPrefix(value, secretRefPrefix)
+		store := Secrets()
+		if store == nil {
+			return "", fmt.Errorf("引用了令牌存储中的 %s，但未设置主密码（%s）", name, PassphraseEnv)
+		}
+		v, ok := store.Get(name)
+		if !ok {
+			return "", fmt.Errorf("令牌存储中没有 %s", name)
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// ResolvedTokens 返回解析引用后的 Tokens
+func (p ProviderConfig) ResolvedTokens() (map[string]string, error) {
+	tokens := make(map[string]string, len(p.Tokens))
+	for key, value := range p.Tokens {
+		v, err := ResolveToken(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		tokens[key] = v
+	}
+	return tokens, nil
+}
+
+var (
+	secretKeyFuncs []func(providerType, key string) bool
+	secretsMu      sync.RWMutex
+	secrets        *SecretStore
+)
+
+// RegisterSecretKeys 注册敏感令牌的判断规则，通常由 provider 包按配置描述中的密码字段注册
+func RegisterSecretKeys(fn func(providerType, key string) bool) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secretKeyFuncs = append(secretKeyFuncs, fn)
+}
+
+// IsSecretKey 令牌是否敏感。除注册的规则外，OAuth 授权写入的 *_token、*_secret 也视为敏感
+func IsSecretKey(providerType, key string) bool {
+	if strings.HasSuffix(key, "_token") || strings.HasSuffix(key, "_secret") {
+		return true
+	}
+
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, fn := range secretKeyFuncs {
+		if fn(providerType, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretsFile 令牌存储文件：与配置文件位于同一目录的 secrets.enc
+func SecretsFile(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "secrets.enc")
+}
+
+// OpenSecrets 设置了主密码时打开配置文件对应的令牌存储，之后的 secret: 引用和 SaveConfig 都使用它；
+// 未设置主密码时返回 nil
+func OpenSecrets(configPath string) (*SecretStore, error) {
+	var store *SecretStore
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		var err error
+		if store, err = OpenSecretStore(SecretsFile(configPath), passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	secretsMu.Lock()
+	secrets = store
+	secretsMu.Unlock()
+	return store, nil
+}
+
+// Secrets 返回当前使用的令牌存储，未启用时返回 nil
+func Secrets() *SecretStore {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	return secrets
+}
+
+// SealTokens 启用令牌存储时，把配置中明文的敏感令牌移入存储，配置中改为 secret:<云盘名称>/<键> 引用。
+// 不会修改原有的 Tokens，而是替换为新的 map
+func SealTokens(cfg *Config) error {
+	store := Secrets()
+	if store == nil {
+		return nil
+	}
+
+	sealed := make(map[string]string)
+	providers := make([]ProviderConfig, len(cfg.Providers))
+	for i, p := range cfg.Providers {
+		tokens := make(map[string]string, len(p.Tokens))
+		for key, value := range p.Tokens {
+			if value != "" && !IsTokenReference(value) && IsSecretKey(p.Type, key) {
+				name := p.Name + "/" + key
+				sealed[name] = value
+				value = secretRefPrefix + name
+			}
+			tokens[key] = value
+		}
+		if p.Tokens == nil {
+			tokens = nil
+		}
+		p.Tokens = tokens
+		providers[i] = p
+	}
+
+	if len(sealed) == 0 {
+		return nil
+	}
+	if err := store.SetAll(sealed); err != nil {
+		return err
+	}
+	cfg.Providers = providers
+	return nil
+}
+
+// tokensMu 串行化 UpdateTokens，避免多个云盘同时刷新令牌时互相覆盖配置文件
+var tokensMu sync.Mutex
+
+// UpdateTokens 把提供商刷新后的令牌写回配置文件中名为 providerName 的云盘配置（如轮换后的 refresh_token）。
+// 原值为 secret: 引用时写入令牌存储，为 ${ENV}、file: 引用时无法写回，跳过并返回错误；其余写入配置文件（SaveConfig 会按需移入令牌存储）
+func UpdateTokens(path, providerName string, tokens map[string]string) error {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	cfg, err := LoadConfig(path)
+	var invalid ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		return err
+	}
+
+	index := -1
+	for i, p := range cfg.Providers {
+		if p.Name == providerName {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("未找到云盘配置: %s", providerName)
+	}
+
+	p := &cfg.Providers[index]
+	updated := make(map[string]string, len(p.Tokens))
+	for k, v := range p.Tokens {
+		updated[k] = v
+	}
+
+	changed := false
+	var skipped []string
+	for key, value := range tokens {
+		current := updated[key]
+		switch {
+		case current == value:
+		case strings.HasPrefix(current, secretRefPrefix) && Secrets() != nil:
+			if err := Secrets().Set(strings.TrimPrefix(current, secretRefPrefix), value); err != nil {
+				return err
+			}
+		case IsTokenReference(current):
+			skipped = append(skipped, key)
+		default:
+			updated[key] = value
+			changed = true
+		}
+	}
+
+	if changed {
+		p.Tokens = updated
+		if err := SaveConfig(path, cfg); err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		return fmt.Errorf("%s 引用了环境变量或文件，新令牌无法写回", strings.Join(skipped, "、"))
+	}
+	return nil
+}
+
+// SecretStore 加密的令牌存储。整个文件使用 AES-256-GCM 加密，密钥由主密码经 scrypt 派生
+type SecretStore struct {
+	path   string
+	salt   []byte
+	key    []byte
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// secretFile 令牌存储文件内容
+type secretFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// OpenSecretStore 打开令牌存储，文件不存在时创建空存储（首次写入时保存）
+func OpenSecretStore(path, passphrase string) (*SecretStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("主密码不能为空")
+	}
+
+	s := &SecretStore{path: path, values: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		s.salt = make([]byte, 16)
+		if _, err := rand.Read(s.salt); err != nil {
+			return nil, err
+		}
+		s.key, err = deriveSecretKey(passphrase, s.salt)
+		return s, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f secretFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析令牌存储失败: %w", err)
+	}
+	if f.Version != 1 || f.KDF != "scrypt" {
+		return nil, fmt.Errorf("不支持的令牌存储格式: version %d, kdf %s", f.Version, f.KDF)
+	}
+
+	s.salt = f.Salt
+	if s.key, err = deriveSecretKey(passphrase, s.salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if err := json.Unmarshal(plain, &s.values); err != nil {
+		return nil, fmt.Errorf("解析令牌存储失败: %w", err)
+	}
+	return s, nil
+}
+
+// deriveSecretKey 由主密码派生 256 位密钥
+func deriveSecretKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+// cipher 创建 AES-GCM 加密器
+func (s *SecretStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Get 读取令牌
+func (s *SecretStore) Get(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[name]
+	return v, ok
+}
+
+// Names 返回所有令牌名称（按名称排序）
+func (s *SecretStore) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set 写入令牌并保存
+func (s *SecretStore) Set(name, value string) error {
+	return s.SetAll(map[string]string{name: value})
+}
+
+// SetAll 写入多个令牌并保存
+func (s *SecretStore) SetAll(values map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, value := range values {
+		s.values[name] = value
+	}
+	return s.save()
+}
+
+// Delete 删除令牌并保存
+func (s *SecretStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[name]; !ok {
+		return fmt.Errorf("令牌存储中没有 %s", name)
+	}
+	delete(s.values, name)
+	return s.save()
+}
+
+// save 加密后写入文件，先写临时文件再重命名，避免写入中断损坏存储。调用方需持有 s.mu
+func (s *SecretStore) save() error {
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(secretFile{
+		Version: 1,
+		KDF:     "scrypt",
+		Salt:    s.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存令牌存储失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("保存令牌存储失败: %w", err)
+	}
+	return nil
+}
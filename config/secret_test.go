@@ -0,0 +1,185 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"CloudFileSync/config"
+)
+
+func TestResolveToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(file, []byte("from-file\n"), 0600)
+	t.Setenv("CFS_TEST_TOKEN", "from-env")
+
+	tests := []struct {
+		value, want string
+	}{
+		{"plain", "plain"},
+		{"${CFS_TEST_TOKEN}", "from-env"},
+		{"file:" + file, "from-file"},
+		{"$CFS_TEST_TOKEN", "$CFS_TEST_TOKEN"},
+	}
+	for _, tt := range tests {
+		got, err := config.ResolveToken(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveToken(%q) = %q, %v，期望 %q", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"${CFS_TEST_MISSING}", "file:" + file + ".missing"} {
+		if _, err := config.ResolveToken(value); err == nil {
+			t.Errorf("ResolveToken(%q) 期望返回错误", value)
+		}
+	}
+}
+
+func TestSecretStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, err := config.OpenSecretStore(path, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("令牌存储权限为 %v，期望 0600", info.Mode().Perm())
+	}
+
+	store, err = config.OpenSecretStore(path, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := store.Get("a"); !ok || v != "1" {
+		t.Errorf("读取到 %q, %v", v, ok)
+	}
+
+	if _, err := config.OpenSecretStore(path, "wrong"); !errors.Is(err, config.ErrWrongPassphrase) {
+		t.Errorf("主密码错误时返回 %v", err)
+	}
+}
+
+func TestSaveConfigSealsTokens(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{"watch_dir": "`+dir+`"}`), 0644)
+
+	t.Setenv(config.PassphraseEnv, "passphrase")
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 测试结束后关闭令牌存储
+	t.Cleanup(func() {
+		os.Unsetenv(config.PassphraseEnv)
+		config.OpenSecrets(path)
+	})
+
+	cfg.Providers = []config.ProviderConfig{{
+		Type:   "onedrive",
+		Name:   "od",
+		Tokens: map[string]string{"access_token": "secret-value", "drive_id": "d", "client_secret": "${CFS_SECRET}"},
+	}}
+	tokens := cfg.Providers[0].Tokens
+	if err := config.SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if tokens["access_token"] != "secret-value" {
+		t.Error("SaveConfig 修改了原有的 Tokens")
+	}
+	got := cfg.Providers[0].Tokens
+	if got["access_token"] != "secret:od/access_token" || got["drive_id"] != "d" || got["client_secret"] != "${CFS_SECRET}" {
+		t.Errorf("保存后的 Tokens 为 %v", got)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret-value") {
+		t.Error("配置文件中仍有明文令牌")
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("配置文件权限为 %v，期望 0600", info.Mode().Perm())
+	}
+
+	v, err := config.ResolveToken(got["access_token"])
+	if err != nil || v != "secret-value" {
+		t.Errorf("解析 secret 引用得到 %q, %v", v, err)
+	}
+}
+
+func TestUpdateTokens(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	cfg := &config.Config{
+		WatchDir: dir,
+		Providers: []config.ProviderConfig{{
+			Type:   "onedrive",
+			Name:   "od",
+			Tokens: map[string]string{"access_token": "old", "refresh_token": "old-refresh", "client_id": "${CFS_CLIENT}"},
+		}},
+	}
+	if err := config.SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.UpdateTokens(path, "od", map[string]string{"access_token": "new", "refresh_token": "new-refresh"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := config.LoadConfig(path)
+	got := loaded.Providers[0].Tokens
+	if got["access_token"] != "new" || got["refresh_token"] != "new-refresh" || got["client_id"] != "${CFS_CLIENT}" {
+		t.Errorf("写回后的 Tokens 为 %v", got)
+	}
+
+	// 环境变量引用无法写回
+	if err := config.UpdateTokens(path, "od", map[string]string{"client_id": "x"}); err == nil {
+		t.Error("覆盖环境变量引用时期望返回错误")
+	}
+	if err := config.UpdateTokens(path, "missing", map[string]string{"access_token": "x"}); err == nil {
+		t.Error("云盘配置不存在时期望返回错误")
+	}
+}
+
+func TestUpdateTokensInSecretStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{"watch_dir": "`+dir+`"}`), 0644)
+
+	t.Setenv(config.PassphraseEnv, "passphrase")
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv(config.PassphraseEnv)
+		config.OpenSecrets(path)
+	})
+
+	cfg.Providers = []config.ProviderConfig{{Type: "onedrive", Name: "od", Tokens: map[string]string{"refresh_token": "old"}}}
+	if err := config.SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// 引用令牌存储的令牌写入存储，配置文件不变
+	if err := config.UpdateTokens(path, "od", map[string]string{"refresh_token": "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("配置文件被修改:\n%s", after)
+	}
+	if v, err := config.ResolveToken("secret:od/refresh_token"); err != nil || v != "new" {
+		t.Errorf("令牌存储中的值为 %q, %v", v, err)
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// FieldError 字段级校验错误，Field 为 JSON 中的字段路径，
+// 如 "delay_time"、"providers[1].name"、"providers[0].tokens.access_token"
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error 实现 error 接口
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors 配置中的所有校验错误
+type ValidationErrors []FieldError
+
+// Error 实现 error 接口
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Error()
+	}
+	return "配置无效: " + strings.Join(messages, "; ")
+}
+
+// Add 添加一个字段错误
+func (e *ValidationErrors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Provider 返回第 index 个云盘配置的字段错误
+func (e ValidationErrors) Provider(index int) ValidationErrors {
+	prefix := ProviderField(index, "")
+	var errs ValidationErrors
+	for _, fe := range e {
+		if strings.HasPrefix(fe.Field, prefix) {
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// Validator 附加的配置检查
+type Validator func(c *Config) ValidationErrors
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []Validator
+)
+
+// RegisterValidator 注册附加的配置检查，通常在其他包的 init 中调用。
+// 云盘类型和 tokens 由 provider 包、冲突处理策略由 syncer 包检查，避免 config 包依赖它们
+func RegisterValidator(v Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators = append(validators, v)
+}
+
+// ProviderField 第 index 个云盘配置中字段的路径
+func ProviderField(index int, field string) string {
+	return fmt.Sprintf("providers[%d].%s", index, field)
+}
+
+// Validate 检查配置，返回 ValidationErrors 或 nil
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(c.WatchDir) == "" {
+		errs.Add("watch_dir", "监听目录不能为空")
+	} else if info, err := os.Stat(c.WatchDir); err != nil {
+		errs.Add("watch_dir", "监听目录不存在")
+	} else if !info.IsDir() {
+		errs.Add("watch_dir", "监听目录不是目录")
+	}
+
+	if c.DelayTime < 0 {
+		errs.Add("delay_time", "延迟时间不能为负数")
+	}
+
+	names := make(map[string]int)
+	for i, p := range c.Providers {
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			errs.Add(ProviderField(i, "name"), "名称不能为空")
+		} else if first, ok := names[name]; ok {
+			errs.Add(ProviderField(i, "name"), fmt.Sprintf("名称「%s」与第 %d 个云盘重复", name, first+1))
+		} else {
+			names[name] = i
+		}
+
+		if strings.TrimSpace(p.Type) == "" {
+			errs.Add(ProviderField(i, "type"), "类型不能为空")
+		}
+
+		// 留空表示云盘根目录
+		if p.Target != "" && !strings.HasPrefix(p.Target, "/") {
+			errs.Add(ProviderField(i, "target"), "目标目录必须以 / 开头")
+		}
+	}
+
+	if c.Web != nil {
+		if c.Web.Username != "" && c.Web.Password == "" {
+			errs.Add("web.password", "设置了用户名时必须设置密码")
+		}
+		if c.Web.Password != "" && c.Web.Username == "" {
+			errs.Add("web.username", "设置了密码时必须设置用户名")
+		}
+		if _, err := ResolveToken(c.Web.Password); err != nil {
+			errs.Add("web.password", err.Error())
+		}
+		if _, err := ResolveToken(c.Web.Token); err != nil {
+			errs.Add("web.token", err.Error())
+		}
+	}
+
+	validatorsMu.RLock()
+	for _, v := range validators {
+		errs = append(errs, v(c)...)
+	}
+	validatorsMu.RUnlock()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
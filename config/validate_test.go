@@ -0,0 +1,82 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"CloudFileSync/config"
+	_ "CloudFileSync/provider"
+	_ "CloudFileSync/syncer"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0644)
+
+	cfg := &config.Config{
+		WatchDir:         file,
+		DelayTime:        -1,
+		ConflictStrategy: "unknown",
+		Providers: []config.ProviderConfig{
+			{Type: "aliyun", Name: "网盘", Tokens: map[string]string{"access_token": "x"}, Target: "/a"},
+			{Type: "baidu", Name: "网盘", Tokens: map[string]string{}, Target: "b"},
+			{Type: "nope", Name: "其他"},
+			{Type: "s3", Name: "s3", Tokens: map[string]string{"bucket": "b"}},
+		},
+	}
+
+	var errs config.ValidationErrors
+	if !errors.As(cfg.Validate(), &errs) {
+		t.Fatalf("期望返回 ValidationErrors")
+	}
+
+	var fields []string
+	for _, fe := range errs {
+		fields = append(fields, fe.Field)
+	}
+	want := []string{
+		"watch_dir",
+		"delay_time",
+		"providers[1].name",
+		"providers[1].target",
+		"providers[1].tokens",
+		"providers[2].type",
+		"providers[3].tokens.endpoint",
+		"providers[3].tokens.access_key",
+		"providers[3].tokens.secret_key",
+		"conflict_strategy",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("错误字段为 %v\n期望 %v", fields, want)
+	}
+
+	if got := errs.Provider(3); len(got) != 3 || got[0].Field != "providers[3].tokens.endpoint" {
+		t.Errorf("Provider(3) = %v", got)
+	}
+	if got := errs.Provider(0); len(got) != 0 {
+		t.Errorf("Provider(0) = %v", got)
+	}
+
+	cfg = &config.Config{WatchDir: dir, Providers: cfg.Providers[:1]}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("有效配置返回错误: %v", err)
+	}
+}
+
+func TestLoadConfigReturnsInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"watch_dir": "", "delay_time": 3}`), 0644)
+
+	cfg, err := config.LoadConfig(path)
+	var errs config.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "watch_dir" {
+		t.Fatalf("期望 watch_dir 错误，实际为 %v", err)
+	}
+	if cfg == nil || cfg.DelayTime != 3 {
+		t.Fatalf("校验失败时应同时返回解析出的配置")
+	}
+}
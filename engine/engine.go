@@ -0,0 +1,498 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+	"CloudFileSync/syncer"
+	"CloudFileSync/watcher"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrRunning 引擎已在运行
+var ErrRunning = errors.New("同步服务已在运行")
+
+// ErrNotRunning 引擎未运行
+var ErrNotRunning = errors.New("同步服务未运行")
+
+// ErrConflictNotFound 冲突不存在，或所属云盘已从配置中删除
+var ErrConflictNotFound = errors.New("冲突不存在或云盘配置已删除")
+
+// Engine 同步引擎：监听目录变化并同步到已启用的云盘。
+// 运行中可通过 Apply 应用新配置，只重建发生变化的云盘和监听器，已排队的文件事件不会丢失
+type Engine struct {
+	lifecycleMu sync.Mutex // 串行化 Start、Stop、Apply，Apply 初始化云盘时不持有 mu
+
+	mu      sync.Mutex
+	cfg     *config.Config
+	running bool
+	state   *syncer.State
+	syncer  *syncer.Syncer
+	targets []syncer.Target // 按配置顺序排列，替换而不修改，处理事件时可直接使用快照
+
+	// 使用 targets 快照期间持有读锁（先于 mu 获取），替换后获取写锁等待旧快照用完再关闭不再使用的云盘
+	targetsInUse sync.RWMutex
+
+	watcher     *watcher.Watcher
+	watcherStop chan struct{}
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+
+	// 文件事件队列，不随监听器重建或引擎停止而清空
+	queueMu sync.Mutex
+	queue   []watcher.FileEvent
+	notify  chan struct{}
+}
+
+// New 创建同步引擎，调用 Start 后开始同步
+func New(cfg *config.Config) *Engine {
+	return &Engine{
+		cfg:    cfg,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Config 返回当前配置，调用方不应修改返回值
+func (e *Engine) Config() *config.Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cfg
+}
+
+// Running 返回引擎是否在运行
+func (e *Engine) Running() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.running
+}
+
+// State 返回同步状态：运行中返回与同步共享的状态，否则从状态文件加载
+func (e *Engine) State() (*syncer.State, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running {
+		return e.state, nil
+	}
+	return syncer.LoadState(e.cfg.GetStateFile())
+}
+
+// Start 初始化云盘、加载同步状态并开始监听
+func (e *Engine) Start() error {
+	e.lifecycleMu.Lock()
+	defer e.lifecycleMu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running {
+		return ErrRunning
+	}
+	if err := e.cfg.Validate(); err != nil {
+		return err
+	}
+
+	// 启动时跳过初始化失败的云盘，其余云盘照常同步
+	targets, _ := buildTargets(e.cfg.Providers, nil)
+	if len(targets) == 0 {
+		return errors.New("没有可用的云盘提供商，请检查配置文件")
+	}
+
+	state, err := syncer.LoadState(e.cfg.GetStateFile())
+	if err != nil {
+		return fmt.Errorf("加载同步状态失败: %w", err)
+	}
+
+	s, err := syncer.New(e.cfg, state)
+	if err != nil {
+		return fmt.Errorf("创建同步器失败: %w", err)
+	}
+
+	w, err := watcher.NewWatcher(e.cfg.WatchDir, e.cfg.GetDelayDuration())
+	if err != nil {
+		return fmt.Errorf("创建文件监听器失败: %w", err)
+	}
+
+	e.state = state
+	e.syncer = s
+	e.targets = targets
+	e.startWatcher(w)
+
+	e.stopChan = make(chan struct{})
+	e.wg.Add(1)
+	go e.run(e.stopChan)
+
+	// 上次停止时未处理完的事件继续处理
+	e.wakeUp()
+
+	e.running = true
+	log.Printf("同步服务已启动，监听目录: %s，云盘 %d 个", e.cfg.WatchDir, len(targets))
+	return nil
+}
+
+// ResolveConflict 按指定策略处理一条待处理冲突。运行中使用同步服务的同步器和云盘，
+// 与文件事件的同步共享同步状态和路径锁；未运行时从状态文件加载并临时创建云盘
+func (e *Engine) ResolveConflict(id string, strategy syncer.Strategy) error {
+	e.targetsInUse.RLock()
+	defer e.targetsInUse.RUnlock()
+
+	e.mu.Lock()
+	cfg, s, targets := e.cfg, e.syncer, e.targets
+	running := e.running
+	e.mu.Unlock()
+
+	if !running {
+		state, err := syncer.LoadState(cfg.GetStateFile())
+		if err != nil {
+			return fmt.Errorf("加载同步状态失败: %w", err)
+		}
+		if s, err = syncer.New(cfg, state); err != nil {
+			return fmt.Errorf("创建同步器失败: %w", err)
+		}
+		targets = nil
+	}
+
+	var providerName string
+	for _, c := range s.State().Conflicts() {
+		if c.ID == id {
+			providerName = c.Provider
+			break
+		}
+	}
+	if providerName == "" {
+		return ErrConflictNotFound
+	}
+
+	for _, t := range targets {
+		if t.Config.Name == providerName {
+			return s.Resolve(t, id, strategy)
+		}
+	}
+	if running {
+		return ErrConflictNotFound
+	}
+
+	for _, p := range cfg.Providers {
+		if p.Name != providerName {
+			continue
+		}
+		pvd, err := provider.NewProvider(p)
+		if err != nil {
+			return fmt.Errorf("初始化云盘提供商失败: %w", err)
+		}
+		defer provider.Close(pvd)
+		return s.Resolve(syncer.Target{Config: p, Provider: pvd}, id, strategy)
+	}
+	return ErrConflictNotFound
+}
+
+// Stop 停止监听和同步，等待正在处理的事件完成。队列中尚未处理的事件在下次 Start 后继续处理
+func (e *Engine) Stop() error {
+	e.lifecycleMu.Lock()
+	defer e.lifecycleMu.Unlock()
+
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return ErrNotRunning
+	}
+	e.running = false
+	e.stopWatcher()
+	close(e.stopChan)
+	targets := e.targets
+	e.targets = nil
+	e.mu.Unlock()
+
+	e.wg.Wait()
+	e.closeTargets(targets)
+	log.Println("同步服务已停止")
+	return nil
+}
+
+// Apply 应用新配置：对比新旧配置，只重建受影响的部分，替换下来的云盘在使用完后关闭。
+// 未运行时只记录配置；加载状态、创建监听器或初始化任一云盘失败时保持原配置继续运行并返回错误
+func (e *Engine) Apply(cfg *config.Config) error {
+	e.lifecycleMu.Lock()
+	defer e.lifecycleMu.Unlock()
+
+	e.mu.Lock()
+	old, state, current := e.cfg, e.state, e.targets
+	if reflect.DeepEqual(old, cfg) {
+		e.mu.Unlock()
+		return nil
+	}
+	if !e.running {
+		e.cfg = cfg
+		e.mu.Unlock()
+		return nil
+	}
+	e.mu.Unlock()
+
+	// 先完成可能失败或耗时的步骤（初始化云盘可能访问网络），不持有 e.mu，处理事件和查询状态不受影响。
+	// lifecycleMu 保证期间不会启动、停止或应用其他配置，全部成功后再替换
+	if cfg.GetStateFile() != old.GetStateFile() {
+		var err error
+		if state, err = syncer.LoadState(cfg.GetStateFile()); err != nil {
+			return fmt.Errorf("加载同步状态失败: %w", err)
+		}
+	}
+
+	s, err := syncer.New(cfg, state)
+	if err != nil {
+		return fmt.Errorf("创建同步器失败: %w", err)
+	}
+
+	targets, err := buildTargets(cfg.Providers, current)
+	if err != nil {
+		closeTargets(unusedTargets(targets, current))
+		return err
+	}
+
+	var w *watcher.Watcher
+	if cfg.WatchDir != old.WatchDir {
+		if w, err = watcher.NewWatcher(cfg.WatchDir, cfg.GetDelayDuration()); err != nil {
+			closeTargets(unusedTargets(targets, current))
+			return fmt.Errorf("创建文件监听器失败: %w", err)
+		}
+	}
+
+	e.mu.Lock()
+	e.cfg = cfg
+	e.state = state
+	e.syncer = s
+
+	if w != nil {
+		e.stopWatcher()
+		e.startWatcher(w)
+		log.Printf("监听目录已更换: %s -> %s", old.WatchDir, cfg.WatchDir)
+	} else if cfg.DelayTime != old.DelayTime {
+		e.watcher.SetDelay(cfg.GetDelayDuration())
+		log.Printf("延迟时间已更新: %d 秒 -> %d 秒", old.DelayTime, cfg.DelayTime)
+	}
+
+	if cfg.ConflictStrategy != old.ConflictStrategy {
+		strategy, _ := syncer.ParseStrategy(cfg.ConflictStrategy)
+		log.Printf("冲突处理策略已更新: %s", strategy)
+	}
+
+	e.targets = targets
+	e.mu.Unlock()
+
+	e.closeTargets(unusedTargets(current, targets))
+	return nil
+}
+
+// closeTargets 等待正在使用旧快照的同步完成后关闭云盘。调用方不能持有 e.mu
+func (e *Engine) closeTargets(targets []syncer.Target) {
+	if len(targets) == 0 {
+		return
+	}
+	e.targetsInUse.Lock()
+	e.targetsInUse.Unlock()
+	closeTargets(targets)
+}
+
+// closeTargets 关闭云盘，失败只记录日志
+func closeTargets(targets []syncer.Target) {
+	for _, t := range targets {
+		if err := provider.Close(t.Provider); err != nil {
+			log.Printf("关闭云盘提供商失败 [%s]: %v", t.Config.Name, err)
+			continue
+		}
+		log.Printf("云盘提供商已关闭: %s", t.Config.Name)
+	}
+}
+
+// unusedTargets 返回 from 中没有被 to 沿用的云盘，沿用条件与 buildTargets 相同
+func unusedTargets(from, to []syncer.Target) []syncer.Target {
+	var unused []syncer.Target
+	for _, t := range from {
+		kept := false
+		for _, u := range to {
+			if u.Config.Name == t.Config.Name && reflect.DeepEqual(u.Config, t.Config) {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			unused = append(unused, t)
+		}
+	}
+	return unused
+}
+
+// buildTargets 初始化已启用的云盘。配置未变化的云盘沿用 current 中的实例，新增或修改过的云盘重新创建。
+// 初始化失败的云盘不在返回的列表中，错误合并后返回
+func buildTargets(providers []config.ProviderConfig, current []syncer.Target) ([]syncer.Target, error) {
+	existing := make(map[string]syncer.Target, len(current))
+	for _, t := range current {
+		existing[t.Config.Name] = t
+	}
+
+	targets := make([]syncer.Target, 0, len(providers))
+	var errs []error
+	for _, p := range providers {
+		if !p.Enable {
+			continue
+		}
+
+		t, ok := existing[p.Name]
+		delete(existing, p.Name)
+		if ok && reflect.DeepEqual(t.Config, p) {
+			targets = append(targets, t)
+			continue
+		}
+
+		pvd, err := provider.NewProvider(p)
+		if err != nil {
+			log.Printf("初始化云盘提供商失败 [%s]: %v", p.Name, err)
+			errs = append(errs, fmt.Errorf("初始化云盘提供商失败 [%s]: %w", p.Name, err))
+			continue
+		}
+		targets = append(targets, syncer.Target{Config: p, Provider: pvd})
+
+		if ok {
+			log.Printf("云盘提供商已重建: %s (目标目录: %s)", pvd.Name(), p.Target)
+		} else {
+			log.Printf("云盘提供商已加载: %s (目标目录: %s)", pvd.Name(), p.Target)
+		}
+	}
+
+	return targets, errors.Join(errs...)
+}
+
+// startWatcher 启动监听器，并把它发出的事件转入队列。调用方需持有 e.mu
+func (e *Engine) startWatcher(w *watcher.Watcher) {
+	stop := make(chan struct{})
+	e.watcher = w
+	e.watcherStop = stop
+
+	w.Start()
+	go func() {
+		for {
+			select {
+			case event := <-w.Events():
+				e.enqueue(event)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopWatcher 停止当前监听器，已发出但尚未转入队列的事件和防抖等待中的事件一并转入。调用方需持有 e.mu
+func (e *Engine) stopWatcher() {
+	pending := e.watcher.Stop()
+	close(e.watcherStop)
+
+	for {
+		select {
+		case event := <-e.watcher.Events():
+			e.enqueue(event)
+		default:
+			for _, event := range pending {
+				e.enqueue(event)
+			}
+			return
+		}
+	}
+}
+
+// enqueue 文件事件加入队列
+func (e *Engine) enqueue(event watcher.FileEvent) {
+	e.queueMu.Lock()
+	e.queue = append(e.queue, event)
+	e.queueMu.Unlock()
+	e.wakeUp()
+}
+
+// dequeue 取出队首的文件事件
+func (e *Engine) dequeue() (watcher.FileEvent, bool) {
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+
+	if len(e.queue) == 0 {
+		return watcher.FileEvent{}, false
+	}
+	event := e.queue[0]
+	e.queue = e.queue[1:]
+	return event, true
+}
+
+// wakeUp 通知处理协程队列中有新事件
+func (e *Engine) wakeUp() {
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run 依次处理队列中的文件事件，直到 stop 关闭
+func (e *Engine) run(stop chan struct{}) {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-e.notify:
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			event, ok := e.dequeue()
+			if !ok {
+				break
+			}
+			e.handle(event)
+		}
+	}
+}
+
+// handle 把一个文件事件同步到所有云盘，使用处理时的配置
+func (e *Engine) handle(event watcher.FileEvent) {
+	e.targetsInUse.RLock()
+	defer e.targetsInUse.RUnlock()
+
+	e.mu.Lock()
+	s := e.syncer
+	targets := e.targets
+	watchDir := e.cfg.WatchDir
+	e.mu.Unlock()
+
+	// 更换监听目录前排队的事件
+	rel, err := filepath.Rel(watchDir, event.Path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		log.Printf("跳过监听目录之外的文件事件: %s", event.Path)
+		return
+	}
+
+	log.Printf("处理文件事件: %s [%s]", event.Path, event.Op)
+
+	removed := event.Op&fsnotify.Remove == fsnotify.Remove
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t syncer.Target) {
+			defer wg.Done()
+
+			if err := s.SyncFile(t, event.Path, removed); err != nil {
+				log.Printf("[%s] 同步失败: %v", t.Provider.Name(), err)
+			}
+		}(t)
+	}
+	wg.Wait()
+}
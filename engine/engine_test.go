@@ -0,0 +1,527 @@
+package engine_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"CloudFileSync/config"
+	"CloudFileSync/engine"
+	"CloudFileSync/events"
+	"CloudFileSync/history"
+	"CloudFileSync/provider"
+	"CloudFileSync/syncer"
+)
+
+// closed 已关闭的 closing 云盘（根目录 -> 关闭次数）
+var (
+	closedMu sync.Mutex
+	closed   = make(map[string]int)
+)
+
+// closingProvider 记录关闭次数的本地目录云盘
+type closingProvider struct {
+	provider.Provider
+	root string
+}
+
+func (p *closingProvider) Close() error {
+	closedMu.Lock()
+	defer closedMu.Unlock()
+	closed[p.root]++
+	return nil
+}
+
+func closedCount(root string) int {
+	closedMu.Lock()
+	defer closedMu.Unlock()
+	return closed[root]
+}
+
+func init() {
+	provider.Register("closing", func(tokens map[string]string) (provider.Provider, error) {
+		p, err := provider.NewLocalProvider(tokens)
+		if err != nil {
+			return nil, err
+		}
+		return &closingProvider{Provider: p, root: tokens["root"]}, nil
+	}, provider.Schema{Fields: []provider.Field{{Key: "root", Required: true}}})
+}
+
+// localProvider 同步到本地目录的云盘配置
+func localProvider(name, root string) config.ProviderConfig {
+	return config.ProviderConfig{
+		Type:   "local",
+		Name:   name,
+		Enable: true,
+		Tokens: map[string]string{"root": root},
+		Target: "/",
+	}
+}
+
+// waitFile 等待文件出现
+func waitFile(t *testing.T, path string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("等待 %s 超时", path)
+}
+
+func TestApplyProviders(t *testing.T) {
+	watchDir := t.TempDir()
+	rootA, rootB := t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", rootA)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	os.WriteFile(filepath.Join(watchDir, "1.txt"), []byte("1"), 0644)
+	waitFile(t, filepath.Join(rootA, "1.txt"))
+
+	// 新增云盘 b，a 不变
+	next := *cfg
+	next.Providers = []config.ProviderConfig{localProvider("a", rootA), localProvider("b", rootB)}
+	if err := e.Apply(&next); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(watchDir, "2.txt"), []byte("2"), 0644)
+	waitFile(t, filepath.Join(rootA, "2.txt"))
+	waitFile(t, filepath.Join(rootB, "2.txt"))
+
+	// 移除云盘 a
+	last := next
+	last.Providers = []config.ProviderConfig{localProvider("b", rootB)}
+	if err := e.Apply(&last); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(watchDir, "3.txt"), []byte("3"), 0644)
+	waitFile(t, filepath.Join(rootB, "3.txt"))
+	if _, err := os.Stat(filepath.Join(rootA, "3.txt")); !os.IsNotExist(err) {
+		t.Errorf("已移除的云盘仍在同步: %v", err)
+	}
+}
+
+func TestApplyKeepsQueuedEvents(t *testing.T) {
+	watchDir, root := t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		DelayTime: 1,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", root)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	// 文件还在防抖等待中时修改延迟和冲突处理策略，事件仍然会被同步
+	os.WriteFile(filepath.Join(watchDir, "a.txt"), []byte("a"), 0644)
+	next := *cfg
+	next.DelayTime = 0
+	next.ConflictStrategy = "local"
+	if err := e.Apply(&next); err != nil {
+		t.Fatal(err)
+	}
+	waitFile(t, filepath.Join(root, "a.txt"))
+
+	// 停止后可以重新启动
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(watchDir, "b.txt"), []byte("b"), 0644)
+	waitFile(t, filepath.Join(root, "b.txt"))
+}
+
+func TestStopKeepsDebouncedEvents(t *testing.T) {
+	watchDir, root := t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		DelayTime: 60,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", root)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	// 停止时还在防抖等待中的事件转入队列，重新启动后立即同步，不再等待延迟
+	os.WriteFile(filepath.Join(watchDir, "a.txt"), []byte("a"), 0644)
+	time.Sleep(200 * time.Millisecond)
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitFile(t, filepath.Join(root, "a.txt"))
+}
+
+func TestApplyWatchDir(t *testing.T) {
+	oldDir, newDir, root := t.TempDir(), t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  oldDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", root)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	next := *cfg
+	next.WatchDir = newDir
+	if err := e.Apply(&next); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(newDir, "new.txt"), []byte("n"), 0644)
+	waitFile(t, filepath.Join(root, "new.txt"))
+
+	// 监听目录不存在时保持原配置
+	bad := next
+	bad.WatchDir = filepath.Join(newDir, "missing")
+	if err := e.Apply(&bad); err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if got := e.Config().WatchDir; got != newDir {
+		t.Errorf("监听目录为 %s，期望保持 %s", got, newDir)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	watchDir, rootA, rootB := t.TempDir(), t.TempDir(), t.TempDir()
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", rootA)},
+	}
+	if err := config.SaveConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	stop, err := e.WatchConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// 无效的配置不会被应用
+	invalid := *cfg
+	invalid.DelayTime = -1
+	config.SaveConfig(path, &invalid)
+	time.Sleep(time.Second)
+	if e.Config().DelayTime != 0 {
+		t.Fatal("应用了无效的配置")
+	}
+
+	next := *cfg
+	next.Providers = []config.ProviderConfig{localProvider("b", rootB)}
+	if err := config.SaveConfig(path, &next); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for e.Config().Providers[0].Name != "b" {
+		if time.Now().After(deadline) {
+			t.Fatal("等待重新加载配置超时")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	os.WriteFile(filepath.Join(watchDir, "b.txt"), []byte("b"), 0644)
+	waitFile(t, filepath.Join(rootB, "b.txt"))
+}
+
+func TestSyncEvents(t *testing.T) {
+	watchDir, root := t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", root)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	_, ch, cancel := events.Default.Subscribe(0)
+	defer cancel()
+
+	os.WriteFile(filepath.Join(watchDir, "events.txt"), []byte("e"), 0644)
+
+	// 创建和写入可能分别触发一轮事件，按各类事件首次出现的顺序比较
+	want := []events.Kind{events.KindDetected, events.KindQueued, events.KindUploading, events.KindDone}
+	var got []events.Kind
+	seen := make(map[events.Kind]bool)
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case ev := <-ch:
+			if ev.Path != "events.txt" || seen[ev.Kind] {
+				continue
+			}
+			seen[ev.Kind] = true
+			if ev.Kind == events.KindDone && ev.Provider != "a" {
+				t.Errorf("完成事件的云盘为 %q", ev.Provider)
+			}
+			got = append(got, ev.Kind)
+		case <-timeout:
+			t.Fatalf("等待同步事件超时，已收到 %v", got)
+		}
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("事件顺序为 %v，期望 %v", got, want)
+		}
+	}
+
+	// 完成事件在写入同步历史之后发布
+	page, err := history.New(cfg.GetHistoryFile()).Query(history.Query{Provider: "a", Result: history.ResultSuccess})
+	if err != nil || page.Total == 0 {
+		t.Fatalf("同步历史 = %+v, %v", page, err)
+	}
+	// 创建时文件可能还是空的，不比较字节数
+	if r := page.Records[0]; r.Path != "events.txt" || r.Action != "upload" {
+		t.Fatalf("同步历史记录 = %+v", r)
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	watchDir, root := t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:         watchDir,
+		ConflictStrategy: "manual",
+		StateFile:        filepath.Join(t.TempDir(), "state.json"),
+		Providers:        []config.ProviderConfig{localProvider("a", root)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	state, _ := e.State()
+	waitFor := func(what string, done func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatalf("等待%s超时", what)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	local := filepath.Join(watchDir, "c.txt")
+	os.WriteFile(local, []byte("v1"), 0644)
+	waitFor("首次同步", func() bool {
+		_, ok := state.Get("a", "c.txt")
+		return ok
+	})
+
+	// 本地和远程都修改
+	os.WriteFile(filepath.Join(root, "c.txt"), []byte("remote v2"), 0644)
+	os.WriteFile(local, []byte("local v2"), 0644)
+	waitFor("检测到冲突", func() bool { return len(state.Conflicts()) == 1 })
+
+	if err := e.ResolveConflict("missing", syncer.StrategyRemote); !errors.Is(err, engine.ErrConflictNotFound) {
+		t.Fatalf("冲突不存在时返回 %v", err)
+	}
+	if err := e.ResolveConflict(state.Conflicts()[0].ID, syncer.StrategyRemote); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != "remote v2" {
+		t.Fatalf("本地文件内容为 %q", data)
+	}
+	if len(state.Conflicts()) != 0 {
+		t.Fatalf("冲突未移除: %+v", state.Conflicts())
+	}
+}
+
+func TestKeepBothWithTwoTargets(t *testing.T) {
+	watchDir, rootA, rootB := t.TempDir(), t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", rootA), localProvider("b", rootB)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	state, _ := e.State()
+	local := filepath.Join(watchDir, "c.txt")
+	os.WriteFile(local, []byte("v1"), 0644)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, okA := state.Get("a", "c.txt")
+		_, okB := state.Get("b", "c.txt")
+		if okA && okB {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("等待首次同步超时")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 只有云盘 a 的远程文件被修改：a 按 keep_both 处理冲突时，b 可能正在上传同一个文件
+	os.WriteFile(filepath.Join(rootA, "c.txt"), []byte("remote v2"), 0644)
+	os.WriteFile(local, []byte("local v2"), 0644)
+
+	copies, _ := filepath.Glob(filepath.Join(watchDir, "c (conflict *).txt"))
+	for len(copies) == 0 && time.Now().Before(deadline.Add(5*time.Second)) {
+		time.Sleep(20 * time.Millisecond)
+		copies, _ = filepath.Glob(filepath.Join(watchDir, "c (conflict *).txt"))
+	}
+	if len(copies) != 1 {
+		t.Fatalf("冲突副本: %v", copies)
+	}
+	name := filepath.Base(copies[0])
+
+	// 两个云盘最终都与本地一致：原路径为 a 的远程版本，冲突副本为本地版本
+	for _, root := range []string{rootA, rootB} {
+		waitFile(t, filepath.Join(root, name))
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			data, _ := os.ReadFile(filepath.Join(root, "c.txt"))
+			copyData, _ := os.ReadFile(filepath.Join(root, name))
+			if string(data) == "remote v2" && string(copyData) == "local v2" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s 中 c.txt 为 %q，冲突副本为 %q", root, data, copyData)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+}
+
+func TestApplyClosesTargets(t *testing.T) {
+	watchDir, rootA, rootB := t.TempDir(), t.TempDir(), t.TempDir()
+	closingA := localProvider("a", rootA)
+	closingA.Type = "closing"
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{closingA},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	// 配置未变化的云盘沿用原实例，不会关闭
+	next := *cfg
+	next.Providers = []config.ProviderConfig{closingA, localProvider("b", rootB)}
+	if err := e.Apply(&next); err != nil {
+		t.Fatal(err)
+	}
+	if n := closedCount(rootA); n != 0 {
+		t.Fatalf("沿用的云盘被关闭 %d 次", n)
+	}
+
+	// 修改配置后重建，旧实例关闭
+	changed := closingA
+	changed.Target = "/sub"
+	last := next
+	last.Providers = []config.ProviderConfig{changed, localProvider("b", rootB)}
+	if err := e.Apply(&last); err != nil {
+		t.Fatal(err)
+	}
+	if n := closedCount(rootA); n != 1 {
+		t.Fatalf("替换的云盘关闭 %d 次，期望 1", n)
+	}
+
+	// 停止时关闭全部云盘
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if n := closedCount(rootA); n != 2 {
+		t.Fatalf("停止后关闭 %d 次，期望 2", n)
+	}
+}
+
+func TestApplyProviderFailure(t *testing.T) {
+	watchDir, rootA, rootC := t.TempDir(), t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		WatchDir:  watchDir,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Providers: []config.ProviderConfig{localProvider("a", rootA)},
+	}
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	// b 的根目录不存在，初始化失败：返回错误，已创建的 c 被关闭，继续使用原配置
+	closingC := localProvider("c", rootC)
+	closingC.Type = "closing"
+	next := *cfg
+	next.Providers = []config.ProviderConfig{
+		localProvider("a", rootA),
+		localProvider("b", filepath.Join(t.TempDir(), "missing")),
+		closingC,
+	}
+	if err := e.Apply(&next); err == nil {
+		t.Fatal("期望初始化失败时返回错误")
+	}
+	if e.Config() != cfg {
+		t.Error("初始化失败后配置被替换")
+	}
+	if n := closedCount(rootC); n != 1 {
+		t.Errorf("未使用的新云盘关闭 %d 次，期望 1", n)
+	}
+
+	os.WriteFile(filepath.Join(watchDir, "a.txt"), []byte("a"), 0644)
+	waitFile(t, filepath.Join(rootA, "a.txt"))
+	if _, err := os.Stat(filepath.Join(rootC, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("失败的配置不应生效: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"CloudFileSync/config"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay 配置文件变化后等待多久再重新加载，合并编辑器保存时的多次写入
+const reloadDelay = 500 * time.Millisecond
+
+// Reload 重新加载配置文件并应用。配置无效时保持当前配置并返回 config.ValidationErrors
+func (e *Engine) Reload(path string) error {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return e.Apply(cfg)
+}
+
+// WatchConfig 监听配置文件的变化和 SIGHUP 信号，自动重新加载配置。返回的函数用于停止监听
+func (e *Engine) WatchConfig(path string) (func(), error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// 监听所在目录而不是文件本身：编辑器通常写入临时文件后重命名覆盖，原文件的监听会失效
+	if err := fsWatcher.Add(filepath.Dir(path)); err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	stop := make(chan struct{})
+	changed := make(chan struct{}, 1)
+	var timer *time.Timer
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-fsWatcher.Events:
+				if !ok {
+					return
+				}
+				if event.Name != path || event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() {
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				})
+
+			case <-changed:
+				log.Printf("配置文件已修改，重新加载: %s", path)
+				e.reload(path)
+
+			case <-sigChan:
+				log.Printf("收到 SIGHUP，重新加载配置: %s", path)
+				e.reload(path)
+
+			case err, ok := <-fsWatcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("监听配置文件错误: %v", err)
+
+			case <-stop:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		signal.Stop(sigChan)
+		fsWatcher.Close()
+	}, nil
+}
+
+// reload 重新加载配置并记录结果
+func (e *Engine) reload(path string) {
+	err := e.Reload(path)
+	if err == nil {
+		return
+	}
+
+	var invalid config.ValidationErrors
+	if errors.As(err, &invalid) {
+		log.Printf("配置无效，继续使用当前配置:")
+		for _, fe := range invalid {
+			log.Printf("  %s", fe)
+		}
+		return
+	}
+	log.Printf("重新加载配置失败，继续使用当前配置: %v", err)
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Kind 事件类型
+type Kind string
+
+const (
+	KindDetected  Kind = "detected"  // 检测到文件变化
+	KindQueued    Kind = "queued"    // 防抖等待结束，进入同步队列
+	KindUploading Kind = "uploading" // 开始上传
+	KindDone      Kind = "done"      // 同步完成（上传、删除、创建目录、移动或下载）
+	KindSkipped   Kind = "skipped"   // 跳过同步
+	KindConflict  Kind = "conflict"  // 检测到冲突
+	KindFailed    Kind = "failed"    // 同步失败
+)
+
+// Level 事件级别
+type Level string
+
+const (
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+)
+
+// Event 同步事件
+type Event struct {
+	ID       uint64    `json:"id"`
+	Time     time.Time `json:"time"`
+	Kind     Kind      `json:"kind"`
+	Level    Level     `json:"level"`
+	Provider string    `json:"provider,omitempty"` // 云盘名称，检测和排队事件为空
+	Path     string    `json:"path"`               // 相对于监听目录的路径，使用 / 分隔
+	Action   string    `json:"action,omitempty"`   // 同步动作：upload、delete、mkdir、move、download
+	Message  string    `json:"message,omitempty"`
+}
+
+// levelOf 事件类型对应的默认级别
+func levelOf(kind Kind) Level {
+	switch kind {
+	case KindFailed:
+		return LevelError
+	case KindSkipped, KindConflict:
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
+
+// DefaultSize 默认保留的事件数
+const DefaultSize = 1000
+
+// subscriberBuffer 订阅者的通道缓冲
+const subscriberBuffer = 256
+
+// Log 事件环形缓冲区：保留最近的事件，并推送给订阅者
+type Log struct {
+	mu     sync.Mutex
+	buf    []Event
+	start  int // 最早事件在 buf 中的位置
+	count  int
+	lastID uint64
+	subs   map[chan Event]struct{}
+}
+
+// NewLog 创建最多保留 size 个事件的环形缓冲区
+func NewLog(size int) *Log {
+	return &Log{
+		buf:  make([]Event, size),
+		subs: make(map[chan Event]struct{}),
+	}
+}
+
+// Default 全局事件缓冲区，监听器和同步器的事件都发布到这里
+var Default = NewLog(DefaultSize)
+
+// Publish 发布事件到全局事件缓冲区
+func Publish(e Event) Event {
+	return Default.Publish(e)
+}
+
+// Publish 发布事件，分配 ID，未设置时间和级别时分别使用当前时间和事件类型对应的级别。
+// 订阅者的通道已满时关闭该通道，订阅者可以用最后收到的 ID 调用 Since 补齐后重新订阅
+func (l *Log) Publish(e Event) Event {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Level == "" {
+		e.Level = levelOf(e.Kind)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	e.ID = l.lastID
+
+	if l.count < len(l.buf) {
+		l.buf[(l.start+l.count)%len(l.buf)] = e
+		l.count++
+	} else {
+		l.buf[l.start] = e
+		l.start = (l.start + 1) % len(l.buf)
+	}
+
+	for ch := range l.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// Since 返回 ID 大于 id 且仍在缓冲区中的事件，按发布顺序排列
+func (l *Log) Since(id uint64) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.since(id)
+}
+
+// since 同 Since，调用方需持有 l.mu
+func (l *Log) since(id uint64) []Event {
+	// 程序重启后 ID 重新计数，客户端持有的 ID 可能大于当前最大 ID
+	if id > l.lastID {
+		id = 0
+	}
+
+	var result []Event
+	for i := 0; i < l.count; i++ {
+		e := l.buf[(l.start+i)%len(l.buf)]
+		if e.ID > id {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Subscribe 订阅 ID 大于 since 的事件：先返回缓冲区中已有的事件，之后发布的事件通过通道推送。
+// 返回的函数用于取消订阅；订阅者处理过慢时通道会被关闭
+func (l *Log) Subscribe(since uint64) ([]Event, <-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	l.mu.Lock()
+	backlog := l.since(since)
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+
+	cancel := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subs[ch]; ok {
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+	return backlog, ch, cancel
+}
@@ -0,0 +1,61 @@
+package events
+
+import "testing"
+
+func TestLogRing(t *testing.T) {
+	l := NewLog(3)
+	for i := 0; i < 5; i++ {
+		l.Publish(Event{Kind: KindQueued, Path: string(rune('a' + i))})
+	}
+
+	got := l.Since(0)
+	if len(got) != 3 || got[0].ID != 3 || got[2].ID != 5 || got[2].Path != "e" {
+		t.Fatalf("Since(0) = %+v", got)
+	}
+	if got := l.Since(4); len(got) != 1 || got[0].ID != 5 {
+		t.Fatalf("Since(4) = %+v", got)
+	}
+
+	// 客户端持有重启前的 ID 时返回全部
+	if got := l.Since(100); len(got) != 3 {
+		t.Fatalf("Since(100) = %+v", got)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	l := NewLog(10)
+	cases := map[Kind]Level{
+		KindDone:     LevelInfo,
+		KindConflict: LevelWarn,
+		KindFailed:   LevelError,
+	}
+	for kind, want := range cases {
+		if e := l.Publish(Event{Kind: kind}); e.Level != want || e.Time.IsZero() {
+			t.Errorf("%s 的级别为 %s，期望 %s", kind, e.Level, want)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	l := NewLog(10)
+	l.Publish(Event{Path: "old"})
+
+	backlog, ch, cancel := l.Subscribe(0)
+	defer cancel()
+	if len(backlog) != 1 || backlog[0].Path != "old" {
+		t.Fatalf("积压事件为 %+v", backlog)
+	}
+
+	l.Publish(Event{Path: "new"})
+	if e := <-ch; e.Path != "new" || e.ID != 2 {
+		t.Fatalf("收到 %+v", e)
+	}
+
+	// 处理过慢的订阅者被关闭
+	for i := 0; i < subscriberBuffer+1; i++ {
+		l.Publish(Event{})
+	}
+	for range ch {
+	}
+	cancel()
+}
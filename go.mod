@@ -3,12 +3,18 @@ module CloudFileSync
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/fsnotify/fsnotify v1.7.0
+	github.com/pkg/sftp v1.13.6
 	github.com/tidwall/gjson v1.17.0
+	golang.org/x/crypto v0.17.0
+	golang.org/x/net v0.19.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/kr/fs v0.1.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
-	golang.org/x/sys v0.4.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 )
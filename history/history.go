@@ -0,0 +1,224 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result 同步结果
+type Result string
+
+const (
+	ResultSuccess Result = "success"
+	ResultFailed  Result = "failed"
+	ResultSkipped Result = "skipped"
+)
+
+// Record 一次同步操作的记录
+type Record struct {
+	Time     time.Time `json:"time"`            // 操作完成时间
+	Provider string    `json:"provider"`        // 云盘名称
+	Path     string    `json:"path"`            // 相对于监听目录的路径，使用 / 分隔
+	From     string    `json:"from,omitempty"`  // 移动前的路径
+	Action   string    `json:"action"`          // 同步动作：upload、delete、mkdir、move、download
+	Bytes    int64     `json:"bytes"`           // 传输的字节数
+	Duration int64     `json:"duration_ms"`     // 耗时（毫秒）
+	Result   Result    `json:"result"`          // 结果
+	Error    string    `json:"error,omitempty"` // 失败或跳过的原因
+}
+
+// maxFileSize 历史文件超过此大小时轮转：当前文件改名为 <文件名>.1（替换上一次轮转的文件），之后写入新文件
+const maxFileSize = 10 * 1024 * 1024
+
+// Store 只追加的同步历史，每行一条 JSON 记录（JSON Lines）。
+// 最多保留当前文件和一个轮转文件，查询时两个文件一起扫描
+type Store struct {
+	path    string
+	maxSize int64
+}
+
+// appendMu 保证同一进程内的写入不会交错。配置重新加载后新旧同步器可能同时写入同一个文件
+var appendMu sync.Mutex
+
+// New 创建同步历史，文件在首次写入时创建
+func New(path string) *Store {
+	return &Store{path: path, maxSize: maxFileSize}
+}
+
+// rotatedPath 轮转后的历史文件
+func (s *Store) rotatedPath() string {
+	return s.path + ".1"
+}
+
+// Append 追加一条记录
+func (s *Store) Append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建同步历史目录失败: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil && info.Size()+int64(len(data)) > s.maxSize {
+		if err := os.Rename(s.path, s.rotatedPath()); err != nil {
+			return fmt.Errorf("轮转同步历史失败: %w", err)
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("打开同步历史失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("写入同步历史失败: %w", err)
+	}
+	return nil
+}
+
+// Query 查询条件，零值表示不筛选
+type Query struct {
+	Provider string    // 云盘名称
+	Action   string    // 同步动作
+	Result   Result    // 结果
+	Search   string    // 路径中包含的文字（不区分大小写）
+	Since    time.Time // 起始时间（含）
+	Until    time.Time // 结束时间（不含）
+	Page     int       // 页码，从 1 开始
+	PageSize int       // 每页条数
+}
+
+// DefaultPageSize、MaxPageSize 每页条数的默认值和上限
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// Page 查询结果，Records 按时间从新到旧排列
+type Page struct {
+	Total    int      `json:"total"`
+	Page     int      `json:"page"`
+	PageSize int      `json:"page_size"`
+	Records  []Record `json:"records"`
+}
+
+// match 记录是否符合查询条件
+func (q Query) match(r Record) bool {
+	switch {
+	case q.Provider != "" && r.Provider != q.Provider:
+		return false
+	case q.Action != "" && r.Action != q.Action:
+		return false
+	case q.Result != "" && r.Result != q.Result:
+		return false
+	case !q.Since.IsZero() && r.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !r.Time.Before(q.Until):
+		return false
+	case q.Search != "":
+		search := strings.ToLower(q.Search)
+		return strings.Contains(strings.ToLower(r.Path), search) || strings.Contains(strings.ToLower(r.From), search)
+	}
+	return true
+}
+
+// Query 按条件查询记录。每次查询扫描两遍历史文件：第一遍统计总数，第二遍只保留请求的一页。
+// 无法解析的行（如写入中断留下的半行）会被跳过
+func (s *Store) Query(q Query) (*Page, error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+	page := &Page{Page: q.Page, PageSize: q.PageSize, Records: []Record{}}
+
+	err := s.scan(func(r Record) {
+		if q.match(r) {
+			page.Total++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 文件按写入顺序排列，最新的记录在末尾，第 n 页对应按写入顺序的 [start, end)
+	end := page.Total - (q.Page-1)*q.PageSize
+	start := end - q.PageSize
+	if start < 0 {
+		start = 0
+	}
+	if end <= 0 {
+		return page, nil
+	}
+
+	index := 0
+	err = s.scan(func(r Record) {
+		if !q.match(r) {
+			return
+		}
+		if index >= start && index < end {
+			page.Records = append(page.Records, r)
+		}
+		index++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 改为从新到旧排列
+	for i, j := 0, len(page.Records)-1; i < j; i, j = i+1, j-1 {
+		page.Records[i], page.Records[j] = page.Records[j], page.Records[i]
+	}
+	return page, nil
+}
+
+// scan 按写入顺序读取轮转文件和当前文件中的记录，文件不存在时跳过
+func (s *Store) scan(fn func(Record)) error {
+	for _, path := range []string{s.rotatedPath(), s.path} {
+		if err := scanFile(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanFile 读取一个历史文件中的记录
+func scanFile(path string, fn func(Record)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开同步历史失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		fn(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取同步历史失败: %w", err)
+	}
+	return nil
+}
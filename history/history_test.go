@@ -0,0 +1,128 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendQuery(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "sub", "history.jsonl"))
+
+	page, err := s.Query(Query{})
+	if err != nil || page.Total != 0 || page.Records == nil {
+		t.Fatalf("文件不存在时 Query = %+v, %v", page, err)
+	}
+
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: base, Provider: "a", Path: "docs/Report.txt", Action: "upload", Bytes: 10, Result: ResultSuccess},
+		{Time: base.Add(time.Hour), Provider: "b", Path: "docs/report.txt", Action: "upload", Result: ResultFailed, Error: "网络错误"},
+		{Time: base.Add(2 * time.Hour), Provider: "a", Path: "new.txt", From: "old/report.txt", Action: "move", Result: ResultSuccess},
+		{Time: base.Add(3 * time.Hour), Provider: "a", Path: "x.bin", Action: "delete", Result: ResultSkipped},
+	}
+	for _, r := range records {
+		if err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"全部，从新到旧", Query{}, []string{"x.bin", "new.txt", "docs/report.txt", "docs/Report.txt"}},
+		{"云盘", Query{Provider: "b"}, []string{"docs/report.txt"}},
+		{"动作和结果", Query{Action: "upload", Result: ResultSuccess}, []string{"docs/Report.txt"}},
+		{"搜索包括移动前的路径", Query{Search: "REPORT"}, []string{"new.txt", "docs/report.txt", "docs/Report.txt"}},
+		{"时间范围", Query{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"new.txt", "docs/report.txt"}},
+	}
+	for _, c := range cases {
+		page, err := s.Query(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != len(c.want) || len(page.Records) != len(c.want) {
+			t.Errorf("%s: %+v", c.name, page)
+			continue
+		}
+		for i, path := range c.want {
+			if page.Records[i].Path != path {
+				t.Errorf("%s: 第 %d 条为 %s，期望 %s", c.name, i, page.Records[i].Path, path)
+			}
+		}
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "history.jsonl"))
+	for i := 0; i < 5; i++ {
+		s.Append(Record{Path: string(rune('a' + i)), Result: ResultSuccess})
+	}
+
+	page, _ := s.Query(Query{Page: 2, PageSize: 2})
+	if page.Total != 5 || len(page.Records) != 2 || page.Records[0].Path != "c" || page.Records[1].Path != "b" {
+		t.Fatalf("第 2 页 = %+v", page)
+	}
+	page, _ = s.Query(Query{Page: 3, PageSize: 2})
+	if len(page.Records) != 1 || page.Records[0].Path != "a" {
+		t.Fatalf("第 3 页 = %+v", page)
+	}
+	page, _ = s.Query(Query{Page: 4, PageSize: 2})
+	if len(page.Records) != 0 {
+		t.Fatalf("超出范围的页 = %+v", page)
+	}
+	if page, _ := s.Query(Query{PageSize: 10000}); page.PageSize != MaxPageSize || page.Page != 1 {
+		t.Fatalf("每页条数未限制: %+v", page)
+	}
+}
+
+func TestQuerySkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s := New(path)
+	s.Append(Record{Path: "a", Result: ResultSuccess})
+
+	// 模拟写入中断留下的半行
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"path":"b","res`)
+	f.Close()
+
+	page, err := s.Query(Query{})
+	if err != nil || page.Total != 1 || page.Records[0].Path != "a" {
+		t.Fatalf("Query = %+v, %v", page, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s := New(path)
+	s.maxSize = 200
+
+	for i := 0; i < 10; i++ {
+		if err := s.Append(Record{Path: string(rune('a' + i)), Result: ResultSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 当前文件不超过上限，最早的记录随第二次轮转删除
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > s.maxSize {
+		t.Fatalf("当前文件 %+v, %v", info, err)
+	}
+	page, err := s.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total == 0 || page.Total >= 10 || page.Records[0].Path != "j" {
+		t.Fatalf("轮转后 Query = %+v", page)
+	}
+
+	// 分页跨越轮转文件和当前文件
+	last := page.Records[page.Total-1].Path
+	tail, _ := s.Query(Query{Page: page.Total, PageSize: 1})
+	if len(tail.Records) != 1 || tail.Records[0].Path != last {
+		t.Fatalf("最后一页 = %+v，期望 %s", tail, last)
+	}
+}
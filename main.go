@@ -1,57 +1,152 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
-	"strings"
-	"sync"
 
 	"CloudFileSync/config"
+	"CloudFileSync/engine"
 	"CloudFileSync/provider"
 	"CloudFileSync/server"
-	"CloudFileSync/watcher"
 )
 
 var (
-	configFile = flag.String("config", "config.json", "配置文件路径")
+	configFile = flag.String("config", defaultConfigFile(), "配置文件路径")
 	webMode    = flag.Bool("web", false, "启用 Web 管理界面")
 	webPort    = flag.Int("port", 8080, "Web 服务器端口")
+	webListen  = flag.String("listen", "", "Web 服务器监听地址，如 0.0.0.0:8080，默认仅本机访问（127.0.0.1:端口）")
+	tlsCert    = flag.String("tls-cert", "", "HTTPS 证书文件（PEM），与 -tls-key 一起使用")
+	tlsKey     = flag.String("tls-key", "", "HTTPS 私钥文件（PEM）")
+	tlsSelf    = flag.Bool("tls-self-signed", false, "使用自签名证书启用 HTTPS，证书首次运行时生成并保存在配置文件所在目录")
 	version    = "1.0.0"
 )
 
+// defaultConfigFile 默认配置文件：当前目录下依次查找 config.json、config.yaml、config.yml、config.toml
+func defaultConfigFile() string {
+	for _, name := range []string{"config.json", "config.yaml", "config.yml", "config.toml"} {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return "config.json"
+}
+
 func main() {
 	flag.Parse()
 
+	// 子命令模式
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
 	// 打印欢迎信息
 	printBanner()
 
 	// 加载配置
 	cfg, err := config.LoadConfig(*configFile)
-	if err != nil {
+	var invalid config.ValidationErrors
+	if errors.As(err, &invalid) && *webMode {
+		// Web 模式照常启动，以便在界面中修改
+		for _, fe := range invalid {
+			log.Printf("配置错误: %s", fe)
+		}
+	} else if err != nil {
 		log.Fatalf("加载配置文件失败: %v", err)
 	}
 
 	log.Printf("配置文件加载成功: %s", *configFile)
+	saveRefreshedTokens(*configFile)
 
 	// 根据模式选择运行方式
 	if *webMode {
 		runWebMode(cfg)
 	} else {
-		runCLIMode(cfg)
+		runCLIMode(cfg, *configFile)
+	}
+}
+
+// runCommand 执行子命令，返回进程退出码
+func runCommand(args []string) int {
+	switch args[0] {
+	case "sync":
+		return runSyncCommand(args[1:])
+	case "ls", "get", "put", "rm", "mv", "mkdir", "verify":
+		return runFsCommand(args[0], args[1:])
+	case "auth":
+		return runAuthCommand(args[1:])
+	case "config":
+		return runConfigCommand(args[1:])
+	case "secret":
+		return runSecretCommand(args[1:])
+	case "help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n", args[0])
+		printUsage()
+		return 2
 	}
 }
 
+// printUsage 打印子命令用法
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `用法: CloudFileSync [-config 配置文件] [-web [-port 端口] [-listen 地址] [-tls-cert 证书 -tls-key 私钥 | -tls-self-signed]] [命令]
+
+命令:
+  sync [--once] [--dry-run]            完整同步监听目录到各云盘
+  ls <云盘>:<路径>                      列举远程目录
+  get <云盘>:<路径> [本地路径]           下载文件
+  put <本地路径> <云盘>:<路径>           上传文件（路径以 / 结尾时上传到该目录下）
+  rm <云盘>:<路径>                      删除远程文件或目录
+  mv <云盘>:<路径> [<云盘>:]<新路径>      移动（重命名）远程文件
+  mkdir <云盘>:<路径>                   创建远程目录
+  verify <云盘>                        校验凭证并显示账号与容量信息
+  auth baidu --device [--name 名称]     设备码授权，令牌写入配置文件
+  config check                         检查配置文件，逐项列出错误
+  config migrate [-to 新文件]           把配置文件升级到当前版本，-to 可转换格式（.json/.yaml/.toml）
+  secret seal|list|set|rm [名称]        管理加密令牌存储（需设置 CLOUDFILESYNC_PASSPHRASE）
+
+<云盘> 为配置中的名称，类型唯一时也可使用类型（如 aliyun、baidu）`)
+}
+
 // runWebMode 运行 Web 模式
 func runWebMode(cfg *config.Config) {
-	log.Printf("启动 Web 管理界面模式，端口: %d", *webPort)
-	log.Printf("请在浏览器中访问: http://localhost:%d", *webPort)
+	addr := *webListen
+	if addr == "" {
+		addr = fmt.Sprintf("127.0.0.1:%d", *webPort)
+	}
+	log.Printf("启动 Web 管理界面模式，监听: %s", addr)
+
+	// 同步引擎在界面中启动，配置文件修改后自动生效
+	e := engine.New(cfg)
+	if stop, err := e.WatchConfig(*configFile); err != nil {
+		log.Printf("监听配置文件失败，修改后需重启: %v", err)
+	} else {
+		defer stop()
+	}
 
 	// 创建 Web 服务器
-	srv := server.NewServer(cfg, *configFile, *webPort)
+	srv := server.NewServer(e, *configFile, addr)
+	switch {
+	case *tlsCert != "" || *tlsKey != "":
+		if *tlsCert == "" || *tlsKey == "" {
+			log.Fatalf("-tls-cert 和 -tls-key 需要同时指定")
+		}
+		if *tlsSelf {
+			log.Fatalf("-tls-self-signed 不能与 -tls-cert 同时使用")
+		}
+		if err := srv.EnableTLS(*tlsCert, *tlsKey); err != nil {
+			log.Fatalf("启用 HTTPS 失败: %v", err)
+		}
+	case *tlsSelf:
+		if err := srv.EnableSelfSignedTLS(); err != nil {
+			log.Fatalf("启用 HTTPS 失败: %v", err)
+		}
+	}
 
 	// 启动服务器
 	if err := srv.Start(); err != nil {
@@ -59,49 +154,35 @@ func runWebMode(cfg *config.Config) {
 	}
 }
 
-// runCLIMode 运行命令行模式
-func runCLIMode(cfg *config.Config) {
+// runCLIMode 运行命令行模式，配置文件修改或收到 SIGHUP 时重新加载配置
+func runCLIMode(cfg *config.Config, configPath string) {
 	log.Printf("监听目录: %s", cfg.WatchDir)
 	log.Printf("延迟时间: %d 秒", cfg.DelayTime)
 
-	// 初始化云盘提供商
-	providers := make([]provider.Provider, 0, len(cfg.Providers))
-	for _, p := range cfg.Providers {
-		if !p.Enable {
-			continue
-		}
-
-		pvd, err := provider.NewProvider(p)
-		if err != nil {
-			log.Printf("初始化云盘提供商失败 [%s]: %v", p.Name, err)
-			continue
-		}
-
-		providers = append(providers, pvd)
-		log.Printf("云盘提供商已加载: %s (目标目录: %s)", pvd.Name(), p.Target)
-	}
-
-	if len(providers) == 0 {
-		log.Fatal("没有可用的云盘提供商，请检查配置文件")
+	e := engine.New(cfg)
+	if err := e.Start(); err != nil {
+		log.Fatalf("启动同步失败: %v", err)
 	}
+	defer e.Stop()
 
-	// 创建文件监听器
-	w, err := watcher.NewWatcher(cfg.WatchDir, cfg.GetDelayDuration())
-	if err != nil {
-		log.Fatalf("创建文件监听器失败: %v", err)
+	if stop, err := e.WatchConfig(configPath); err != nil {
+		log.Printf("监听配置文件失败，修改后需重启: %v", err)
+	} else {
+		defer stop()
 	}
-	defer w.Stop()
-
-	// 启动监听
-	w.Start()
-
-	// 处理文件变化
-	handleFileChanges(w, cfg, providers)
 
 	// 等待退出信号
 	waitForExit()
 }
 
+// saveRefreshedTokens 让提供商自行刷新的令牌（如 OneDrive 轮换的 refresh_token）写回配置文件 path，
+// 运行中的同步服务会随配置文件的变化重新加载
+func saveRefreshedTokens(path string) {
+	provider.SetTokenSaver(func(providerName string, tokens map[string]string) error {
+		return config.UpdateTokens(path, providerName, tokens)
+	})
+}
+
 // printBanner 打印欢迎信息
 func printBanner() {
 	fmt.Println(`
@@ -111,63 +192,6 @@ func printBanner() {
 ╚══════════════════════════════════════════════════╝`)
 }
 
-// handleFileChanges 处理文件变化
-func handleFileChanges(w *watcher.Watcher, cfg *config.Config, providers []provider.Provider) {
-	go func() {
-		for event := range w.Events() {
-			log.Printf("处理文件事件: %s [%s]", event.Path, event.Op)
-
-			// 上传到所有云盘
-			var wg sync.WaitGroup
-			for i, p := range providers {
-				wg.Add(1)
-				go func(index int, pvd provider.Provider) {
-					defer wg.Done()
-
-					targetDir := cfg.Providers[index].Target
-					remotePath := getRemotePath(event.Path, cfg.WatchDir, targetDir)
-
-					err := uploadFile(pvd, event.Path, remotePath, event.Op)
-					if err != nil {
-						log.Printf("[%s] 上传失败: %v", pvd.Name(), err)
-					}
-				}(i, p)
-			}
-			wg.Wait()
-		}
-	}()
-}
-
-// uploadFile 上传文件到云盘
-func uploadFile(pvd provider.Provider, localPath, remotePath string, op interface{}) error {
-	// 处理删除事件
-	// 注意：这里需要根据实际的 fsnotify.Op 类型判断
-	opStr := fmt.Sprintf("%v", op)
-	if strings.Contains(opStr, "Remove") {
-		return pvd.DeleteFile(remotePath)
-	}
-
-	// 上传或创建目录
-	return pvd.UploadFile(localPath, remotePath)
-}
-
-// getRemotePath 获取远程路径
-func getRemotePath(localPath, watchDir, targetDir string) string {
-	// 获取相对路径
-	relPath, err := filepath.Rel(watchDir, localPath)
-	if err != nil {
-		return filepath.Join(targetDir, filepath.Base(localPath))
-	}
-
-	// 确保目标目录以 / 结尾
-	targetDir = strings.TrimSuffix(targetDir, "/")
-	if targetDir != "" && !strings.HasSuffix(targetDir, "/") {
-		targetDir += "/"
-	}
-
-	return targetDir + relPath
-}
-
 // waitForExit 等待退出信号
 func waitForExit() {
 	sigChan := make(chan os.Signal, 1)
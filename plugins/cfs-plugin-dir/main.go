@@ -0,0 +1,155 @@
+// cfs-plugin-dir 是 exec 提供商的参考插件，将文件保存到 tokens 中 root 指定的目录。
+//
+// 编译: go build -o cfs-plugin-dir ./plugins/cfs-plugin-dir
+//
+// 配置示例:
+//
+//	{
+//	  "type": "exec",
+//	  "name": "插件示例",
+//	  "enable": true,
+//	  "tokens": {"command": "/usr/local/bin/cfs-plugin-dir", "root": "/mnt/backup"},
+//	  "target": "/CloudFileSync"
+//	}
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"CloudFileSync/provider/plugin"
+)
+
+// dirPlugin 目录插件
+type dirPlugin struct {
+	root string
+}
+
+// Init 初始化
+func (d *dirPlugin) Init(tokens map[string]string) (string, error) {
+	root := tokens["root"]
+	if root == "" {
+		return "", fmt.Errorf("缺少 root")
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("根目录不可用: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("根目录不是目录: %s", root)
+	}
+
+	d.root = root
+	fmt.Fprintf(os.Stderr, "根目录: %s\n", root)
+	return "目录插件", nil
+}
+
+// Upload 复制文件，先写临时文件再重命名
+func (d *dirPlugin) Upload(localPath, remotePath string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest := d.fullPath(remotePath)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".cfs-plugin-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dest)
+}
+
+// Delete 删除文件或目录
+func (d *dirPlugin) Delete(remotePath string) error {
+	dest := d.fullPath(remotePath)
+	if dest == filepath.Clean(d.root) {
+		return fmt.Errorf("不能删除根目录")
+	}
+	return os.RemoveAll(dest)
+}
+
+// Mkdir 递归创建目录
+func (d *dirPlugin) Mkdir(remotePath string) error {
+	return os.MkdirAll(d.fullPath(remotePath), 0755)
+}
+
+// List 列举目录
+func (d *dirPlugin) List(remotePath string) ([]plugin.FileInfo, error) {
+	entries, err := os.ReadDir(d.fullPath(remotePath))
+	if os.IsNotExist(err) {
+		return nil, plugin.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cleanPath(remotePath)
+	files := make([]plugin.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, fileInfo(path.Join(dir, entry.Name()), info))
+	}
+	return files, nil
+}
+
+// Stat 获取文件信息
+func (d *dirPlugin) Stat(remotePath string) (*plugin.FileInfo, error) {
+	info, err := os.Stat(d.fullPath(remotePath))
+	if os.IsNotExist(err) {
+		return nil, plugin.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := fileInfo(cleanPath(remotePath), info)
+	return &result, nil
+}
+
+// fullPath 将远程路径映射到根目录下
+func (d *dirPlugin) fullPath(remotePath string) string {
+	return filepath.Join(d.root, filepath.FromSlash(cleanPath(remotePath)))
+}
+
+// cleanPath 规范化远程路径，不允许通过 .. 跳出根目录
+func cleanPath(remotePath string) string {
+	return path.Clean("/" + strings.Trim(remotePath, "/"))
+}
+
+// fileInfo 转换文件信息
+func fileInfo(remotePath string, info os.FileInfo) plugin.FileInfo {
+	return plugin.FileInfo{
+		Path:    remotePath,
+		Name:    path.Base(remotePath),
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime(),
+	}
+}
+
+func main() {
+	plugin.Main(&dirPlugin{})
+}
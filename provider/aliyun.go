@@ -8,11 +8,11 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/tidwall/gjson"
@@ -24,31 +24,208 @@ type AliYunProvider struct {
 	DriveID     string
 	httpClient  *http.Client
 	baseURL     string
+	cache       *aliyunPathCache
 }
 
+// aliyunPathCache 远程路径到文件 ID 的缓存，避免每次操作都逐级列举父目录。
+// 目录完整列举后记录在 listed 中，有效期内查找其中不存在的文件无需再次请求
+type aliyunPathCache struct {
+	mu     sync.RWMutex
+	ids    map[string]string    // 路径 -> file_id
+	listed map[string]time.Time // 已完整列举的目录 -> 列举时间
+}
+
+// newAliYunPathCache 创建路径缓存
+func newAliYunPathCache() *aliyunPathCache {
+	return &aliyunPathCache{
+		ids:    map[string]string{"/": "root"},
+		listed: make(map[string]time.Time),
+	}
+}
+
+// lookup 查找路径对应的文件 ID，known 为 false 表示缓存中没有结论，需要请求接口
+func (c *aliyunPathCache) lookup(remotePath string) (fileID string, known bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if id, ok := c.ids[remotePath]; ok {
+		return id, true
+	}
+	// 父目录不久前完整列举过，说明文件不存在；列举结果过期后需要重新列举，以便发现其他客户端新建的文件
+	listedAt, ok := c.listed[path.Dir(remotePath)]
+	return "", ok && time.Since(listedAt) < aliyunListingTTL
+}
+
+// set 记录路径对应的文件 ID
+func (c *aliyunPathCache) set(remotePath, fileID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[remotePath] = fileID
+}
+
+// setListing 记录目录的完整列举结果，列举结果中已不存在的子项从缓存中删除
+func (c *aliyunPathCache) setListing(dir string, children map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for p, id := range c.ids {
+		if p != "/" && path.Dir(p) == dir && children[path.Base(p)] != id {
+			c.removeLocked(p)
+		}
+	}
+	for name, id := range children {
+		c.ids[path.Join(dir, name)] = id
+	}
+	c.listed[dir] = time.Now()
+}
+
+// remove 文件已删除或移走，删除路径及其子路径的缓存
+func (c *aliyunPathCache) remove(remotePath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(remotePath)
+}
+
+// expire 使目录的列举结果过期，之后查找其中不存在的文件需要重新列举
+func (c *aliyunPathCache) expire(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.listed, dir)
+}
+
+// invalidate 缓存可能已过期，除 remove 外父目录也需要重新列举
+func (c *aliyunPathCache) invalidate(remotePath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(remotePath)
+	delete(c.listed, path.Dir(remotePath))
+}
+
+// removeLocked 删除路径及其子路径的缓存，调用方需持有写锁
+func (c *aliyunPathCache) removeLocked(remotePath string) {
+	prefix := strings.TrimSuffix(remotePath, "/") + "/"
+	for p := range c.ids {
+		if p == remotePath || strings.HasPrefix(p, prefix) {
+			delete(c.ids, p)
+		}
+	}
+	for p := range c.listed {
+		if p == remotePath || strings.HasPrefix(p, prefix) {
+			delete(c.listed, p)
+		}
+	}
+	c.ids["/"] = "root"
+}
+
+const (
+	// aliyunBaseURL 开放平台接口地址
+	aliyunBaseURL = "https://openapi.alipan.com"
+	// aliyunPartSize 默认分片大小
+	aliyunPartSize = 10 * 1024 * 1024
+	// aliyunMaxParts 单个文件最多分片数
+	aliyunMaxParts = 10000
+	// aliyunListingTTL 目录列举结果用于判断文件不存在的有效期
+	aliyunListingTTL = 30 * time.Second
+)
+
 // AliYunConfig 阿里云盘配置
 type AliYunConfig struct {
 	AccessToken string `json:"access_token"`
 	DriveID     string `json:"drive_id"`
+	DriveType   string `json:"drive_type"` // drive_id 为空时按此类型自动获取: default、resource、backup
+}
+
+func init() {
+	Register("aliyun", NewAliYunProvider, Schema{
+		DisplayName: "阿里云盘",
+		Icon:        "☁️",
+		Fields: []Field{
+			{Key: "access_token", Label: "Access Token", Type: "password", Placeholder: "输入阿里云盘 access_token"},
+			{Key: "client_id", Label: "Client ID（可选，用于 OAuth 授权）", Placeholder: "开放平台应用的 client_id / AppKey"},
+			{Key: "client_secret", Label: "Client Secret（可选）", Type: "password", Placeholder: "开放平台应用的 client_secret / SecretKey",
+				Help: "保存配置后，可在云盘列表中点击「授权」自动获取 Access Token"},
+			{Key: "drive_id", Label: "网盘", Type: "select", Options: []Option{{Value: "", Label: "自动获取（默认盘）"}},
+				Help: "点击「验证」后可从账号下的网盘中选择"},
+		},
+		AnyOf: []string{"access_token", "client_id"},
+	})
 }
 
 // NewAliYunProvider 创建阿里云盘提供商
 func NewAliYunProvider(tokens map[string]string) (Provider, error) {
+	return NewAliYunProviderWithClient(tokens, "", nil)
+}
+
+// NewAliYunProviderWithClient 使用指定的接口地址和 HTTP 客户端创建阿里云盘提供商，用于测试或代理，
+// baseURL 为空时使用开放平台地址，httpClient 为空时使用默认客户端
+func NewAliYunProviderWithClient(tokens map[string]string, baseURL string, httpClient *http.Client) (Provider, error) {
 	accessToken := tokens["access_token"]
 	if accessToken == "" {
 		return nil, fmt.Errorf("缺少 access_token")
 	}
 
-	driveID := tokens["drive_id"]
+	if baseURL == "" {
+		baseURL = aliyunBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: 60 * time.Second,
+		}
+	}
 
-	return &AliYunProvider{
+	a := &AliYunProvider{
 		accessToken: accessToken,
-		DriveID:     driveID,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-		baseURL: "https://openapi.alipan.com",
-	}, nil
+		DriveID:     tokens["drive_id"],
+		httpClient:  httpClient,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		cache:       newAliYunPathCache(),
+	}
+
+	// 未配置 drive_id 时自动获取
+	if a.DriveID == "" {
+		driveID, err := a.discoverDriveID(tokens["drive_type"])
+		if err != nil {
+			return nil, fmt.Errorf("获取 drive_id 失败: %w", err)
+		}
+		a.DriveID = driveID
+	}
+
+	return a, nil
+}
+
+// aliyunDriveTypes drive_type 可选值与 getDriveInfo 返回字段的对应关系
+var aliyunDriveTypes = map[string]string{
+	"default":  "default_drive_id",
+	"resource": "resource_drive_id",
+	"backup":   "backup_drive_id",
+}
+
+// discoverDriveID 通过 getDriveInfo 获取指定类型网盘的 drive_id，默认使用 default_drive_id
+func (a *AliYunProvider) discoverDriveID(driveType string) (string, error) {
+	if driveType == "" {
+		driveType = "default"
+	}
+
+	key, ok := aliyunDriveTypes[driveType]
+	if !ok {
+		key = driveType
+	}
+	if !strings.HasSuffix(key, "_drive_id") {
+		return "", fmt.Errorf("不支持的 drive_type: %s（可选 default、resource、backup）", driveType)
+	}
+
+	info, err := a.post("/adrive/v1.0/user/getDriveInfo", map[string]string{})
+	if err != nil {
+		return "", err
+	}
+
+	driveID := info.Get(key).String()
+	if driveID == "" {
+		return "", fmt.Errorf("账号没有 %s", key)
+	}
+
+	log.Printf("[%s] 自动获取 %s: %s", a.Name(), key, driveID)
+	return driveID, nil
 }
 
 // Name 返回提供商名称
@@ -76,8 +253,8 @@ func (a *AliYunProvider) UploadFile(localPath, remotePath string) error {
 
 	log.Printf("[%s] 上传文件: %s -> %s", a.Name(), localPath, remotePath)
 
-	// 检查文件是否已存在
-	fileID, exists, err := a.checkFileExists(remotePath, fileSHA1)
+	// 检查文件是否已存在，内容不同时返回旧文件 ID
+	oldFileID, exists, err := a.checkFileExists(remotePath, fileSHA1)
 	if err != nil {
 		return fmt.Errorf("检查文件是否存在失败: %w", err)
 	}
@@ -87,31 +264,56 @@ func (a *AliYunProvider) UploadFile(localPath, remotePath string) error {
 		return nil
 	}
 
-	// 获取上传地址
-	uploadURL, err := a.getUploadURL(fileInfo.Name(), fileSHA1, fileInfo.Size())
+	// 确保父目录存在
+	remotePath = "/" + strings.Trim(remotePath, "/")
+	parentID, err := a.getOrCreateDir(path.Dir(remotePath))
 	if err != nil {
-		return fmt.Errorf("获取上传地址失败: %w", err)
+		return fmt.Errorf("创建父目录失败: %w", err)
 	}
 
-	// 分片上传
-	partInfoList, err := a.uploadParts(uploadURL, localPath, fileInfo.Size())
+	// 创建文件并获取分片上传地址
+	upload, err := a.createUpload(parentID, path.Base(remotePath), fileInfo.Size())
 	if err != nil {
+		// 父目录可能已在其他客户端被删除，清除缓存后重新查找或创建一次
+		a.cache.invalidate(path.Dir(remotePath))
+		if parentID, err = a.getOrCreateDir(path.Dir(remotePath)); err != nil {
+			return fmt.Errorf("创建父目录失败: %w", err)
+		}
+		if upload, err = a.createUpload(parentID, path.Base(remotePath), fileInfo.Size()); err != nil {
+			return fmt.Errorf("获取上传地址失败: %w", err)
+		}
+	}
+
+	// 分片上传
+	if err := a.uploadParts(upload, localPath, fileInfo.Size()); err != nil {
 		return fmt.Errorf("分片上传失败: %w", err)
 	}
 
 	// 完成上传
-	err = a.completeUpload(fileID, uploadURL, fileSHA1, fileInfo.Name(), fileInfo.Size(), partInfoList)
-	if err != nil {
+	if err := a.completeUpload(upload); err != nil {
 		return fmt.Errorf("完成上传失败: %w", err)
 	}
 
+	a.cache.set(remotePath, upload.fileID)
+
+	// 开放平台不支持覆盖同名文件，新文件上传完成后再删除旧文件
+	if oldFileID != "" {
+		if err := a.deleteByID(oldFileID); err != nil {
+			return fmt.Errorf("删除旧文件失败: %w", err)
+		}
+	}
+
 	log.Printf("[%s] 上传完成: %s", a.Name(), remotePath)
 	return nil
 }
 
-// DeleteFile 删除文件
+// DeleteFile 删除文件，缓存认为不存在时重新列举父目录确认，避免遗漏其他客户端新建的文件
 func (a *AliYunProvider) DeleteFile(remotePath string) error {
 	fileID, err := a.getFileIDByPath(remotePath)
+	if err == nil && fileID == "" {
+		a.cache.expire(path.Dir(cleanRemotePath(remotePath)))
+		fileID, err = a.getFileIDByPath(remotePath)
+	}
 	if err != nil {
 		return err
 	}
@@ -121,94 +323,29 @@ func (a *AliYunProvider) DeleteFile(remotePath string) error {
 		return nil
 	}
 
-	url := fmt.Sprintf("%s/adrive/v1.0/openFile/delete", a.baseURL)
-	data := map[string]string{
-		"drive_id": a.DriveID,
-		"file_id":  fileID,
-	}
-
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return err
-	}
-
-	a.setAuthHeader(req)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("删除文件失败: %s", resp.Status)
+	if err := a.deleteByID(fileID); err != nil {
+		a.cache.invalidate(cleanRemotePath(remotePath))
+		return fmt.Errorf("删除文件失败: %w", err)
 	}
+	a.cache.remove(cleanRemotePath(remotePath))
 
 	log.Printf("[%s] 删除文件: %s", a.Name(), remotePath)
 	return nil
 }
 
-// CreateDir 创建目录
-func (a *AliYunProvider) CreateDir(remotePath string) error {
-	// 清理路径
-	remotePath = strings.Trim(remotePath, "/")
-	if remotePath == "" {
-		remotePath = "/"
-	}
-
-	// 如果是根目录，直接返回
-	if remotePath == "/" {
-		return nil
-	}
-
-	// 检查目录是否存在
-	parentDir := filepath.Dir(remotePath)
-	dirName := filepath.Base(remotePath)
-
-	parentID, err := a.getOrCreateDir(parentDir)
-	if err != nil {
-		return err
-	}
-
-	// 检查当前目录是否存在
-	_, exists, err := a.checkFileExists(remotePath, "")
-	if err == nil && exists {
-		return nil
-	}
-
-	// 创建目录
-	url := fmt.Sprintf("%s/adrive/v1.0/openFile/create", a.baseURL)
-	data := map[string]interface{}{
-		"drive_id":       a.DriveID,
-		"parent_file_id": parentID,
-		"name":           dirName,
-		"type":           "folder",
-		"check_name_mode": "refuse",
-	}
-
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return err
-	}
-
-	a.setAuthHeader(req)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("创建目录失败: %s", resp.Status)
-	}
+// deleteByID 根据文件 ID 删除文件或目录
+func (a *AliYunProvider) deleteByID(fileID string) error {
+	_, err := a.post("/adrive/v1.0/openFile/delete", map[string]string{
+		"drive_id": a.DriveID,
+		"file_id":  fileID,
+	})
+	return err
+}
 
-	log.Printf("[%s] 创建目录: %s", a.Name(), remotePath)
-	return nil
+// CreateDir 递归创建目录，目录已存在时直接返回
+func (a *AliYunProvider) CreateDir(remotePath string) error {
+	_, err := a.getOrCreateDir(remotePath)
+	return err
 }
 
 // calculateSHA1 计算文件 SHA1
@@ -228,104 +365,106 @@ func (a *AliYunProvider) calculateSHA1(filePath string) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// getUploadURL 获取上传地址
-func (a *AliYunProvider) getUploadURL(fileName, fileSHA1 string, fileSize int64) (string, error) {
-	url := fmt.Sprintf("%s/adrive/v1.0/openFile/getUploadUrl", a.baseURL)
-	data := map[string]interface{}{
-		"drive_id":  a.DriveID,
-		"file_name": fileName,
-		"part_info_list": []map[string]int{
-			{"part_number": 1},
-		},
-		"size":         fileSize,
-		"check_name_mode": "overwrite",
-	}
+// aliyunUpload 创建文件后返回的分片上传信息
+type aliyunUpload struct {
+	fileID   string
+	uploadID string
+	partSize int64
+	partURLs []string
+}
 
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", err
+// createUpload 在父目录下创建文件并获取各分片的上传地址
+func (a *AliYunProvider) createUpload(parentID, fileName string, fileSize int64) (*aliyunUpload, error) {
+	// 超过最大分片数时增大分片
+	partSize := int64(aliyunPartSize)
+	for fileSize/partSize >= aliyunMaxParts {
+		partSize *= 2
 	}
 
-	a.setAuthHeader(req)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return "", err
+	partCount := int((fileSize + partSize - 1) / partSize)
+	if partCount == 0 {
+		partCount = 1
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	result := gjson.ParseBytes(body)
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("获取上传地址失败: %s", result.String())
+	partInfoList := make([]map[string]int, partCount)
+	for i := range partInfoList {
+		partInfoList[i] = map[string]int{"part_number": i + 1}
 	}
 
-	return result.Get("upload_url").String(), nil
-}
-
-// uploadParts 上传分片
-func (a *AliYunProvider) uploadParts(uploadURL, localPath string, fileSize int64) ([]map[string]string, error) {
-	file, err := os.Open(localPath)
+	result, err := a.post("/adrive/v1.0/openFile/create", map[string]interface{}{
+		"drive_id":        a.DriveID,
+		"parent_file_id":  parentID,
+		"name":            fileName,
+		"type":            "file",
+		"check_name_mode": "ignore",
+		"size":            fileSize,
+		"part_info_list":  partInfoList,
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	req, err := http.NewRequest("PUT", uploadURL, file)
-	if err != nil {
-		return nil, err
+	upload := &aliyunUpload{
+		fileID:   result.Get("file_id").String(),
+		uploadID: result.Get("upload_id").String(),
+		partSize: partSize,
 	}
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	for _, part := range result.Get("part_info_list").Array() {
+		upload.partURLs = append(upload.partURLs, part.Get("upload_url").String())
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("上传失败: %s", resp.Status)
+	if len(upload.partURLs) != partCount {
+		return nil, fmt.Errorf("上传地址数量不正确: %d（需要 %d）", len(upload.partURLs), partCount)
 	}
 
-	return []map[string]string{
-		{"part_number": "1"},
-	}, nil
+	return upload, nil
 }
 
-// completeUpload 完成上传
-func (a *AliYunProvider) completeUpload(fileID, uploadURL, fileSHA1, fileName string, fileSize int64, partInfoList []map[string]string) error {
-	url := fmt.Sprintf("%s/adrive/v1.0/openFile/complete", a.baseURL)
-	data := map[string]interface{}{
-		"drive_id":       a.DriveID,
-		"file_id":        fileID,
-		"upload_id":      a.extractUploadID(uploadURL),
-		"part_info_list": partInfoList,
-	}
-
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+// uploadParts 依次上传各分片
+func (a *AliYunProvider) uploadParts(upload *aliyunUpload, localPath string, fileSize int64) error {
+	file, err := os.Open(localPath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	a.setAuthHeader(req)
-	req.Header.Set("Content-Type", "application/json")
+	for i, uploadURL := range upload.partURLs {
+		offset := int64(i) * upload.partSize
+		size := min(upload.partSize, fileSize-offset)
 
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+		req, err := http.NewRequest("PUT", uploadURL, io.NewSectionReader(file, offset, size))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = size
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("完成上传失败: %s", resp.Status)
+		resp, err := a.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("上传分片 %d 失败: %s", i+1, resp.Status)
+		}
+
+		if len(upload.partURLs) > 1 {
+			log.Printf("[%s] 上传进度: %d/%d 字节", a.Name(), offset+size, fileSize)
+		}
 	}
 
 	return nil
 }
 
+// completeUpload 完成上传
+func (a *AliYunProvider) completeUpload(upload *aliyunUpload) error {
+	_, err := a.post("/adrive/v1.0/openFile/complete", map[string]string{
+		"drive_id":  a.DriveID,
+		"file_id":   upload.fileID,
+		"upload_id": upload.uploadID,
+	})
+	return err
+}
+
 // checkFileExists 检查文件是否存在
 func (a *AliYunProvider) checkFileExists(remotePath, contentHash string) (string, bool, error) {
 	fileID, err := a.getFileIDByPath(remotePath)
@@ -359,90 +498,67 @@ func (a *AliYunProvider) checkFileExists(remotePath, contentHash string) (string
 	result := gjson.ParseBytes(body)
 
 	if resp.StatusCode != http.StatusOK {
+		// 缓存的文件 ID 可能已失效
+		a.cache.invalidate(cleanRemotePath(remotePath))
 		return "", false, nil
 	}
 
+	// 接口返回的 content_hash 为大写
 	existingHash := result.Get("content_hash").String()
-	if existingHash == contentHash {
+	if strings.EqualFold(existingHash, contentHash) {
 		return fileID, true, nil
 	}
 
 	return fileID, false, nil
 }
 
-// getFileIDByPath 根据路径获取文件ID
+// getFileIDByPath 根据路径获取文件ID，文件不存在时返回空。
+// 缓存的目录 ID 失效时列举会失败并清除缓存，此时重新查找一次
 func (a *AliYunProvider) getFileIDByPath(remotePath string) (string, error) {
-	remotePath = strings.Trim(remotePath, "/")
-	if remotePath == "" {
-		return "root", nil
-	}
-
-	parts := strings.Split(remotePath, "/")
-	currentID := "root"
-
-	for i, part := range parts {
-		if part == "" {
-			continue
-		}
-
-		fileID, err := a.findFileInDir(currentID, part)
-		if err != nil {
-			// 如果是最后一级且文件不存在，返回空
-			if i == len(parts)-1 {
-				return "", nil
-			}
-			return "", err
-		}
-
-		if fileID == "" {
-			return "", nil
-		}
-
-		currentID = fileID
+	fileID, err := a.lookupPath(remotePath)
+	if err != nil {
+		fileID, err = a.lookupPath(remotePath)
 	}
-
-	return currentID, nil
+	return fileID, err
 }
 
-// findFileInDir 在目录中查找文件
-func (a *AliYunProvider) findFileInDir(parentID, fileName string) (string, error) {
-	url := fmt.Sprintf("%s/adrive/v1.0/openFile/list", a.baseURL)
-	data := map[string]string{
-		"drive_id":       a.DriveID,
-		"parent_file_id": parentID,
-		"limit":          "100",
+// lookupPath 优先使用缓存，逐级列举父目录查找文件 ID
+func (a *AliYunProvider) lookupPath(remotePath string) (string, error) {
+	remotePath = cleanRemotePath(remotePath)
+	if fileID, known := a.cache.lookup(remotePath); known {
+		return fileID, nil
 	}
 
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
+	parentID, err := a.lookupPath(path.Dir(remotePath))
+	if err != nil || parentID == "" {
 		return "", err
 	}
 
-	a.setAuthHeader(req)
-	req.Header.Set("Content-Type", "application/json")
+	return a.findFileInDir(path.Dir(remotePath), parentID, path.Base(remotePath))
+}
 
-	resp, err := a.httpClient.Do(req)
+// findFileInDir 列举目录查找文件，列举结果写入缓存
+func (a *AliYunProvider) findFileInDir(parentPath, parentID, fileName string) (string, error) {
+	items, err := a.listDir(parentID)
 	if err != nil {
+		// 缓存的目录 ID 可能已失效
+		a.cache.invalidate(parentPath)
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	result := gjson.ParseBytes(body)
+	a.cacheListing(parentPath, items)
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("列举文件失败: %s", result.String())
-	}
+	fileID, _ := a.cache.lookup(path.Join(parentPath, fileName))
+	return fileID, nil
+}
 
-	items := result.Get("items").Array()
+// cacheListing 缓存目录的完整列举结果
+func (a *AliYunProvider) cacheListing(dir string, items []gjson.Result) {
+	children := make(map[string]string, len(items))
 	for _, item := range items {
-		if item.Get("name").String() == fileName {
-			return item.Get("file_id").String(), nil
-		}
+		children[item.Get("name").String()] = item.Get("file_id").String()
 	}
-
-	return "", nil
+	a.cache.setListing(dir, children)
 }
 
 // getOrCreateDir 获取或创建目录
@@ -468,7 +584,7 @@ func (a *AliYunProvider) getOrCreateDir(remotePath string) (string, error) {
 // createDirRecursive 递归创建目录
 func (a *AliYunProvider) createDirRecursive(remotePath string) (string, error) {
 	parts := strings.Split(remotePath, "/")
-	currentPath := ""
+	currentPath := "/"
 	currentID := "root"
 
 	for _, part := range parts {
@@ -476,58 +592,41 @@ func (a *AliYunProvider) createDirRecursive(remotePath string) (string, error) {
 			continue
 		}
 
-		currentPath += "/" + part
+		currentPath = path.Join(currentPath, part)
 		fileID, err := a.getFileIDByPath(currentPath)
 		if err != nil {
 			return "", err
 		}
 
 		if fileID == "" {
-			// 创建目录
-			url := fmt.Sprintf("%s/adrive/v1.0/openFile/create", a.baseURL)
-			data := map[string]interface{}{
-				"drive_id":       a.DriveID,
-				"parent_file_id": currentID,
-				"name":           part,
-				"type":           "folder",
-			}
-
-			jsonData, _ := json.Marshal(data)
-			req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+			fileID, err = a.createFolder(currentID, part)
 			if err != nil {
 				return "", err
 			}
-
-			a.setAuthHeader(req)
-			req.Header.Set("Content-Type", "application/json")
-
-			resp, err := a.httpClient.Do(req)
-			if err != nil {
-				return "", err
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				return "", fmt.Errorf("创建目录失败: %s", resp.Status)
-			}
-
-			body, _ := io.ReadAll(resp.Body)
-			result := gjson.ParseBytes(body)
-			currentID = result.Get("file_id").String()
-		} else {
-			currentID = fileID
+			a.cache.set(currentPath, fileID)
+			log.Printf("[%s] 创建目录: %s", a.Name(), currentPath)
 		}
+
+		currentID = fileID
 	}
 
 	return currentID, nil
 }
 
-// extractUploadID 从上传 URL 中提取 upload_id
-func (a *AliYunProvider) extractUploadID(uploadURL string) string {
-	// 简单实现，实际需要从 URL 中解析
-	// 例如: https://cn-beijing-data.aliyundrive.net/...
-	// 实际的 upload_id 可能在 URL 参数中
-	return "1"
+// createFolder 在父目录下创建文件夹，同名文件夹已存在时返回其 ID
+func (a *AliYunProvider) createFolder(parentID, name string) (string, error) {
+	result, err := a.post("/adrive/v1.0/openFile/create", map[string]string{
+		"drive_id":        a.DriveID,
+		"parent_file_id":  parentID,
+		"name":            name,
+		"type":            "folder",
+		"check_name_mode": "refuse",
+	})
+	if err != nil {
+		return "", fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	return result.Get("file_id").String(), nil
 }
 
 // setAuthHeader 设置认证头
@@ -535,62 +634,224 @@ func (a *AliYunProvider) setAuthHeader(req *http.Request) {
 	req.Header.Set("Authorization", "Bearer "+a.accessToken)
 }
 
-// UploadWithProgress 带进度的上传（未实现）
-func (a *AliYunProvider) uploadWithMultipart(uploadURL string, file *os.File, fileSize int64, progressCallback ProgressCallback) error {
-	// 创建 multipart 表单
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+// Stat 获取远程文件信息
+func (a *AliYunProvider) Stat(remotePath string) (*FileInfo, error) {
+	fileID, err := a.getFileIDByPath(remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if fileID == "" {
+		return nil, ErrNotFound
+	}
 
-	part, err := writer.CreateFormFile("file", filepath.Base(file.Name()))
+	result, err := a.post("/adrive/v1.0/openFile/get", map[string]string{
+		"drive_id": a.DriveID,
+		"file_id":  fileID,
+	})
+	if err != nil {
+		a.cache.invalidate(cleanRemotePath(remotePath))
+		return nil, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	return a.parseFileInfo(result, remotePath), nil
+}
+
+// DownloadFile 下载文件到本地
+func (a *AliYunProvider) DownloadFile(remotePath, localPath string) error {
+	fileID, err := a.getFileIDByPath(remotePath)
 	if err != nil {
 		return err
 	}
 
-	uploaded := int64(0)
-	buffer := make([]byte, 32*1024) // 32KB 缓冲区
+	if fileID == "" {
+		return ErrNotFound
+	}
 
-	for {
-		n, err := file.Read(buffer)
-		if n > 0 {
-			part.Write(buffer[:n])
-			uploaded += int64(n)
-
-			if progressCallback != nil {
-				progressCallback(UploadProgress{
-					Uploaded:   uploaded,
-					TotalSize:  fileSize,
-					Percentage: float64(uploaded) / float64(fileSize) * 100,
-				})
-			}
-		}
+	result, err := a.post("/adrive/v1.0/openFile/getDownloadUrl", map[string]string{
+		"drive_id": a.DriveID,
+		"file_id":  fileID,
+	})
+	if err != nil {
+		a.cache.invalidate(cleanRemotePath(remotePath))
+		return fmt.Errorf("获取下载地址失败: %w", err)
+	}
 
-		if err == io.EOF {
-			break
-		}
+	resp, err := a.httpClient.Get(result.Get("url").String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-		if err != nil {
-			return err
-		}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载文件失败: %s", resp.Status)
 	}
 
-	writer.Close()
+	log.Printf("[%s] 下载文件: %s -> %s", a.Name(), remotePath, localPath)
+	return writeLocalFile(localPath, resp.Body)
+}
+
+// parseFileInfo 解析接口返回的文件信息
+func (a *AliYunProvider) parseFileInfo(item gjson.Result, remotePath string) *FileInfo {
+	modTime, _ := time.Parse(time.RFC3339, item.Get("updated_at").String())
+
+	return &FileInfo{
+		Path:    "/" + strings.Trim(remotePath, "/"),
+		Name:    item.Get("name").String(),
+		Size:    item.Get("size").Int(),
+		IsDir:   item.Get("type").String() == "folder",
+		ModTime: modTime,
+		Hash:    strings.ToLower(item.Get("content_hash").String()),
+	}
+}
 
-	req, err := http.NewRequest("PUT", uploadURL, body)
+// post 调用开放平台接口并解析返回结果
+func (a *AliYunProvider) post(api string, data interface{}) (gjson.Result, error) {
+	jsonData, _ := json.Marshal(data)
+	req, err := http.NewRequest("POST", a.baseURL+api, bytes.NewReader(jsonData))
 	if err != nil {
-		return err
+		return gjson.Result{}, err
 	}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	a.setAuthHeader(req)
+	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		return err
+		return gjson.Result{}, err
 	}
 	defer resp.Body.Close()
 
+	body, _ := io.ReadAll(resp.Body)
+	result := gjson.ParseBytes(body)
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("上传失败: %s", resp.Status)
+		return result, fmt.Errorf("%s: %s", resp.Status, result.Get("message").String())
+	}
+
+	return result, nil
+}
+
+// MoveFile 移动（重命名）文件
+func (a *AliYunProvider) MoveFile(oldPath, newPath string) error {
+	fileID, err := a.getFileIDByPath(oldPath)
+	if err != nil {
+		return err
+	}
+
+	if fileID == "" {
+		return ErrNotFound
+	}
+
+	parentID, err := a.getOrCreateDir(path.Dir("/" + strings.Trim(newPath, "/")))
+	if err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	result, err := a.post("/adrive/v1.0/openFile/move", map[string]string{
+		"drive_id":          a.DriveID,
+		"file_id":           fileID,
+		"to_parent_file_id": parentID,
+		"new_name":          path.Base(newPath),
+		"check_name_mode":   "refuse",
+	})
+	if err != nil {
+		a.cache.invalidate(cleanRemotePath(oldPath))
+		return fmt.Errorf("移动文件失败: %w", err)
+	}
+
+	// 目标已存在时接口不会移动，只返回 exist
+	if result.Get("exist").Bool() {
+		return fmt.Errorf("移动文件失败: 目标已存在: %s", newPath)
 	}
 
+	a.cache.remove(cleanRemotePath(oldPath))
+	a.cache.set(cleanRemotePath(newPath), fileID)
+
+	log.Printf("[%s] 移动文件: %s -> %s", a.Name(), oldPath, newPath)
 	return nil
 }
+
+// List 列举目录下的文件
+func (a *AliYunProvider) List(remotePath string) ([]FileInfo, error) {
+	dirID, err := a.getFileIDByPath(remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if dirID == "" {
+		return nil, ErrNotFound
+	}
+
+	dir := cleanRemotePath(remotePath)
+	items, err := a.listDir(dirID)
+	if err != nil {
+		a.cache.invalidate(dir)
+		return nil, err
+	}
+
+	a.cacheListing(dir, items)
+
+	files := make([]FileInfo, 0, len(items))
+	for _, item := range items {
+		files = append(files, *a.parseFileInfo(item, path.Join(dir, item.Get("name").String())))
+	}
+
+	return files, nil
+}
+
+// listDir 分页列举目录下的全部文件
+func (a *AliYunProvider) listDir(parentID string) ([]gjson.Result, error) {
+	var items []gjson.Result
+	marker := ""
+
+	for {
+		data := map[string]interface{}{
+			"drive_id":       a.DriveID,
+			"parent_file_id": parentID,
+			"limit":          100,
+		}
+		if marker != "" {
+			data["marker"] = marker
+		}
+
+		result, err := a.post("/adrive/v1.0/openFile/list", data)
+		if err != nil {
+			return nil, fmt.Errorf("列举文件失败: %w", err)
+		}
+
+		items = append(items, result.Get("items").Array()...)
+
+		marker = result.Get("next_marker").String()
+		if marker == "" {
+			return items, nil
+		}
+	}
+}
+
+// Verify 校验 access_token 并获取账号与容量信息
+func (a *AliYunProvider) Verify() (*AccountInfo, error) {
+	user, err := a.post("/adrive/v1.0/user/getDriveInfo", map[string]string{})
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+
+	info := &AccountInfo{
+		UserName: user.Get("name").String(),
+		DriveIDs: make(map[string]string),
+	}
+	for _, key := range []string{"default_drive_id", "resource_drive_id", "backup_drive_id"} {
+		if id := user.Get(key).String(); id != "" {
+			info.DriveIDs[key] = id
+		}
+	}
+
+	space, err := a.post("/adrive/v1.0/user/getSpaceInfo", map[string]string{})
+	if err != nil {
+		return nil, fmt.Errorf("获取空间信息失败: %w", err)
+	}
+	info.UsedSpace = space.Get("personal_space_info.used_size").Int()
+	info.TotalSpace = space.Get("personal_space_info.total_size").Int()
+
+	return info, nil
+}
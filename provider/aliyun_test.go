@@ -0,0 +1,104 @@
+package provider_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/provider"
+	"CloudFileSync/provider/providertest"
+)
+
+func TestAliYunManyFilesInOneFolder(t *testing.T) {
+	srv := providertest.NewAliYunServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("a"), 0644)
+
+	const count = 1000
+	for i := 0; i < count; i++ {
+		if err := p.UploadFile(local, fmt.Sprintf("/big/f%04d.txt", i)); err != nil {
+			t.Fatalf("上传第 %d 个文件失败: %v", i, err)
+		}
+	}
+
+	// 根目录和 /big 各列举一次，之后的查找全部命中缓存
+	if lists := srv.Calls("/adrive/v1.0/openFile/list"); lists > 2 {
+		t.Errorf("上传 %d 个文件列举了 %d 次目录", count, lists)
+	}
+
+	// 新的提供商没有缓存，需要分页列举才能找到排在后面的文件
+	fresh := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	info, err := fresh.(provider.Stater).Stat(fmt.Sprintf("/big/f%04d.txt", count-1))
+	if err != nil {
+		t.Fatalf("查找第 %d 个文件失败: %v", count, err)
+	}
+	if info.Size != 1 {
+		t.Errorf("Size = %d", info.Size)
+	}
+}
+
+func TestAliYunStaleCache(t *testing.T) {
+	srv := providertest.NewAliYunServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("a"), 0644)
+
+	if err := p.UploadFile(local, "/dir/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他客户端删除目录后，缓存中的目录 ID 失效
+	other := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	if err := other.DeleteFile("/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 使用失效的目录 ID 失败后清除缓存，重新创建目录并上传
+	if err := p.UploadFile(local, "/dir/b.txt"); err != nil {
+		t.Fatalf("目录 ID 失效后上传失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.(provider.Stater).Stat("/dir/a.txt"); err != provider.ErrNotFound {
+		t.Fatalf("期望 ErrNotFound，实际为 %v", err)
+	}
+}
+
+func TestAliYunDeleteRemoteCreated(t *testing.T) {
+	srv := providertest.NewAliYunServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("a"), 0644)
+
+	if err := p.UploadFile(local, "/dir/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.(provider.Stater).Stat("/dir/b.txt"); err != provider.ErrNotFound {
+		t.Fatalf("期望 ErrNotFound，实际为 %v", err)
+	}
+
+	// 其他客户端在已列举过的目录中新建文件，缓存中没有该文件
+	other := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	if err := other.UploadFile(local, "/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除前重新列举父目录，不能因缓存未命中而跳过
+	if err := p.DeleteFile("/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	fresh := providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	if _, err := fresh.(provider.Stater).Stat("/dir/b.txt"); err != provider.ErrNotFound {
+		t.Fatalf("期望 ErrNotFound，实际为 %v", err)
+	}
+}
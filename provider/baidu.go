@@ -8,36 +8,78 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/tidwall/gjson"
 )
 
+const (
+	// baiduBlockSize 分片大小，普通用户单个分片上限为 4MB
+	baiduBlockSize = 4 * 1024 * 1024
+)
+
 // BaiduProvider 百度云盘提供商
 type BaiduProvider struct {
 	accessToken string
 	httpClient  *http.Client
-	baseURL     string
+	baseURL     string // xpan 接口地址
+	pcsURL      string // 分片上传接口地址
+}
+
+func init() {
+	Register("baidu", NewBaiduProvider, Schema{
+		DisplayName: "百度网盘",
+		Icon:        "📦",
+		Fields: []Field{
+			{Key: "access_token", Label: "Access Token", Type: "password", Placeholder: "输入百度网盘 access_token"},
+			{Key: "client_id", Label: "Client ID（可选，用于 OAuth 授权）", Placeholder: "开放平台应用的 client_id / AppKey"},
+			{Key: "client_secret", Label: "Client Secret（可选）", Type: "password", Placeholder: "开放平台应用的 client_secret / SecretKey",
+				Help: "保存配置后，可在云盘列表中点击「授权」自动获取 Access Token"},
+		},
+		AnyOf: []string{"access_token", "client_id"},
+	})
 }
 
 // NewBaiduProvider 创建百度云盘提供商
 func NewBaiduProvider(tokens map[string]string) (Provider, error) {
+	return NewBaiduProviderWithClient(tokens, "", nil)
+}
+
+// NewBaiduProviderWithClient 使用指定的接口地址和 HTTP 客户端创建百度云盘提供商，用于测试或代理。
+// baseURL 形如 https://pan.baidu.com，指定后分片上传也使用该地址；为空时使用官方地址，httpClient 为空时使用默认客户端
+func NewBaiduProviderWithClient(tokens map[string]string, baseURL string, httpClient *http.Client) (Provider, error) {
 	accessToken := tokens["access_token"]
 	if accessToken == "" {
 		return nil, fmt.Errorf("缺少 access_token")
 	}
 
-	return &BaiduProvider{
-		accessToken: accessToken,
-		httpClient: &http.Client{
+	if httpClient == nil {
+		httpClient = &http.Client{
 			Timeout: 60 * time.Second,
-		},
-		baseURL: "https://pan.baidu.com/rest/2.0/xpan",
-	}, nil
+		}
+	}
+
+	b := &BaiduProvider{
+		accessToken: accessToken,
+		httpClient:  httpClient,
+		baseURL:     "https://pan.baidu.com/rest/2.0/xpan",
+		pcsURL:      "https://d.pcs.baidu.com/rest/2.0/pcs",
+	}
+
+	if baseURL != "" {
+		baseURL = strings.TrimSuffix(baseURL, "/")
+		b.baseURL = baseURL + "/rest/2.0/xpan"
+		b.pcsURL = baseURL + "/rest/2.0/pcs"
+	}
+
+	return b, nil
 }
 
 // Name 返回提供商名称
@@ -59,39 +101,37 @@ func (b *BaiduProvider) UploadFile(localPath, remotePath string) error {
 
 	log.Printf("[%s] 上传文件: %s -> %s", b.Name(), localPath, remotePath)
 
-	// 计算文件 MD5
-	fileMD5, err := b.calculateMD5(localPath)
+	// 计算各分片 MD5
+	blockList, err := b.blockMD5s(localPath)
 	if err != nil {
 		return fmt.Errorf("计算文件哈希失败: %w", err)
 	}
 
-	// 获取父目录路径
-	remoteDir := filepath.Dir(remotePath)
-	fileName := filepath.Base(remotePath)
-
 	// 确保父目录存在
-	parentPath, err := b.getOrCreateDir(remoteDir)
-	if err != nil {
+	remotePath = "/" + strings.Trim(remotePath, "/")
+	if _, err := b.getOrCreateDir(path.Dir(remotePath)); err != nil {
 		return fmt.Errorf("创建父目录失败: %w", err)
 	}
 
 	// 预上传
-	uploadURL, err := b.preUpload(fileMD5, fileInfo.Size())
+	uploadID, blocks, err := b.precreate(remotePath, fileInfo.Size(), blockList)
 	if err != nil {
 		return fmt.Errorf("预上传失败: %w", err)
 	}
 
+	// uploadID 为空表示秒传成功
+	if uploadID == "" {
+		log.Printf("[%s] 秒传完成: %s", b.Name(), remotePath)
+		return nil
+	}
+
 	// 分片上传
-	if uploadURL != "" {
-		err = b.uploadFile(uploadURL, localPath)
-		if err != nil {
-			return fmt.Errorf("上传文件失败: %w", err)
-		}
+	if err := b.uploadBlocks(uploadID, remotePath, localPath, blocks); err != nil {
+		return fmt.Errorf("上传文件失败: %w", err)
 	}
 
 	// 创建文件
-	err = b.createFile(parentPath, fileName, fileMD5, fileInfo.Size())
-	if err != nil {
+	if err := b.createFile(remotePath, fileInfo.Size(), blockList, uploadID); err != nil {
 		return fmt.Errorf("创建文件失败: %w", err)
 	}
 
@@ -111,27 +151,19 @@ func (b *BaiduProvider) DeleteFile(remotePath string) error {
 		return nil
 	}
 
-	url := fmt.Sprintf("%s/file?method=filemanager&opera=delete", b.baseURL)
-	data := map[string]interface{}{
-		"filelist": fmt.Sprintf("[\"%s\"]", fsID),
-	}
+	filelist, _ := json.Marshal([]string{"/" + strings.Trim(remotePath, "/")})
 
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url+"&access_token="+b.accessToken, bytes.NewReader(jsonData))
-	if err != nil {
-		return err
-	}
+	form := url.Values{}
+	form.Set("async", "0")
+	form.Set("filelist", string(filelist))
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := b.httpClient.Do(req)
+	api := fmt.Sprintf("%s/file?method=filemanager&opera=delete&access_token=%s", b.baseURL, b.accessToken)
+	result, err := b.postForm(api, form)
 	if err != nil {
-		return err
+		return fmt.Errorf("删除文件失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("删除文件失败: %s", resp.Status)
+	if result.Get("errno").Int() != 0 {
+		return fmt.Errorf("删除文件失败: %s", result.String())
 	}
 
 	log.Printf("[%s] 删除文件: %s", b.Name(), remotePath)
@@ -144,140 +176,139 @@ func (b *BaiduProvider) CreateDir(remotePath string) error {
 	return err
 }
 
-// calculateMD5 计算文件 MD5
-func (b *BaiduProvider) calculateMD5(filePath string) (string, error) {
+// blockMD5s 按分片大小计算文件各分片的 MD5
+func (b *BaiduProvider) blockMD5s(filePath string) ([]string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer file.Close()
 
-	hash := md5.New()
-	_, err = io.Copy(hash, file)
-	if err != nil {
-		return "", err
-	}
+	var blockList []string
+	for {
+		hash := md5.New()
+		n, err := io.CopyN(hash, file, baiduBlockSize)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+		// 空文件也需要一个分片
+		if n > 0 || len(blockList) == 0 {
+			blockList = append(blockList, hex.EncodeToString(hash.Sum(nil)))
+		}
+		if n < baiduBlockSize {
+			return blockList, nil
+		}
+	}
 }
 
-// preUpload 预上传
-func (b *BaiduProvider) preUpload(fileMD5 string, fileSize int64) (string, error) {
-	url := fmt.Sprintf("%s/file?method=precreate&access_token=%s", b.baseURL, b.accessToken)
+// precreate 预上传，返回上传 ID 和需要上传的分片序号，上传 ID 为空表示秒传成功
+func (b *BaiduProvider) precreate(remotePath string, fileSize int64, blockList []string) (string, []int64, error) {
+	blocks, _ := json.Marshal(blockList)
 
-	data := map[string]interface{}{
-		"path":       "/"+fileMD5, // 临时路径
-		"size":       fileSize,
-		"content_md5": fileMD5,
-		"is":         0,
-	}
+	form := url.Values{}
+	form.Set("path", remotePath)
+	form.Set("size", strconv.FormatInt(fileSize, 10))
+	form.Set("isdir", "0")
+	form.Set("autoinit", "1")
+	form.Set("rtype", "3") // 覆盖同名文件
+	form.Set("block_list", string(blocks))
 
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+	api := fmt.Sprintf("%s/file?method=precreate&access_token=%s", b.baseURL, b.accessToken)
+	result, err := b.postForm(api, form)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := b.httpClient.Do(req)
-	if err != nil {
-		return "", err
+	if result.Get("errno").Int() != 0 {
+		return "", nil, fmt.Errorf("预上传失败: %s", result.String())
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	result := gjson.ParseBytes(body)
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("预上传失败: %s", result.String())
+	// return_type 为 2 表示云端已有相同文件，秒传成功
+	if result.Get("return_type").Int() == 2 {
+		return "", nil, nil
 	}
 
-	// 检查是否需要上传
-	// 如果返回的 return_type 为 1，表示秒传成功
-	// 如果返回的 return_type 为 2，表示需要上传
-	returnType := result.Get("return_type").Int()
-
-	if returnType == 1 {
-		// 秒传成功，无需上传
-		return "", nil
+	var needed []int64
+	for _, seq := range result.Get("block_list").Array() {
+		needed = append(needed, seq.Int())
 	}
 
-	// 获取上传地址
-	uploadURL := result.Get("dlink").String()
-	return uploadURL, nil
+	return result.Get("uploadid").String(), needed, nil
 }
 
-// uploadFile 上传文件
-func (b *BaiduProvider) uploadFile(uploadURL, localPath string) error {
+// uploadBlocks 上传需要的分片
+func (b *BaiduProvider) uploadBlocks(uploadID, remotePath, localPath string, blocks []int64) error {
 	file, err := os.Open(localPath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	req, err := http.NewRequest("PUT", uploadURL, file)
-	if err != nil {
-		return err
-	}
+	for i, seq := range blocks {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
 
-	// 设置切片上传
-	req.Header.Set("Content-Type", "application/octet-stream")
+		part, err := writer.CreateFormFile("file", path.Base(remotePath))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, io.NewSectionReader(file, seq*baiduBlockSize, baiduBlockSize)); err != nil {
+			return err
+		}
+		writer.Close()
 
-	resp, err := b.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+		query := url.Values{}
+		query.Set("method", "upload")
+		query.Set("access_token", b.accessToken)
+		query.Set("type", "tmpfile")
+		query.Set("path", remotePath)
+		query.Set("uploadid", uploadID)
+		query.Set("partseq", strconv.FormatInt(seq, 10))
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("上传失败: %s", resp.Status)
-	}
+		req, err := http.NewRequest("POST", b.pcsURL+"/superfile2?"+query.Encode(), body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	return nil
-}
+		resp, err := b.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
 
-// createFile 创建文件
-func (b *BaiduProvider) createFile(parentPath, fileName, fileMD5 string, fileSize int64) error {
-	url := fmt.Sprintf("%s/file?method=create&access_token=%s", b.baseURL, b.accessToken)
+		result := gjson.ParseBytes(respBody)
+		if resp.StatusCode != http.StatusOK || result.Get("md5").String() == "" {
+			return fmt.Errorf("上传分片 %d 失败: %s", seq, result.String())
+		}
 
-	remotePath := strings.TrimSuffix(parentPath, "/")
-	if remotePath == "" {
-		remotePath = "/"
+		if len(blocks) > 1 {
+			log.Printf("[%s] 上传进度: 分片 %d/%d", b.Name(), i+1, len(blocks))
+		}
 	}
 
-	remotePath = remotePath + "/" + fileName
+	return nil
+}
 
-	data := map[string]interface{}{
-		"path":       remotePath,
-		"size":       fileSize,
-		"content_md5": fileMD5,
-		"is":         0,
-	}
+// createFile 合并分片创建文件，覆盖同名文件
+func (b *BaiduProvider) createFile(remotePath string, fileSize int64, blockList []string, uploadID string) error {
+	blocks, _ := json.Marshal(blockList)
 
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return err
-	}
+	form := url.Values{}
+	form.Set("path", remotePath)
+	form.Set("size", strconv.FormatInt(fileSize, 10))
+	form.Set("isdir", "0")
+	form.Set("rtype", "3")
+	form.Set("uploadid", uploadID)
+	form.Set("block_list", string(blocks))
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := b.httpClient.Do(req)
+	api := fmt.Sprintf("%s/file?method=create&access_token=%s", b.baseURL, b.accessToken)
+	result, err := b.postForm(api, form)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	result := gjson.ParseBytes(body)
-
-	if resp.StatusCode != http.StatusOK {
-		// 检查是否文件已存在
-		if result.Get("errno").Int() == -8 {
-			// 文件已存在，视为成功
-			return nil
-		}
+	if result.Get("errno").Int() != 0 {
 		return fmt.Errorf("创建文件失败: %s", result.String())
 	}
 
@@ -286,33 +317,38 @@ func (b *BaiduProvider) createFile(parentPath, fileName, fileMD5 string, fileSiz
 
 // getFsIDByPath 根据路径获取文件 fs_id
 func (b *BaiduProvider) getFsIDByPath(remotePath string) (string, error) {
+	meta, err := b.getMeta(remotePath)
+	if err != nil || !meta.Exists() {
+		return "", err
+	}
+
+	return meta.Get("fs_id").String(), nil
+}
+
+// getMeta 获取文件元信息，文件不存在时返回空结果
+func (b *BaiduProvider) getMeta(remotePath string) (gjson.Result, error) {
 	remotePath = strings.Trim(remotePath, "/")
 	if remotePath == "" {
-		return "", nil
+		return gjson.Result{}, nil
 	}
 
 	// 获取文件元信息
-	url := fmt.Sprintf("%s/file?method=meta&access_token=%s", b.baseURL, b.accessToken)
-	url += fmt.Sprintf("&path=/%s", remotePath)
-
-	resp, err := b.httpClient.Get(url)
+	api := fmt.Sprintf("%s/file?method=meta&path=%s&access_token=%s", b.baseURL, url.QueryEscape("/"+remotePath), b.accessToken)
+	result, err := b.get(api)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	result := gjson.ParseBytes(body)
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("获取文件信息失败: %s", result.String())
+		return gjson.Result{}, fmt.Errorf("获取文件信息失败: %w", err)
 	}
 
-	if result.Get("errno").Int() != 0 {
-		return "", nil
+	switch result.Get("errno").Int() {
+	case 0:
+	case -9:
+		// 文件不存在
+		return gjson.Result{}, nil
+	default:
+		return gjson.Result{}, fmt.Errorf("获取文件信息失败: %s", result.String())
 	}
 
-	return result.Get("list.0.fs_id").String(), nil
+	return result.Get("list.0"), nil
 }
 
 // getOrCreateDir 获取或创建目录
@@ -372,38 +408,227 @@ func (b *BaiduProvider) createDirRecursive(remotePath string) (string, error) {
 
 // createSingleDir 创建单个目录
 func (b *BaiduProvider) createSingleDir(remotePath string) error {
-	url := fmt.Sprintf("%s/file?method=create&access_token=%s", b.baseURL, b.accessToken)
+	form := url.Values{}
+	form.Set("path", remotePath)
+	form.Set("size", "0")
+	form.Set("isdir", "1")
+
+	api := fmt.Sprintf("%s/file?method=create&access_token=%s", b.baseURL, b.accessToken)
+	result, err := b.postForm(api, form)
+	if err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	switch result.Get("errno").Int() {
+	case 0:
+		log.Printf("[%s] 创建目录: %s", b.Name(), remotePath)
+	case -8:
+		// 目录已存在
+	default:
+		return fmt.Errorf("创建目录失败: %s", result.String())
+	}
 
-	data := map[string]interface{}{
-		"path": remotePath,
-		"size": 0,
-		"is":   1, // 1 表示目录
+	return nil
+}
+
+// Stat 获取远程文件信息
+func (b *BaiduProvider) Stat(remotePath string) (*FileInfo, error) {
+	meta, err := b.getMeta(remotePath)
+	if err != nil {
+		return nil, err
 	}
 
-	jsonData, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+	if !meta.Exists() {
+		return nil, ErrNotFound
+	}
+
+	return b.parseFileInfo(meta), nil
+}
+
+// DownloadFile 下载文件到本地
+func (b *BaiduProvider) DownloadFile(remotePath, localPath string) error {
+	fsID, err := b.getFsIDByPath(remotePath)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if fsID == "" {
+		return ErrNotFound
+	}
 
-	resp, err := b.httpClient.Do(req)
+	// 获取下载链接
+	url := fmt.Sprintf("%s/multimedia?method=filemetas&dlink=1&fsids=[%s]&access_token=%s", b.baseURL, fsID, b.accessToken)
+	resp, err := b.httpClient.Get(url)
 	if err != nil {
 		return err
 	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	result := gjson.ParseBytes(body)
+	if resp.StatusCode != http.StatusOK || result.Get("errno").Int() != 0 {
+		return fmt.Errorf("获取下载链接失败: %s", result.String())
+	}
+
+	// 下载链接需要附带 access_token 并使用指定 User-Agent
+	req, err := http.NewRequest("GET", result.Get("list.0.dlink").String()+"&access_token="+b.accessToken, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "pan.baidu.com")
+
+	resp, err = b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载文件失败: %s", resp.Status)
+	}
+
+	log.Printf("[%s] 下载文件: %s -> %s", b.Name(), remotePath, localPath)
+	return writeLocalFile(localPath, resp.Body)
+}
+
+// parseFileInfo 解析接口返回的文件信息
+func (b *BaiduProvider) parseFileInfo(item gjson.Result) *FileInfo {
+	return &FileInfo{
+		Path:    item.Get("path").String(),
+		Name:    item.Get("server_filename").String(),
+		Size:    item.Get("size").Int(),
+		IsDir:   item.Get("isdir").Int() == 1,
+		ModTime: time.Unix(item.Get("server_mtime").Int(), 0),
+		Hash:    item.Get("md5").String(),
+	}
+}
+
+// MoveFile 移动（重命名）文件
+func (b *BaiduProvider) MoveFile(oldPath, newPath string) error {
+	newPath = "/" + strings.Trim(newPath, "/")
+
+	destDir, err := b.getOrCreateDir(path.Dir(newPath))
+	if err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	filelist, _ := json.Marshal([]map[string]string{{
+		"path":    "/" + strings.Trim(oldPath, "/"),
+		"dest":    destDir,
+		"newname": path.Base(newPath),
+		"ondup":   "fail",
+	}})
+
+	form := url.Values{}
+	form.Set("async", "0")
+	form.Set("filelist", string(filelist))
+
+	api := fmt.Sprintf("%s/file?method=filemanager&opera=move&access_token=%s", b.baseURL, b.accessToken)
+	result, err := b.postForm(api, form)
+	if err != nil {
+		return fmt.Errorf("移动文件失败: %w", err)
+	}
+	if result.Get("errno").Int() != 0 {
+		return fmt.Errorf("移动文件失败: %s", result.String())
+	}
+
+	log.Printf("[%s] 移动文件: %s -> %s", b.Name(), oldPath, newPath)
+	return nil
+}
+
+// List 列举目录下的文件
+func (b *BaiduProvider) List(remotePath string) ([]FileInfo, error) {
+	dir := "/" + strings.Trim(remotePath, "/")
+	const limit = 1000
+
+	var files []FileInfo
+	for start := 0; ; start += limit {
+		api := fmt.Sprintf("%s/file?method=list&dir=%s&start=%d&limit=%d&access_token=%s",
+			b.baseURL, url.QueryEscape(dir), start, limit, b.accessToken)
+
+		result, err := b.get(api)
+		if err != nil {
+			return nil, fmt.Errorf("列举文件失败: %w", err)
+		}
+
+		switch result.Get("errno").Int() {
+		case 0:
+		case -9:
+			return nil, ErrNotFound
+		default:
+			return nil, fmt.Errorf("列举文件失败: %s", result.String())
+		}
+
+		items := result.Get("list").Array()
+		for _, item := range items {
+			files = append(files, *b.parseFileInfo(item))
+		}
+
+		if len(items) < limit {
+			return files, nil
+		}
+	}
+}
+
+// Verify 校验 access_token 并获取账号与容量信息
+func (b *BaiduProvider) Verify() (*AccountInfo, error) {
+	user, err := b.get(fmt.Sprintf("%s/nas?method=uinfo&access_token=%s", b.baseURL, b.accessToken))
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.Get("errno").Int() != 0 {
+		return nil, fmt.Errorf("获取用户信息失败: %s", user.String())
+	}
+
+	// 容量接口不在 xpan 路径下
+	quotaURL := strings.TrimSuffix(b.baseURL, "/rest/2.0/xpan") + "/api/quota"
+	quota, err := b.get(fmt.Sprintf("%s?checkfree=1&checkexpire=1&access_token=%s", quotaURL, b.accessToken))
+	if err != nil {
+		return nil, fmt.Errorf("获取容量信息失败: %w", err)
+	}
+	if quota.Get("errno").Int() != 0 {
+		return nil, fmt.Errorf("获取容量信息失败: %s", quota.String())
+	}
+
+	return &AccountInfo{
+		UserName:   user.Get("baidu_name").String(),
+		UsedSpace:  quota.Get("used").Int(),
+		TotalSpace: quota.Get("total").Int(),
+	}, nil
+}
+
+// get 发送 GET 请求并解析返回结果
+func (b *BaiduProvider) get(api string) (gjson.Result, error) {
+	resp, err := b.httpClient.Get(api)
+	if err != nil {
+		return gjson.Result{}, err
+	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	result := gjson.ParseBytes(body)
 
 	if resp.StatusCode != http.StatusOK {
-		// 检查是否目录已存在
-		if result.Get("errno").Int() == -8 {
-			return nil
-		}
-		return fmt.Errorf("创建目录失败: %s", result.String())
+		return result, fmt.Errorf("%s: %s", resp.Status, result.String())
 	}
 
-	return nil
+	return result, nil
+}
+
+// postForm 发送表单 POST 请求并解析返回结果
+func (b *BaiduProvider) postForm(api string, form url.Values) (gjson.Result, error) {
+	resp, err := b.httpClient.PostForm(api, form)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result := gjson.ParseBytes(body)
+
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("%s: %s", resp.Status, result.String())
+	}
+
+	return result, nil
 }
@@ -0,0 +1,96 @@
+package provider_test
+
+import (
+	"net"
+	"testing"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+	"CloudFileSync/provider/providertest"
+)
+
+// newFromTokens 通过注册表创建提供商
+func newFromTokens(t *testing.T, providerType string, tokens map[string]string) provider.Provider {
+	t.Helper()
+
+	p, err := provider.NewProvider(config.ProviderConfig{Type: providerType, Name: providerType, Tokens: tokens})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLocalConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return newFromTokens(t, "local", map[string]string{"root": t.TempDir()})
+	}, providertest.Options{LargeFileSize: 8 << 20})
+}
+
+func TestAliYunConformance(t *testing.T) {
+	srv := providertest.NewAliYunServer()
+	defer srv.Close()
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return providertest.Connect(t, provider.NewAliYunProviderWithClient, srv.Server, srv.Tokens())
+	}, providertest.Options{LargeFileSize: 25 << 20})
+}
+
+func TestBaiduConformance(t *testing.T) {
+	srv := providertest.NewBaiduServer()
+	defer srv.Close()
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return providertest.Connect(t, provider.NewBaiduProviderWithClient, srv.Server, srv.Tokens())
+	}, providertest.Options{LargeFileSize: 9 << 20})
+}
+
+func TestS3Conformance(t *testing.T) {
+	srv := providertest.NewS3Server()
+	defer srv.Close()
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return newFromTokens(t, "s3", srv.Tokens())
+	}, providertest.Options{LargeFileSize: 65 << 20})
+}
+
+func TestOneDriveConformance(t *testing.T) {
+	srv := providertest.NewOneDriveServer()
+	defer srv.Close()
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return providertest.Connect(t, provider.NewOneDriveProviderWithClient, srv.Server, srv.Tokens())
+	}, providertest.Options{LargeFileSize: 25 << 20})
+}
+
+func TestPan115Conformance(t *testing.T) {
+	srv := providertest.NewPan115Server()
+	defer srv.Close()
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return providertest.Connect(t, provider.NewPan115ProviderWithClient, srv.Server, srv.Tokens())
+	}, providertest.Options{LargeFileSize: 25 << 20})
+}
+
+func TestWebDAVConformance(t *testing.T) {
+	srv := newWebDAVServer(t, "", "")
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return newFromTokens(t, "webdav", map[string]string{"url": srv.URL})
+	}, providertest.Options{LargeFileSize: 33 << 20})
+}
+
+func TestSFTPConformance(t *testing.T) {
+	addr := startSFTPServer(t, "test", "secret")
+	host, port, _ := net.SplitHostPort(addr)
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		return newFromTokens(t, "sftp", map[string]string{
+			"host":                     host,
+			"port":                     port,
+			"username":                 "test",
+			"password":                 "secret",
+			"insecure_ignore_host_key": "true",
+			"root":                     t.TempDir(),
+		})
+	}, providertest.Options{LargeFileSize: 8 << 20})
+}
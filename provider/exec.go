@@ -0,0 +1,392 @@
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"CloudFileSync/provider/plugin"
+)
+
+const (
+	// execDefaultTimeout 默认单次请求超时
+	execDefaultTimeout = 5 * time.Minute
+	// execMaxRestarts 在 execRestartWindow 内允许的最大启动次数，超过后不再自动重启
+	execMaxRestarts   = 5
+	execRestartWindow = time.Minute
+)
+
+// ExecProvider 外部插件提供商，通过标准输入输出与插件进程通信（协议见 provider/plugin）
+type ExecProvider struct {
+	command string
+	args    []string
+	tokens  map[string]string
+	timeout time.Duration
+	name    atomic.Value // 插件名称（string），初始化后更新；读取不加锁，请求进行中 Name() 也不会被阻塞
+
+	mu     sync.Mutex // 持有期间独占与插件进程的通信
+	proc   *pluginProcess
+	starts []time.Time // 最近的启动时间，用于限制重启频率
+	lastID uint64
+	closed bool
+}
+
+// pluginProcess 运行中的插件进程
+type pluginProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan plugin.Response
+	done      chan struct{} // 进程退出或输出关闭时关闭
+	quit      chan struct{} // 结束进程时关闭
+	stopped   bool
+}
+
+func init() {
+	Register("exec", NewExecProvider, Schema{
+		DisplayName: "外部插件",
+		Icon:        "🧩",
+		Fields: []Field{
+			{Key: "command", Label: "插件路径", Required: true, Placeholder: "/usr/local/bin/cfs-plugin-foo"},
+			{Key: "args", Label: "启动参数", Placeholder: "可选，以空格分隔"},
+			{Key: "timeout", Label: "请求超时（秒）", Type: "number", Placeholder: "300"},
+		},
+		// 插件路径和参数会在本机执行，不允许通过 Web 界面设置
+		ConfigFileOnly: true,
+	})
+}
+
+// NewExecProvider 创建外部插件提供商，插件在首次使用时启动
+func NewExecProvider(tokens map[string]string) (Provider, error) {
+	command := tokens["command"]
+	if command == "" {
+		return nil, fmt.Errorf("缺少 command")
+	}
+
+	timeout := execDefaultTimeout
+	if tokens["timeout"] != "" {
+		seconds, err := strconv.Atoi(tokens["timeout"])
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("无效的 timeout: %s", tokens["timeout"])
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	e := &ExecProvider{
+		command: command,
+		args:    strings.Fields(tokens["args"]),
+		tokens:  tokens,
+		timeout: timeout,
+	}
+	e.name.Store(filepath.Base(command))
+	return e, nil
+}
+
+// Name 返回插件名称
+func (e *ExecProvider) Name() string {
+	return e.name.Load().(string)
+}
+
+// UploadFile 上传文件
+func (e *ExecProvider) UploadFile(localPath, remotePath string) error {
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%s] 上传文件: %s -> %s", e.Name(), localPath, remotePath)
+	if err := e.call(plugin.MethodUpload, plugin.UploadParams{LocalPath: absPath, RemotePath: remotePath}, nil); err != nil {
+		return fmt.Errorf("上传失败: %w", err)
+	}
+
+	log.Printf("[%s] 上传完成: %s", e.Name(), remotePath)
+	return nil
+}
+
+// DeleteFile 删除文件或目录
+func (e *ExecProvider) DeleteFile(remotePath string) error {
+	if err := e.call(plugin.MethodDelete, plugin.PathParams{Path: remotePath}, nil); err != nil {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+
+	log.Printf("[%s] 删除文件: %s", e.Name(), remotePath)
+	return nil
+}
+
+// CreateDir 创建目录
+func (e *ExecProvider) CreateDir(remotePath string) error {
+	if err := e.call(plugin.MethodMkdir, plugin.PathParams{Path: remotePath}, nil); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return nil
+}
+
+// Stat 获取文件信息
+func (e *ExecProvider) Stat(remotePath string) (*FileInfo, error) {
+	var info plugin.FileInfo
+	if err := e.call(plugin.MethodStat, plugin.PathParams{Path: remotePath}, &info); err != nil {
+		return nil, err
+	}
+
+	result := convertPluginFileInfo(info)
+	return &result, nil
+}
+
+// List 列举目录
+func (e *ExecProvider) List(remotePath string) ([]FileInfo, error) {
+	var items []plugin.FileInfo
+	if err := e.call(plugin.MethodList, plugin.PathParams{Path: remotePath}, &items); err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(items))
+	for _, item := range items {
+		files = append(files, convertPluginFileInfo(item))
+	}
+	return files, nil
+}
+
+// convertPluginFileInfo 转换插件返回的文件信息
+func convertPluginFileInfo(info plugin.FileInfo) FileInfo {
+	return FileInfo{
+		Path:    info.Path,
+		Name:    info.Name,
+		Size:    info.Size,
+		IsDir:   info.IsDir,
+		ModTime: info.ModTime,
+		Hash:    info.Hash,
+	}
+}
+
+// call 发送请求并等待响应，插件进程意外退出时重启并重试一次
+func (e *ExecProvider) call(method string, params, result interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := e.roundTrip(method, params)
+		if err == errPluginExited && attempt == 0 {
+			log.Printf("[%s] 插件进程已退出，正在重启", e.Name())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if resp.Error != nil {
+			if resp.Error.Code == plugin.ErrCodeNotFound {
+				return ErrNotFound
+			}
+			return resp.Error
+		}
+
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("解析插件响应失败: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// errPluginExited 插件进程在请求过程中退出
+var errPluginExited = fmt.Errorf("插件进程已退出")
+
+// roundTrip 发送一次请求，调用方需持有锁
+func (e *ExecProvider) roundTrip(method string, params interface{}) (*plugin.Response, error) {
+	if err := e.ensureStarted(); err != nil {
+		return nil, err
+	}
+	return e.send(e.proc, method, params)
+}
+
+// send 向插件进程发送请求并等待对应 ID 的响应，超时时结束进程
+func (e *ExecProvider) send(proc *pluginProcess, method string, params interface{}) (*plugin.Response, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	e.lastID++
+	req := plugin.Request{ID: e.lastID, Method: method, Params: data}
+	line, _ := json.Marshal(req)
+	if _, err := proc.stdin.Write(append(line, '\n')); err != nil {
+		e.stop(proc)
+		return nil, errPluginExited
+	}
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case resp := <-proc.responses:
+			if resp.ID != req.ID {
+				log.Printf("[%s] 忽略不匹配的插件响应: %d", e.Name(), resp.ID)
+				continue
+			}
+			return &resp, nil
+		case <-proc.done:
+			e.stop(proc)
+			return nil, errPluginExited
+		case <-timer.C:
+			log.Printf("[%s] 插件请求超时（%s %v），结束插件进程", e.Name(), method, e.timeout)
+			e.stop(proc)
+			return nil, fmt.Errorf("插件请求超时: %s", method)
+		}
+	}
+}
+
+// ensureStarted 启动插件进程并完成初始化，调用方需持有锁
+func (e *ExecProvider) ensureStarted() error {
+	if e.proc != nil {
+		return nil
+	}
+	if e.closed {
+		return fmt.Errorf("插件已关闭")
+	}
+
+	// 限制重启频率，避免插件启动即崩溃时反复拉起
+	now := time.Now()
+	recent := e.starts[:0]
+	for _, t := range e.starts {
+		if now.Sub(t) < execRestartWindow {
+			recent = append(recent, t)
+		}
+	}
+	e.starts = recent
+	if len(e.starts) >= execMaxRestarts {
+		return fmt.Errorf("插件在 %v 内启动了 %d 次，已停止重启", execRestartWindow, len(e.starts))
+	}
+	e.starts = append(e.starts, now)
+
+	proc, err := e.start()
+	if err != nil {
+		return err
+	}
+
+	// 握手
+	resp, err := e.send(proc, plugin.MethodInit, plugin.InitParams{Version: plugin.ProtocolVersion, Tokens: e.tokens})
+	if err != nil {
+		return fmt.Errorf("插件初始化失败: %w", err)
+	}
+	if resp.Error != nil {
+		e.stop(proc)
+		return fmt.Errorf("插件初始化失败: %w", resp.Error)
+	}
+
+	var result plugin.InitResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		e.stop(proc)
+		return fmt.Errorf("解析插件初始化结果失败: %w", err)
+	}
+	if result.Version != plugin.ProtocolVersion {
+		e.stop(proc)
+		return fmt.Errorf("插件协议版本不兼容: %d（需要 %d）", result.Version, plugin.ProtocolVersion)
+	}
+	if result.Name != "" {
+		e.name.Store(result.Name)
+	}
+
+	e.proc = proc
+	log.Printf("[%s] 插件已启动: %s", e.Name(), e.command)
+	return nil
+}
+
+// start 启动插件进程
+func (e *ExecProvider) start() (*pluginProcess, error) {
+	cmd := exec.Command(e.command, e.args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动插件失败: %w", err)
+	}
+
+	proc := &pluginProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan plugin.Response),
+		done:      make(chan struct{}),
+		quit:      make(chan struct{}),
+	}
+
+	// 读取响应
+	go func() {
+		defer close(proc.done)
+		decoder := json.NewDecoder(stdout)
+		for {
+			var resp plugin.Response
+			if err := decoder.Decode(&resp); err != nil {
+				if err != io.EOF {
+					log.Printf("[%s] 解析插件输出失败: %v", e.command, err)
+				}
+				return
+			}
+			select {
+			case proc.responses <- resp:
+			case <-proc.quit:
+				return
+			}
+		}
+	}()
+
+	// 转发标准错误输出到日志
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Printf("[%s] 插件: %s", filepath.Base(e.command), scanner.Text())
+		}
+	}()
+
+	return proc, nil
+}
+
+// Close 实现 io.Closer 接口，结束插件进程，之后不再重新启动
+func (e *ExecProvider) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	if e.proc != nil {
+		e.stop(e.proc)
+	}
+	return nil
+}
+
+// stop 结束插件进程，调用方需持有锁
+func (e *ExecProvider) stop(proc *pluginProcess) {
+	if e.proc == proc {
+		e.proc = nil
+	}
+	if proc.stopped {
+		return
+	}
+	proc.stopped = true
+
+	close(proc.quit)
+	proc.stdin.Close()
+	if proc.cmd.Process != nil {
+		proc.cmd.Process.Kill()
+	}
+	go proc.cmd.Wait()
+}
@@ -0,0 +1,174 @@
+package provider_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+	"CloudFileSync/provider/plugin"
+	"CloudFileSync/provider/providertest"
+)
+
+// 测试二进制通过环境变量 CFS_TEST_PLUGIN 充当插件进程
+func TestMain(m *testing.M) {
+	switch os.Getenv("CFS_TEST_PLUGIN") {
+	case "":
+		os.Exit(m.Run())
+	default:
+		plugin.Main(&testPlugin{mode: os.Getenv("CFS_TEST_PLUGIN"), marker: os.Getenv("CFS_TEST_MARKER")})
+		os.Exit(0)
+	}
+}
+
+// testPlugin 用于测试重启和超时的插件
+type testPlugin struct {
+	mode   string // crash: 第一次上传时退出；hang: stat 时不响应
+	marker string // crash 模式下记录是否已经崩溃过
+}
+
+func (p *testPlugin) Init(tokens map[string]string) (string, error) { return "测试插件", nil }
+func (p *testPlugin) Delete(remotePath string) error                { return nil }
+func (p *testPlugin) Mkdir(remotePath string) error                 { return nil }
+func (p *testPlugin) List(remotePath string) ([]plugin.FileInfo, error) {
+	return nil, plugin.ErrNotFound
+}
+
+func (p *testPlugin) Upload(localPath, remotePath string) error {
+	if p.mode == "crash" {
+		if _, err := os.Stat(p.marker); os.IsNotExist(err) {
+			os.WriteFile(p.marker, nil, 0644)
+			os.Exit(3)
+		}
+	}
+	return nil
+}
+
+func (p *testPlugin) Stat(remotePath string) (*plugin.FileInfo, error) {
+	if p.mode == "hang" {
+		time.Sleep(time.Hour)
+	}
+	return nil, plugin.ErrNotFound
+}
+
+// newTestPlugin 创建以测试二进制为插件的 exec 提供商
+func newTestPlugin(t *testing.T, mode string, tokens map[string]string) provider.Provider {
+	t.Helper()
+
+	t.Setenv("CFS_TEST_PLUGIN", mode)
+	t.Setenv("CFS_TEST_MARKER", filepath.Join(t.TempDir(), "crashed"))
+
+	if tokens == nil {
+		tokens = map[string]string{}
+	}
+	tokens["command"] = os.Args[0]
+
+	p, err := provider.NewProvider(config.ProviderConfig{Type: "exec", Name: mode, Tokens: tokens})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestExecConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要编译参考插件")
+	}
+
+	bin := filepath.Join(t.TempDir(), "cfs-plugin-dir")
+	build := exec.Command("go", "build", "-o", bin, "CloudFileSync/plugins/cfs-plugin-dir")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("编译参考插件失败: %v\n%s", err, out)
+	}
+
+	providertest.Run(t, func(t *testing.T) provider.Provider {
+		p, err := provider.NewProvider(config.ProviderConfig{
+			Type:   "exec",
+			Name:   "dir",
+			Tokens: map[string]string{"command": bin, "root": t.TempDir()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}, providertest.Options{LargeFileSize: 8 << 20})
+}
+
+func TestExecRestartsCrashedPlugin(t *testing.T) {
+	p := newTestPlugin(t, "crash", nil)
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("a"), 0644)
+
+	// 第一次上传时插件退出，应自动重启并重试成功
+	if err := p.UploadFile(local, "/a.txt"); err != nil {
+		t.Fatalf("插件崩溃后重试失败: %v", err)
+	}
+	if p.Name() != "测试插件" {
+		t.Errorf("Name() = %q", p.Name())
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	p := newTestPlugin(t, "hang", map[string]string{"timeout": "1"})
+
+	_, err := p.(provider.Stater).Stat("/a.txt")
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Fatalf("期望超时错误，实际为 %v", err)
+	}
+
+	// 超时后插件被结束，下一次请求会重新启动
+	if err := p.CreateDir("/d"); err != nil {
+		t.Fatalf("超时后重启失败: %v", err)
+	}
+}
+
+func TestExecNotFound(t *testing.T) {
+	p := newTestPlugin(t, "ok", nil)
+
+	if _, err := p.(provider.Lister).List("/missing"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("期望 ErrNotFound，实际为 %v", err)
+	}
+}
+
+func TestExecClose(t *testing.T) {
+	p := newTestPlugin(t, "ok", nil)
+
+	if err := p.CreateDir("/d"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.Close(p); err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭后不再启动新的插件进程
+	if err := p.CreateDir("/d"); err == nil {
+		t.Fatal("期望关闭后请求失败")
+	}
+}
+
+func TestExecNameDuringRequest(t *testing.T) {
+	p := newTestPlugin(t, "hang", map[string]string{"timeout": "2"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.(provider.Stater).Stat("/a.txt")
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	// 请求等待响应期间 Name() 不被阻塞（日志和同步事件都会调用）
+	named := make(chan string, 1)
+	go func() { named <- p.Name() }()
+	select {
+	case <-named:
+	case <-time.After(time.Second):
+		t.Fatal("请求进行中 Name() 被阻塞")
+	}
+	<-done
+}
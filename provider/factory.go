@@ -7,12 +7,29 @@ import (
 
 // NewProvider 根据配置创建云盘提供商
 func NewProvider(providerCfg config.ProviderConfig) (Provider, error) {
-	switch providerCfg.Type {
-	case "aliyun":
-		return NewAliYunProvider(providerCfg.Tokens)
-	case "baidu":
-		return NewBaiduProvider(providerCfg.Tokens)
-	default:
+	registryMu.RLock()
+	reg, ok := registry[providerCfg.Type]
+	registryMu.RUnlock()
+
+	if !ok {
 		return nil, fmt.Errorf("不支持的云盘类型: %s", providerCfg.Type)
 	}
+
+	// 解析 ${ENV}、file:、secret: 引用
+	tokens, err := providerCfg.ResolvedTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reg.schema.Validate(tokens); err != nil {
+		return nil, err
+	}
+
+	p, err := reg.constructor(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	watchTokens(p, providerCfg.Name)
+	return p, nil
 }
@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalProvider 本地目录提供商，将文件镜像到另一个目录（如 NAS 挂载点、USB 备份盘）
+type LocalProvider struct {
+	root string
+}
+
+func init() {
+	Register("local", NewLocalProvider, Schema{
+		DisplayName: "本地目录",
+		Icon:        "💾",
+		Fields: []Field{
+			{Key: "root", Label: "根目录", Required: true, Placeholder: "/mnt/nas 或 /Volumes/Backup",
+				Help: "文件将镜像到「根目录 + 目标目录」下，根目录必须已存在（如 NAS 挂载点、USB 备份盘）"},
+		},
+	})
+}
+
+// NewLocalProvider 创建本地目录提供商
+func NewLocalProvider(tokens map[string]string) (Provider, error) {
+	root := tokens["root"]
+	if root == "" {
+		return nil, fmt.Errorf("缺少 root")
+	}
+
+	// 不自动创建根目录，避免挂载点未挂载时把文件写到本机磁盘
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("根目录不可用: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("根目录不是目录: %s", root)
+	}
+
+	return &LocalProvider{root: root}, nil
+}
+
+// Name 返回提供商名称
+func (l *LocalProvider) Name() string {
+	return "本地目录"
+}
+
+// UploadFile 复制文件到目标目录
+func (l *LocalProvider) UploadFile(localPath, remotePath string) error {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	if fileInfo.IsDir() {
+		return l.CreateDir(remotePath)
+	}
+
+	dest := l.fullPath(remotePath)
+
+	// 大小和修改时间一致时视为未变化
+	if destInfo, err := os.Stat(dest); err == nil && !destInfo.IsDir() &&
+		destInfo.Size() == fileInfo.Size() && destInfo.ModTime().Equal(fileInfo.ModTime()) {
+		log.Printf("[%s] 文件已存在，跳过上传: %s", l.Name(), remotePath)
+		return nil
+	}
+
+	log.Printf("[%s] 上传文件: %s -> %s", l.Name(), localPath, remotePath)
+
+	if err := copyFile(localPath, dest); err != nil {
+		return fmt.Errorf("复制文件失败: %w", err)
+	}
+
+	// 保留修改时间，便于后续比较
+	if err := os.Chtimes(dest, fileInfo.ModTime(), fileInfo.ModTime()); err != nil {
+		return err
+	}
+
+	log.Printf("[%s] 上传完成: %s", l.Name(), remotePath)
+	return nil
+}
+
+// DeleteFile 删除文件或目录
+func (l *LocalProvider) DeleteFile(remotePath string) error {
+	dest := l.fullPath(remotePath)
+	if dest == filepath.Clean(l.root) {
+		return fmt.Errorf("不能删除根目录")
+	}
+
+	if _, err := os.Lstat(dest); os.IsNotExist(err) {
+		log.Printf("[%s] 文件不存在，跳过删除: %s", l.Name(), remotePath)
+		return nil
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+
+	log.Printf("[%s] 删除文件: %s", l.Name(), remotePath)
+	return nil
+}
+
+// CreateDir 创建目录
+func (l *LocalProvider) CreateDir(remotePath string) error {
+	if err := os.MkdirAll(l.fullPath(remotePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return nil
+}
+
+// Stat 获取文件信息
+func (l *LocalProvider) Stat(remotePath string) (*FileInfo, error) {
+	info, err := os.Stat(l.fullPath(remotePath))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l.fileInfo(cleanRemotePath(remotePath), info), nil
+}
+
+// List 列举目录
+func (l *LocalProvider) List(remotePath string) ([]FileInfo, error) {
+	entries, err := os.ReadDir(l.fullPath(remotePath))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cleanRemotePath(remotePath)
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, *l.fileInfo(path.Join(dir, entry.Name()), info))
+	}
+
+	return files, nil
+}
+
+// DownloadFile 复制文件到本地
+func (l *LocalProvider) DownloadFile(remotePath, localPath string) error {
+	src := l.fullPath(remotePath)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return ErrNotFound
+	}
+
+	log.Printf("[%s] 下载文件: %s -> %s", l.Name(), remotePath, localPath)
+	return copyFile(src, localPath)
+}
+
+// MoveFile 移动文件
+func (l *LocalProvider) MoveFile(oldPath, newPath string) error {
+	src := l.fullPath(oldPath)
+	if _, err := os.Lstat(src); os.IsNotExist(err) {
+		return ErrNotFound
+	}
+
+	dest := l.fullPath(newPath)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	if err := os.Rename(src, dest); err != nil {
+		return fmt.Errorf("移动文件失败: %w", err)
+	}
+
+	log.Printf("[%s] 移动文件: %s -> %s", l.Name(), oldPath, newPath)
+	return nil
+}
+
+// Verify 检查根目录是否可写
+func (l *LocalProvider) Verify() (*AccountInfo, error) {
+	tmp, err := os.CreateTemp(l.root, ".cfs-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("根目录不可写: %w", err)
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	return &AccountInfo{UserName: l.root}, nil
+}
+
+// fullPath 将远程路径映射到根目录下，不允许通过 .. 跳出根目录
+func (l *LocalProvider) fullPath(remotePath string) string {
+	return filepath.Join(l.root, filepath.FromSlash(cleanRemotePath(remotePath)))
+}
+
+// fileInfo 转换文件信息
+func (l *LocalProvider) fileInfo(remotePath string, info os.FileInfo) *FileInfo {
+	return &FileInfo{
+		Path:    remotePath,
+		Name:    info.Name(),
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// cleanRemotePath 规范化远程路径为以 / 开头的绝对路径
+func cleanRemotePath(remotePath string) string {
+	return path.Clean("/" + strings.Trim(remotePath, "/"))
+}
+
+// copyFile 复制文件
+func copyFile(src, dest string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeLocalFile(dest, file)
+}
@@ -0,0 +1,584 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"CloudFileSync/auth"
+)
+
+const (
+	// oneDriveGraphURL Microsoft Graph 接口地址
+	oneDriveGraphURL = "https://graph.microsoft.com/v1.0"
+	// oneDriveSimpleUploadLimit 不超过此大小的文件直接 PUT 上传，更大的文件使用上传会话
+	oneDriveSimpleUploadLimit = 4 * 1024 * 1024
+	// oneDriveChunkSize 上传会话的分片大小，必须是 320 KiB 的整数倍
+	oneDriveChunkSize = 32 * 320 * 1024
+	// oneDriveMaxRetries 接口限流（429、503）时的最大重试次数
+	oneDriveMaxRetries = 3
+)
+
+// OneDriveProvider OneDrive 提供商（Microsoft Graph），按路径寻址，无需维护文件 ID
+type OneDriveProvider struct {
+	httpClient *http.Client
+	driveURL   string       // 网盘接口地址，如 https://graph.microsoft.com/v1.0/me/drive
+	oauth      *auth.Client // 用于刷新令牌，未配置 client_id 时为 nil
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time                      // 为零值表示未知，只在接口返回 401 时刷新
+	onRefresh    func(tokens map[string]string) // 刷新令牌后调用，见 OnTokenRefresh
+}
+
+func init() {
+	Register("onedrive", NewOneDriveProvider, Schema{
+		DisplayName: "OneDrive",
+		Icon:        "🔷",
+		Fields: []Field{
+			{Key: "access_token", Label: "Access Token", Type: "password", Placeholder: "输入 Microsoft Graph access_token"},
+			{Key: "refresh_token", Label: "Refresh Token", Type: "password", Placeholder: "用于 access_token 过期后自动续期"},
+			{Key: "client_id", Label: "Client ID（可选，用于 OAuth 授权和刷新令牌）", Placeholder: "Azure 应用注册的应用程序（客户端）ID"},
+			{Key: "client_secret", Label: "Client Secret（可选）", Type: "password", Placeholder: "公共客户端应用可留空",
+				Help: "保存配置后，可在云盘列表中点击「授权」自动获取 Access Token 和 Refresh Token"},
+			{Key: "drive_id", Label: "Drive ID（可选）", Placeholder: "留空使用当前账号的默认网盘"},
+		},
+		AnyOf: []string{"access_token", "refresh_token", "client_id"},
+	})
+}
+
+// NewOneDriveProvider 创建 OneDrive 提供商
+func NewOneDriveProvider(tokens map[string]string) (Provider, error) {
+	return NewOneDriveProviderWithClient(tokens, "", nil)
+}
+
+// NewOneDriveProviderWithClient 使用指定的接口地址和 HTTP 客户端创建 OneDrive 提供商，用于测试或代理。
+// baseURL 为空时使用 Microsoft Graph 和 Microsoft 账号的令牌接口，
+// 非空时 Graph 接口为 baseURL + "/v1.0"，令牌接口为 baseURL + "/oauth2/v2.0/token"
+func NewOneDriveProviderWithClient(tokens map[string]string, baseURL string, httpClient *http.Client) (Provider, error) {
+	o := &OneDriveProvider{
+		httpClient:   httpClient,
+		accessToken:  tokens["access_token"],
+		refreshToken: tokens["refresh_token"],
+	}
+	if o.httpClient == nil {
+		o.httpClient = &http.Client{
+			Timeout: 0, // 大文件分片上传耗时不确定，不设置整体超时
+		}
+	}
+
+	if tokens["client_id"] != "" {
+		client, err := auth.NewClient("onedrive", tokens, "")
+		if err != nil {
+			return nil, err
+		}
+		if baseURL != "" {
+			client.Endpoint.TokenURL = strings.TrimSuffix(baseURL, "/") + "/oauth2/v2.0/token"
+		}
+		o.oauth = client
+	}
+
+	if o.accessToken == "" && !o.canRefresh() {
+		return nil, fmt.Errorf("缺少 access_token，请先完成授权或同时配置 refresh_token 和 client_id")
+	}
+
+	if expiresAt := tokens["expires_at"]; expiresAt != "" && o.accessToken != "" {
+		if t, err := time.Parse(time.RFC3339, expiresAt); err == nil {
+			o.expiresAt = t
+		}
+	}
+
+	graphURL := oneDriveGraphURL
+	if baseURL != "" {
+		graphURL = strings.TrimSuffix(baseURL, "/") + "/v1.0"
+	}
+	if driveID := tokens["drive_id"]; driveID != "" {
+		o.driveURL = graphURL + "/drives/" + url.PathEscape(driveID)
+	} else {
+		o.driveURL = graphURL + "/me/drive"
+	}
+
+	return o, nil
+}
+
+// Name 返回提供商名称
+func (o *OneDriveProvider) Name() string {
+	return "OneDrive"
+}
+
+// UploadFile 上传文件，小文件直接上传，大文件使用上传会话分片上传。父目录不存在时由服务端自动创建
+func (o *OneDriveProvider) UploadFile(localPath, remotePath string) error {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	if fileInfo.IsDir() {
+		return o.CreateDir(remotePath)
+	}
+
+	log.Printf("[%s] 上传文件: %s -> %s", o.Name(), localPath, remotePath)
+
+	if fileInfo.Size() <= oneDriveSimpleUploadLimit {
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			return fmt.Errorf("读取文件失败: %w", err)
+		}
+		if _, err := o.call("PUT", o.itemURL(remotePath, "content"), "application/octet-stream", data); err != nil {
+			return fmt.Errorf("上传失败: %w", err)
+		}
+	} else if err := o.uploadSession(localPath, remotePath, fileInfo.Size()); err != nil {
+		return fmt.Errorf("分片上传失败: %w", err)
+	}
+
+	log.Printf("[%s] 上传完成: %s", o.Name(), remotePath)
+	return nil
+}
+
+// uploadSession 创建上传会话并按顺序上传分片，失败时取消会话
+func (o *OneDriveProvider) uploadSession(localPath, remotePath string, size int64) error {
+	session, err := o.callJSON("POST", o.itemURL(remotePath, "createUploadSession"), map[string]interface{}{
+		"item": map[string]string{"@microsoft.graph.conflictBehavior": "replace"},
+	})
+	if err != nil {
+		return fmt.Errorf("创建上传会话失败: %w", err)
+	}
+
+	uploadURL := session.Get("uploadUrl").String()
+	if uploadURL == "" {
+		return fmt.Errorf("创建上传会话失败: 未返回 uploadUrl")
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buf := make([]byte, oneDriveChunkSize)
+	for offset := int64(0); offset < size; {
+		n, err := io.ReadFull(file, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			o.cancelUploadSession(uploadURL)
+			return fmt.Errorf("读取文件失败: %w", err)
+		}
+
+		if err := o.uploadChunk(uploadURL, buf[:n], offset, size); err != nil {
+			o.cancelUploadSession(uploadURL)
+			return err
+		}
+
+		offset += int64(n)
+		log.Printf("[%s] 上传进度: %s %.1f%%", o.Name(), remotePath, float64(offset)*100/float64(size))
+	}
+
+	return nil
+}
+
+// uploadChunk 上传一个分片。uploadUrl 已包含授权信息，不能携带 Authorization 头
+func (o *OneDriveProvider) uploadChunk(uploadURL string, chunk []byte, offset, size int64) error {
+	req, err := http.NewRequest("PUT", uploadURL, bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 202 表示还需要后续分片，200/201 表示上传完成
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK, http.StatusCreated:
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return oneDriveResponseError(resp)
+}
+
+// cancelUploadSession 取消上传会话，释放已上传的分片
+func (o *OneDriveProvider) cancelUploadSession(uploadURL string) {
+	req, err := http.NewRequest("DELETE", uploadURL, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := o.httpClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// DeleteFile 删除文件或目录，文件不存在时直接返回
+func (o *OneDriveProvider) DeleteFile(remotePath string) error {
+	_, err := o.call("DELETE", o.itemURL(remotePath, ""), "", nil)
+	if errors.Is(err, ErrNotFound) {
+		log.Printf("[%s] 文件不存在，跳过删除: %s", o.Name(), remotePath)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+
+	log.Printf("[%s] 删除文件: %s", o.Name(), remotePath)
+	return nil
+}
+
+// CreateDir 逐级创建目录，目录已存在时直接返回
+func (o *OneDriveProvider) CreateDir(remotePath string) error {
+	remotePath = cleanRemotePath(remotePath)
+
+	// 大多数情况下目录已存在，先查询一次避免逐级创建
+	if info, err := o.Stat(remotePath); err == nil {
+		if !info.IsDir {
+			return fmt.Errorf("创建目录失败 %s: 已存在同名文件", remotePath)
+		}
+		return nil
+	}
+
+	current := "/"
+	for _, part := range strings.Split(strings.Trim(remotePath, "/"), "/") {
+		if part == "" {
+			continue
+		}
+
+		_, err := o.callJSON("POST", o.itemURL(current, "children"), map[string]interface{}{
+			"name":                              part,
+			"folder":                            map[string]interface{}{},
+			"@microsoft.graph.conflictBehavior": "fail",
+		})
+		current = path.Join(current, part)
+
+		// 409 表示目录已存在
+		var graphErr *oneDriveError
+		if err != nil && !(errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusConflict) {
+			return fmt.Errorf("创建目录失败 %s: %w", current, err)
+		}
+	}
+	return nil
+}
+
+// Stat 获取文件信息
+func (o *OneDriveProvider) Stat(remotePath string) (*FileInfo, error) {
+	item, err := o.call("GET", o.itemURL(remotePath, ""), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := o.parseItem(path.Dir(cleanRemotePath(remotePath)), item)
+	info.Path = cleanRemotePath(remotePath)
+	return &info, nil
+}
+
+// List 列举目录，按 @odata.nextLink 分页
+func (o *OneDriveProvider) List(remotePath string) ([]FileInfo, error) {
+	dir := cleanRemotePath(remotePath)
+
+	var files []FileInfo
+	next := o.itemURL(dir, "children") + "?$top=200"
+	for next != "" {
+		page, err := o.call("GET", next, "", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Get("value").Array() {
+			files = append(files, o.parseItem(dir, item))
+		}
+		next = page.Get("@odata\\.nextLink").String()
+	}
+
+	return files, nil
+}
+
+// DownloadFile 下载文件，content 接口会重定向到预签名的下载地址
+func (o *OneDriveProvider) DownloadFile(remotePath, localPath string) error {
+	resp, err := o.do("GET", o.itemURL(remotePath, "content"), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载文件失败: %w", oneDriveResponseError(resp))
+	}
+
+	log.Printf("[%s] 下载文件: %s -> %s", o.Name(), remotePath, localPath)
+	return writeLocalFile(localPath, resp.Body)
+}
+
+// MoveFile 移动文件或目录，目标已存在时覆盖
+func (o *OneDriveProvider) MoveFile(oldPath, newPath string) error {
+	newPath = cleanRemotePath(newPath)
+	if err := o.CreateDir(path.Dir(newPath)); err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	parent, err := o.call("GET", o.itemURL(path.Dir(newPath), ""), "", nil)
+	if err != nil {
+		return fmt.Errorf("获取目标目录失败: %w", err)
+	}
+
+	_, err = o.callJSON("PATCH", o.itemURL(oldPath, "")+"?@microsoft.graph.conflictBehavior=replace", map[string]interface{}{
+		"parentReference": map[string]string{"id": parent.Get("id").String()},
+		"name":            path.Base(newPath),
+	})
+	if errors.Is(err, ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("移动文件失败: %w", err)
+	}
+
+	log.Printf("[%s] 移动文件: %s -> %s", o.Name(), oldPath, newPath)
+	return nil
+}
+
+// Verify 校验令牌并获取账号和容量信息
+func (o *OneDriveProvider) Verify() (*AccountInfo, error) {
+	drive, err := o.call("GET", o.driveURL, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取网盘信息失败: %w", err)
+	}
+
+	info := &AccountInfo{
+		UserName:   drive.Get("owner.user.displayName").String(),
+		UsedSpace:  drive.Get("quota.used").Int(),
+		TotalSpace: drive.Get("quota.total").Int(),
+	}
+	if id := drive.Get("id").String(); id != "" {
+		info.DriveIDs = map[string]string{drive.Get("driveType").String(): id}
+	}
+	return info, nil
+}
+
+// parseItem 将 driveItem 转换为文件信息，dir 为所在目录
+func (o *OneDriveProvider) parseItem(dir string, item gjson.Result) FileInfo {
+	name := item.Get("name").String()
+	info := FileInfo{
+		Path:  path.Join(dir, name),
+		Name:  name,
+		Size:  item.Get("size").Int(),
+		IsDir: item.Get("folder").Exists(),
+	}
+	info.ModTime, _ = time.Parse(time.RFC3339, item.Get("lastModifiedDateTime").String())
+
+	// 个人版提供 SHA1，商业版只提供 quickXorHash
+	info.Hash = strings.ToLower(item.Get("file.hashes.sha1Hash").String())
+	if info.Hash == "" {
+		info.Hash = item.Get("file.hashes.quickXorHash").String()
+	}
+	if info.IsDir {
+		info.Size = 0
+	}
+	return info
+}
+
+// itemURL 生成按路径寻址的接口地址，如 /me/drive/root:/a/b.txt:/content
+func (o *OneDriveProvider) itemURL(remotePath, action string) string {
+	remotePath = cleanRemotePath(remotePath)
+
+	if remotePath == "/" {
+		if action == "" {
+			return o.driveURL + "/root"
+		}
+		return o.driveURL + "/root/" + action
+	}
+
+	var escaped []string
+	for _, part := range strings.Split(strings.Trim(remotePath, "/"), "/") {
+		// 冒号用于分隔路径和操作，文件名中的冒号需要转义
+		escaped = append(escaped, strings.ReplaceAll(url.PathEscape(part), ":", "%3A"))
+	}
+
+	u := o.driveURL + "/root:/" + strings.Join(escaped, "/")
+	if action != "" {
+		u += ":/" + action
+	}
+	return u
+}
+
+// callJSON 以 JSON 请求体调用接口
+func (o *OneDriveProvider) callJSON(method, rawURL string, payload interface{}) (gjson.Result, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return o.call(method, rawURL, "application/json", data)
+}
+
+// call 调用接口并解析 JSON 响应，404 返回 ErrNotFound
+func (o *OneDriveProvider) call(method, rawURL, contentType string, body []byte) (gjson.Result, error) {
+	resp, err := o.do(method, rawURL, contentType, body)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return gjson.Result{}, oneDriveResponseError(resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.ParseBytes(data), nil
+}
+
+// do 发送带授权的请求。返回 401 时刷新令牌后重试一次，被限流时按 Retry-After 等待后重试
+func (o *OneDriveProvider) do(method, rawURL, contentType string, body []byte) (*http.Response, error) {
+	refreshed := false
+	for retries := 0; ; retries++ {
+		token, err := o.token()
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := o.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && !refreshed && o.canRefresh():
+			resp.Body.Close()
+			if err := o.renewToken(token); err != nil {
+				return nil, err
+			}
+			refreshed = true
+		case (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && retries < oneDriveMaxRetries:
+			resp.Body.Close()
+			wait := time.Duration(1<<retries) * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			log.Printf("[%s] 请求被限流，%v 后重试", o.Name(), wait)
+			time.Sleep(wait)
+		default:
+			return resp, nil
+		}
+	}
+}
+
+// canRefresh 是否可以使用 refresh_token 续期
+func (o *OneDriveProvider) canRefresh() bool {
+	return o.oauth != nil && o.refreshToken != ""
+}
+
+// token 返回当前的 access_token，已知即将过期或尚未获取时先刷新
+func (o *OneDriveProvider) token() (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	expiring := !o.expiresAt.IsZero() && time.Until(o.expiresAt) < time.Minute
+	if (o.accessToken == "" || expiring) && o.canRefresh() {
+		if err := o.refreshLocked(); err != nil {
+			return "", err
+		}
+	}
+	return o.accessToken, nil
+}
+
+// renewToken 接口返回 401 后刷新令牌，其他请求已用新令牌替换 stale 时不再重复刷新
+func (o *OneDriveProvider) renewToken(stale string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.accessToken != stale {
+		return nil
+	}
+	return o.refreshLocked()
+}
+
+// OnTokenRefresh 实现 TokenRefresher 接口。Microsoft 每次刷新都会轮换 refresh_token，
+// 新令牌需要写回配置，否则重启后使用的旧 refresh_token 可能已失效
+func (o *OneDriveProvider) OnTokenRefresh(fn func(tokens map[string]string)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onRefresh = fn
+}
+
+// refreshLocked 使用 refresh_token 换取新的令牌，调用方需持有锁。刷新后通过 onRefresh 通知新令牌
+func (o *OneDriveProvider) refreshLocked() error {
+	token, err := o.oauth.Refresh(o.refreshToken)
+	if err != nil {
+		return fmt.Errorf("刷新 access_token 失败: %w", err)
+	}
+
+	o.accessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		o.refreshToken = token.RefreshToken
+	}
+	o.expiresAt = time.Time{}
+	if token.ExpiresIn > 0 {
+		o.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	log.Printf("[%s] 已刷新 access_token", o.Name())
+
+	if o.onRefresh != nil {
+		tokens := make(map[string]string)
+		token.Apply(tokens)
+		o.onRefresh(tokens)
+	}
+	return nil
+}
+
+// oneDriveError Graph 接口返回的错误
+type oneDriveError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error 实现 error 接口
+func (e *oneDriveError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// oneDriveResponseError 解析错误响应，404 返回 ErrNotFound
+func oneDriveResponseError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	result := gjson.ParseBytes(body)
+
+	e := &oneDriveError{
+		StatusCode: resp.StatusCode,
+		Code:       result.Get("error.code").String(),
+		Message:    result.Get("error.message").String(),
+	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
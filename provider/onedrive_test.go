@@ -0,0 +1,127 @@
+package provider_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/provider"
+	"CloudFileSync/provider/providertest"
+)
+
+func TestOneDriveRefreshToken(t *testing.T) {
+	srv := providertest.NewOneDriveServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewOneDriveProviderWithClient, srv.Server, srv.Tokens())
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("a"), 0644)
+
+	if err := p.UploadFile(local, "/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Refreshes(); n != 0 {
+		t.Fatalf("令牌有效时刷新了 %d 次", n)
+	}
+
+	// access_token 过期后自动用 refresh_token 续期并重试
+	srv.ExpireAccessToken()
+	if err := p.UploadFile(local, "/b.txt"); err != nil {
+		t.Fatalf("令牌过期后上传失败: %v", err)
+	}
+	// 服务端轮换了 refresh_token，再次过期时需要使用新的 refresh_token
+	srv.ExpireAccessToken()
+	if _, err := p.(provider.Stater).Stat("/b.txt"); err != nil {
+		t.Fatalf("令牌再次过期后查询失败: %v", err)
+	}
+	if n := srv.Refreshes(); n != 2 {
+		t.Fatalf("刷新次数为 %d，期望 2", n)
+	}
+}
+
+func TestOneDriveRefreshTokenNotify(t *testing.T) {
+	srv := providertest.NewOneDriveServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewOneDriveProviderWithClient, srv.Server, srv.Tokens())
+	var saved map[string]string
+	p.(provider.TokenRefresher).OnTokenRefresh(func(tokens map[string]string) {
+		saved = tokens
+	})
+
+	srv.ExpireAccessToken()
+	if _, err := p.(provider.Stater).Stat("/"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换后的 refresh_token 需要写回配置，重启后才能继续使用
+	if saved["refresh_token"] != srv.RefreshToken || saved["access_token"] != srv.AccessToken || saved["expires_at"] == "" {
+		t.Fatalf("通知的令牌为 %v", saved)
+	}
+}
+
+func TestOneDriveRefreshTokenOnly(t *testing.T) {
+	srv := providertest.NewOneDriveServer()
+	defer srv.Close()
+
+	// 只配置 refresh_token 时首次请求前获取 access_token
+	tokens := srv.Tokens()
+	delete(tokens, "access_token")
+	p := providertest.Connect(t, provider.NewOneDriveProviderWithClient, srv.Server, tokens)
+
+	info, err := p.(provider.Verifier).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UserName == "" || info.TotalSpace == 0 {
+		t.Errorf("账号信息不完整: %+v", info)
+	}
+	if n := srv.Refreshes(); n != 1 {
+		t.Fatalf("刷新次数为 %d，期望 1", n)
+	}
+}
+
+func TestOneDriveWithoutRefreshToken(t *testing.T) {
+	srv := providertest.NewOneDriveServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewOneDriveProviderWithClient, srv.Server, map[string]string{"access_token": srv.AccessToken})
+	srv.ExpireAccessToken()
+
+	if _, err := p.(provider.Stater).Stat("/"); err == nil || errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("期望返回授权错误，实际为 %v", err)
+	}
+
+	if _, err := provider.NewOneDriveProviderWithClient(map[string]string{"client_id": "x"}, srv.URL, nil); err == nil {
+		t.Fatal("只有 client_id 时期望返回错误")
+	}
+}
+
+func TestOneDriveListPagination(t *testing.T) {
+	srv := providertest.NewOneDriveServer()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewOneDriveProviderWithClient, srv.Server, srv.Tokens())
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("a"), 0644)
+
+	const count = 250
+	for i := 0; i < count; i++ {
+		if err := p.UploadFile(local, fmt.Sprintf("/many/f%03d.txt", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := p.(provider.Lister).List("/many")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != count {
+		t.Fatalf("列举到 %d 个文件，期望 %d", len(files), count)
+	}
+	if files[count-1].Path != "/many/f249.txt" {
+		t.Errorf("最后一个文件为 %s", files[count-1].Path)
+	}
+}
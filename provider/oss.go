@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ossCredentials 阿里云 OSS 临时凭证（STS），由网盘的上传接口下发
+type ossCredentials struct {
+	Endpoint        string // 如 https://oss-cn-shenzhen.aliyuncs.com
+	AccessKeyID     string
+	AccessKeySecret string
+	SecurityToken   string
+}
+
+// ossCallback 上传完成后 OSS 回调网盘服务端的参数（JSON 原文），网盘据此登记文件
+type ossCallback struct {
+	Callback    string
+	CallbackVar string
+}
+
+// ossClient 使用 OSS 签名 V1 上传对象，支持简单上传和分片上传
+type ossClient struct {
+	creds      ossCredentials
+	httpClient *http.Client
+}
+
+// putObject 简单上传，callback 非空时返回网盘回调的响应
+func (c *ossClient) putObject(bucket, object string, data []byte, callback *ossCallback) ([]byte, error) {
+	resp, err := c.do("PUT", bucket, object, nil, callback.headers(), data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ossError(resp, "上传失败")
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// initiateMultipart 初始化分片上传，返回 UploadId
+func (c *ossClient) initiateMultipart(bucket, object string) (string, error) {
+	resp, err := c.do("POST", bucket, object, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", ossError(resp, "初始化分片上传失败")
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("初始化分片上传失败: 解析响应失败: %w", err)
+	}
+	return result.UploadID, nil
+}
+
+// ossPart 已上传的分片
+type ossPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadPart 上传一个分片
+func (c *ossClient) uploadPart(bucket, object, uploadID string, partNumber int, data []byte) (ossPart, error) {
+	query := url.Values{}
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	query.Set("uploadId", uploadID)
+
+	resp, err := c.do("PUT", bucket, object, query, nil, data)
+	if err != nil {
+		return ossPart{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ossPart{}, ossError(resp, fmt.Sprintf("上传分片 %d 失败", partNumber))
+	}
+	return ossPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")}, nil
+}
+
+// completeMultipart 合并分片，callback 非空时返回网盘回调的响应
+func (c *ossClient) completeMultipart(bucket, object, uploadID string, parts []ossPart, callback *ossCallback) ([]byte, error) {
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name  `xml:"CompleteMultipartUpload"`
+		Parts   []ossPart `xml:"Part"`
+	}{Parts: parts})
+
+	resp, err := c.do("POST", bucket, object, url.Values{"uploadId": {uploadID}}, callback.headers(), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ossError(resp, "完成分片上传失败")
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// abortMultipart 取消分片上传，释放已上传的分片
+func (c *ossClient) abortMultipart(bucket, object, uploadID string) {
+	if resp, err := c.do("DELETE", bucket, object, url.Values{"uploadId": {uploadID}}, nil, nil); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// headers 回调参数需 Base64 编码后放入请求头
+func (cb *ossCallback) headers() map[string]string {
+	if cb == nil || cb.Callback == "" {
+		return nil
+	}
+	headers := map[string]string{
+		"X-Oss-Callback": base64.StdEncoding.EncodeToString([]byte(cb.Callback)),
+	}
+	if cb.CallbackVar != "" {
+		headers["X-Oss-Callback-Var"] = base64.StdEncoding.EncodeToString([]byte(cb.CallbackVar))
+	}
+	return headers
+}
+
+// do 发送经过签名的请求
+func (c *ossClient) do(method, bucket, object string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	u, err := c.url(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = ossQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if c.creds.SecurityToken != "" {
+		req.Header.Set("X-Oss-Security-Token", c.creds.SecurityToken)
+	}
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Authorization", "OSS "+c.creds.AccessKeyID+":"+c.sign(req, bucket, object, query))
+
+	return c.httpClient.Do(req)
+}
+
+// url 生成对象地址。endpoint 为 IP 或 localhost 时使用路径风格（bucket 放在路径中），否则使用虚拟主机风格
+func (c *ossClient) url(bucket, object string) (*url.URL, error) {
+	endpoint := c.creds.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 OSS endpoint: %w", err)
+	}
+
+	if host := u.Hostname(); host == "localhost" || net.ParseIP(host) != nil {
+		u.Path = "/" + bucket + "/" + object
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + object
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return u, nil
+}
+
+// sign 计算 OSS 签名 V1：HMAC-SHA1(VERB\nContent-MD5\nContent-Type\nDate\nOSS 头 + 资源)
+func (c *ossClient) sign(req *http.Request, bucket, object string, query url.Values) string {
+	var ossHeaders []string
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-oss-") {
+			ossHeaders = append(ossHeaders, k+":"+strings.TrimSpace(strings.Join(v, ",")))
+		}
+	}
+	sort.Strings(ossHeaders)
+
+	var b strings.Builder
+	b.WriteString(req.Method + "\n")
+	b.WriteString(req.Header.Get("Content-MD5") + "\n")
+	b.WriteString(req.Header.Get("Content-Type") + "\n")
+	b.WriteString(req.Header.Get("Date") + "\n")
+	for _, h := range ossHeaders {
+		b.WriteString(h + "\n")
+	}
+	b.WriteString("/" + bucket + "/" + object)
+	if q := ossQuery(query); q != "" {
+		b.WriteString("?" + q)
+	}
+
+	h := hmac.New(sha1.New, []byte(c.creds.AccessKeySecret))
+	h.Write([]byte(b.String()))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ossQuery 按名称排序拼接子资源参数，值为空时只保留名称（如 ?uploads）
+func ossQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if v == "" {
+				parts = append(parts, k)
+			} else {
+				parts = append(parts, k+"="+v)
+			}
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// ossError 提取 OSS 错误响应中的错误码和描述
+func ossError(resp *http.Response, action string) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("%s: %s: %s", action, e.Code, e.Message)
+	}
+	return fmt.Errorf("%s: %s", action, resp.Status)
+}
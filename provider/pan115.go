@@ -0,0 +1,767 @@
+package provider
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// pan115BaseURL 开放平台接口地址
+	pan115BaseURL = "https://proapi.115.com"
+	// pan115PassportURL 刷新令牌接口地址
+	pan115PassportURL = "https://passportapi.115.com"
+	// pan115UserAgent 获取下载地址和下载文件时必须使用相同的 User-Agent
+	pan115UserAgent = "CloudFileSync"
+	// pan115PreHashSize 计算 preid 使用的文件头部大小
+	pan115PreHashSize = 128 * 1024
+	// pan115MultipartThreshold 超过此大小的文件使用 OSS 分片上传
+	pan115MultipartThreshold = 20 * 1024 * 1024
+	// pan115PartSize 默认分片大小
+	pan115PartSize = 10 * 1024 * 1024
+	// pan115MaxParts 单个文件最多分片数
+	pan115MaxParts = 10000
+	// pan115PageSize 列举目录时每页数量
+	pan115PageSize = 1000
+)
+
+// pan115NotFoundCodes 表示文件或目录不存在的错误码
+var pan115NotFoundCodes = map[int64]bool{
+	20018: true, // 文件不存在或已删除
+	70005: true, // 按路径查询时路径不存在
+}
+
+// Pan115Provider 115 网盘提供商（115 开放平台）
+type Pan115Provider struct {
+	httpClient  *http.Client
+	baseURL     string
+	passportURL string
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	onRefresh    func(tokens map[string]string) // 刷新令牌后调用，见 OnTokenRefresh
+}
+
+func init() {
+	Register("115", NewPan115Provider, Schema{
+		DisplayName: "115 网盘",
+		Icon:        "🟠",
+		Fields: []Field{
+			{Key: "access_token", Label: "Access Token", Type: "password", Placeholder: "输入 115 开放平台 access_token"},
+			{Key: "refresh_token", Label: "Refresh Token", Type: "password", Placeholder: "用于 access_token 过期后自动续期",
+				Help: "access_token 有效期较短，建议同时填写 refresh_token"},
+		},
+		AnyOf: []string{"access_token", "refresh_token"},
+	})
+}
+
+// NewPan115Provider 创建 115 网盘提供商
+func NewPan115Provider(tokens map[string]string) (Provider, error) {
+	return NewPan115ProviderWithClient(tokens, "", nil)
+}
+
+// NewPan115ProviderWithClient 使用指定的接口地址和 HTTP 客户端创建 115 网盘提供商，用于测试或代理，
+// baseURL 非空时开放平台接口和刷新令牌接口都使用该地址
+func NewPan115ProviderWithClient(tokens map[string]string, baseURL string, httpClient *http.Client) (Provider, error) {
+	if tokens["access_token"] == "" && tokens["refresh_token"] == "" {
+		return nil, fmt.Errorf("缺少 access_token")
+	}
+
+	p := &Pan115Provider{
+		httpClient:   httpClient,
+		baseURL:      pan115BaseURL,
+		passportURL:  pan115PassportURL,
+		accessToken:  tokens["access_token"],
+		refreshToken: tokens["refresh_token"],
+	}
+	if baseURL != "" {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+		p.passportURL = p.baseURL
+	}
+	if p.httpClient == nil {
+		p.httpClient = &http.Client{
+			Timeout: 0, // 分片上传耗时不确定，不设置整体超时
+		}
+	}
+
+	return p, nil
+}
+
+// Name 返回提供商名称
+func (p *Pan115Provider) Name() string {
+	return "115网盘"
+}
+
+// UploadFile 上传文件：先按 SHA1 尝试秒传，服务端没有相同文件时再上传到 OSS
+func (p *Pan115Provider) UploadFile(localPath, remotePath string) error {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	if fileInfo.IsDir() {
+		return p.CreateDir(remotePath)
+	}
+
+	fileSHA1, preSHA1, err := pan115Hashes(localPath)
+	if err != nil {
+		return fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+
+	remotePath = cleanRemotePath(remotePath)
+
+	// 网盘允许同名文件，内容相同时跳过，不同时上传完成后删除旧文件
+	old, err := p.getInfo(remotePath)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("检查文件是否存在失败: %w", err)
+	}
+	if old != nil && old.IsDir {
+		return fmt.Errorf("上传失败: %s 是目录", remotePath)
+	}
+	if old != nil && strings.EqualFold(old.Hash, fileSHA1) {
+		log.Printf("[%s] 文件已存在，跳过上传: %s", p.Name(), remotePath)
+		return nil
+	}
+
+	log.Printf("[%s] 上传文件: %s -> %s", p.Name(), localPath, remotePath)
+
+	parentID, err := p.getOrCreateDir(path.Dir(remotePath))
+	if err != nil {
+		return fmt.Errorf("创建父目录失败: %w", err)
+	}
+
+	params := url.Values{}
+	params.Set("file_name", path.Base(remotePath))
+	params.Set("file_size", strconv.FormatInt(fileInfo.Size(), 10))
+	params.Set("target", "U_1_"+parentID)
+	params.Set("fileid", fileSHA1)
+	params.Set("preid", preSHA1)
+
+	upload, err := p.post("/open/upload/init", params)
+	if err != nil {
+		return fmt.Errorf("初始化上传失败: %w", err)
+	}
+
+	// 秒传需要二次校验时，按要求提交文件指定区间的 SHA1
+	if signKey := upload.Get("sign_key").String(); signKey != "" && upload.Get("sign_check").String() != "" {
+		signVal, err := pan115RangeSHA1(localPath, upload.Get("sign_check").String())
+		if err != nil {
+			return fmt.Errorf("计算校验值失败: %w", err)
+		}
+		params.Set("sign_key", signKey)
+		params.Set("sign_val", signVal)
+		if upload, err = p.post("/open/upload/init", params); err != nil {
+			return fmt.Errorf("初始化上传失败: %w", err)
+		}
+	}
+
+	if upload.Get("status").Int() == 2 {
+		log.Printf("[%s] 秒传成功: %s", p.Name(), remotePath)
+	} else if err := p.uploadToOSS(localPath, fileInfo.Size(), upload); err != nil {
+		return err
+	}
+
+	if old != nil {
+		if err := p.deleteByID(old.id); err != nil {
+			return fmt.Errorf("删除旧文件失败: %w", err)
+		}
+	}
+
+	log.Printf("[%s] 上传完成: %s", p.Name(), remotePath)
+	return nil
+}
+
+// uploadToOSS 使用上传凭证将文件写入 OSS，完成时 OSS 回调网盘登记文件
+func (p *Pan115Provider) uploadToOSS(localPath string, size int64, upload gjson.Result) error {
+	bucket := upload.Get("bucket").String()
+	object := upload.Get("object").String()
+	if bucket == "" || object == "" {
+		return fmt.Errorf("初始化上传失败: 未返回上传地址")
+	}
+	callback := &ossCallback{
+		Callback:    upload.Get("callback.callback").String(),
+		CallbackVar: upload.Get("callback.callback_var").String(),
+	}
+
+	token, err := p.get("/open/upload/get_token", nil)
+	if err != nil {
+		return fmt.Errorf("获取上传凭证失败: %w", err)
+	}
+	client := &ossClient{
+		creds: ossCredentials{
+			Endpoint:        token.Get("endpoint").String(),
+			AccessKeyID:     token.Get("AccessKeyId").String(),
+			AccessKeySecret: token.Get("AccessKeySecret").String(),
+			SecurityToken:   token.Get("SecurityToken").String(),
+		},
+		httpClient: p.httpClient,
+	}
+
+	var result []byte
+	if size > pan115MultipartThreshold {
+		result, err = p.uploadMultipart(client, bucket, object, localPath, size, callback)
+	} else {
+		var data []byte
+		if data, err = os.ReadFile(localPath); err == nil {
+			result, err = client.putObject(bucket, object, data, callback)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// 回调结果为网盘接口的响应格式
+	if _, err := pan115Data(result); err != nil {
+		return fmt.Errorf("登记文件失败: %w", err)
+	}
+	return nil
+}
+
+// uploadMultipart 分片上传，失败时取消
+func (p *Pan115Provider) uploadMultipart(client *ossClient, bucket, object, localPath string, size int64, callback *ossCallback) ([]byte, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	partSize := int64(pan115PartSize)
+	for size/partSize >= pan115MaxParts {
+		partSize *= 2
+	}
+
+	uploadID, err := client.initiateMultipart(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []ossPart
+	buf := make([]byte, partSize)
+	for offset := int64(0); offset < size; {
+		n, err := io.ReadFull(file, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			client.abortMultipart(bucket, object, uploadID)
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+
+		part, err := client.uploadPart(bucket, object, uploadID, len(parts)+1, buf[:n])
+		if err != nil {
+			client.abortMultipart(bucket, object, uploadID)
+			return nil, err
+		}
+		parts = append(parts, part)
+
+		offset += int64(n)
+		log.Printf("[%s] 上传进度: %d/%d 字节", p.Name(), offset, size)
+	}
+
+	result, err := client.completeMultipart(bucket, object, uploadID, parts, callback)
+	if err != nil {
+		client.abortMultipart(bucket, object, uploadID)
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteFile 删除文件或目录
+func (p *Pan115Provider) DeleteFile(remotePath string) error {
+	info, err := p.getInfo(remotePath)
+	if errors.Is(err, ErrNotFound) {
+		log.Printf("[%s] 文件不存在，跳过删除: %s", p.Name(), remotePath)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.deleteByID(info.id); err != nil {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+
+	log.Printf("[%s] 删除文件: %s", p.Name(), remotePath)
+	return nil
+}
+
+// deleteByID 根据 ID 删除文件或目录
+func (p *Pan115Provider) deleteByID(fileID string) error {
+	_, err := p.post("/open/ufile/delete", url.Values{"file_ids": {fileID}})
+	return err
+}
+
+// CreateDir 递归创建目录，目录已存在时直接返回
+func (p *Pan115Provider) CreateDir(remotePath string) error {
+	_, err := p.getOrCreateDir(remotePath)
+	return err
+}
+
+// getOrCreateDir 获取目录 ID，不存在时逐级创建，根目录 ID 为 0
+func (p *Pan115Provider) getOrCreateDir(remotePath string) (string, error) {
+	remotePath = cleanRemotePath(remotePath)
+	if remotePath == "/" {
+		return "0", nil
+	}
+
+	info, err := p.getInfo(remotePath)
+	if err == nil {
+		if !info.IsDir {
+			return "", fmt.Errorf("%s 已存在同名文件", remotePath)
+		}
+		return info.id, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	parentID, err := p.getOrCreateDir(path.Dir(remotePath))
+	if err != nil {
+		return "", err
+	}
+
+	folder, err := p.post("/open/folder/add", url.Values{
+		"pid":       {parentID},
+		"file_name": {path.Base(remotePath)},
+	})
+	if err != nil {
+		// 其他客户端可能同时创建了该目录
+		if info, statErr := p.getInfo(remotePath); statErr == nil && info.IsDir {
+			return info.id, nil
+		}
+		return "", fmt.Errorf("创建目录失败 %s: %w", remotePath, err)
+	}
+
+	log.Printf("[%s] 创建目录: %s", p.Name(), remotePath)
+	return folder.Get("file_id").String(), nil
+}
+
+// Stat 获取文件信息
+func (p *Pan115Provider) Stat(remotePath string) (*FileInfo, error) {
+	info, err := p.getInfo(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	return &info.FileInfo, nil
+}
+
+// List 列举目录，按 offset 分页
+func (p *Pan115Provider) List(remotePath string) ([]FileInfo, error) {
+	dir := cleanRemotePath(remotePath)
+	dirID, err := p.dirID(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileInfo
+	for offset := 0; ; {
+		query := url.Values{}
+		query.Set("cid", dirID)
+		query.Set("show_dir", "1")
+		query.Set("limit", strconv.Itoa(pan115PageSize))
+		query.Set("offset", strconv.Itoa(offset))
+
+		result, err := p.request("GET", "/open/ufile/files", query)
+		if err != nil {
+			return nil, err
+		}
+
+		items := result.Get("data").Array()
+		for _, item := range items {
+			name := item.Get("fn").String()
+			info := FileInfo{
+				Path:    path.Join(dir, name),
+				Name:    name,
+				Size:    item.Get("fs").Int(),
+				IsDir:   item.Get("fc").String() == "0",
+				ModTime: time.Unix(item.Get("upt").Int(), 0),
+				Hash:    item.Get("sha1").String(),
+			}
+			if info.IsDir {
+				info.Size = 0
+			}
+			files = append(files, info)
+		}
+
+		offset += len(items)
+		if len(items) == 0 || offset >= int(result.Get("count").Int()) {
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// DownloadFile 下载文件
+func (p *Pan115Provider) DownloadFile(remotePath, localPath string) error {
+	info, err := p.getInfo(remotePath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return fmt.Errorf("%s 是目录", remotePath)
+	}
+
+	data, err := p.post("/open/ufile/downurl", url.Values{"pick_code": {info.pickCode}})
+	if err != nil {
+		return fmt.Errorf("获取下载地址失败: %w", err)
+	}
+
+	// 返回结果以文件 ID 为键
+	var downloadURL string
+	data.ForEach(func(_, item gjson.Result) bool {
+		downloadURL = item.Get("url.url").String()
+		return downloadURL == ""
+	})
+	if downloadURL == "" {
+		return fmt.Errorf("获取下载地址失败: 未返回下载地址")
+	}
+
+	req, err := http.NewRequest("GET", downloadURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", pan115UserAgent)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载文件失败: %s", resp.Status)
+	}
+
+	log.Printf("[%s] 下载文件: %s -> %s", p.Name(), remotePath, localPath)
+	return writeLocalFile(localPath, resp.Body)
+}
+
+// MoveFile 移动文件或目录，目标已存在时覆盖
+func (p *Pan115Provider) MoveFile(oldPath, newPath string) error {
+	oldPath, newPath = cleanRemotePath(oldPath), cleanRemotePath(newPath)
+
+	info, err := p.getInfo(oldPath)
+	if err != nil {
+		return err
+	}
+
+	destID, err := p.getOrCreateDir(path.Dir(newPath))
+	if err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	// 网盘允许同名文件，先删除目标
+	if existing, err := p.getInfo(newPath); err == nil {
+		if err := p.deleteByID(existing.id); err != nil {
+			return fmt.Errorf("删除目标文件失败: %w", err)
+		}
+	}
+
+	if path.Dir(oldPath) != path.Dir(newPath) {
+		if _, err := p.post("/open/ufile/move", url.Values{"file_ids": {info.id}, "to_cid": {destID}}); err != nil {
+			return fmt.Errorf("移动文件失败: %w", err)
+		}
+	}
+	if path.Base(oldPath) != path.Base(newPath) {
+		if _, err := p.post("/open/ufile/update", url.Values{"file_id": {info.id}, "file_name": {path.Base(newPath)}}); err != nil {
+			return fmt.Errorf("重命名文件失败: %w", err)
+		}
+	}
+
+	log.Printf("[%s] 移动文件: %s -> %s", p.Name(), oldPath, newPath)
+	return nil
+}
+
+// Verify 校验令牌并获取账号和容量信息
+func (p *Pan115Provider) Verify() (*AccountInfo, error) {
+	user, err := p.get("/open/user/info", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+
+	return &AccountInfo{
+		UserName:   user.Get("user_name").String(),
+		UsedSpace:  user.Get("rt_space_info.all_use.size").Int(),
+		TotalSpace: user.Get("rt_space_info.all_total.size").Int(),
+	}, nil
+}
+
+// pan115Item 文件详情，附带操作需要的 ID 和提取码
+type pan115Item struct {
+	FileInfo
+	id       string
+	pickCode string
+}
+
+// getInfo 按路径获取文件或目录详情，不存在时返回 ErrNotFound
+func (p *Pan115Provider) getInfo(remotePath string) (*pan115Item, error) {
+	remotePath = cleanRemotePath(remotePath)
+	if remotePath == "/" {
+		return &pan115Item{FileInfo: FileInfo{Path: "/", Name: "/", IsDir: true}, id: "0"}, nil
+	}
+
+	data, err := p.get("/open/folder/get_info", url.Values{"path": {remotePath}})
+	if err != nil {
+		var apiErr *pan115Error
+		if errors.As(err, &apiErr) && apiErr.notFound() {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if data.Get("file_id").String() == "" {
+		return nil, ErrNotFound
+	}
+
+	item := &pan115Item{
+		FileInfo: FileInfo{
+			Path:    remotePath,
+			Name:    path.Base(remotePath),
+			Size:    data.Get("size_byte").Int(),
+			IsDir:   data.Get("file_category").String() == "0",
+			ModTime: time.Unix(data.Get("utime").Int(), 0),
+			Hash:    data.Get("sha1").String(),
+		},
+		id:       data.Get("file_id").String(),
+		pickCode: data.Get("pick_code").String(),
+	}
+	if item.IsDir {
+		item.Size = 0
+	}
+	return item, nil
+}
+
+// dirID 获取目录 ID，不存在或不是目录时返回 ErrNotFound
+func (p *Pan115Provider) dirID(remotePath string) (string, error) {
+	info, err := p.getInfo(remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir {
+		return "", ErrNotFound
+	}
+	return info.id, nil
+}
+
+// get 发送 GET 请求，返回 data 字段
+func (p *Pan115Provider) get(api string, query url.Values) (gjson.Result, error) {
+	result, err := p.request("GET", api, query)
+	return result.Get("data"), err
+}
+
+// post 发送表单 POST 请求，返回 data 字段
+func (p *Pan115Provider) post(api string, form url.Values) (gjson.Result, error) {
+	result, err := p.request("POST", api, form)
+	return result.Get("data"), err
+}
+
+// request 发送带授权的请求并检查 state，令牌过期时刷新后重试一次
+func (p *Pan115Provider) request(method, api string, params url.Values) (gjson.Result, error) {
+	for refreshed := false; ; refreshed = true {
+		p.mu.Lock()
+		token, canRefresh := p.accessToken, p.refreshToken != ""
+		p.mu.Unlock()
+
+		if token == "" {
+			if err := p.renewToken(token); err != nil {
+				return gjson.Result{}, err
+			}
+			continue
+		}
+
+		var req *http.Request
+		var err error
+		if method == "GET" {
+			u := p.baseURL + api
+			if len(params) > 0 {
+				u += "?" + params.Encode()
+			}
+			req, err = http.NewRequest(method, u, nil)
+		} else {
+			req, err = http.NewRequest(method, p.baseURL+api, strings.NewReader(params.Encode()))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("User-Agent", pan115UserAgent)
+
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return gjson.Result{}, err
+		}
+
+		result := gjson.ParseBytes(body)
+		if _, err = pan115Data(body); err != nil {
+			var apiErr *pan115Error
+			if !refreshed && canRefresh && errors.As(err, &apiErr) && apiErr.authFailed() {
+				if err := p.renewToken(token); err != nil {
+					return gjson.Result{}, err
+				}
+				continue
+			}
+			if resp.StatusCode != http.StatusOK && !errors.As(err, &apiErr) {
+				return gjson.Result{}, fmt.Errorf("%s", resp.Status)
+			}
+			return gjson.Result{}, err
+		}
+		return result, nil
+	}
+}
+
+// OnTokenRefresh 实现 TokenRefresher 接口。115 每次刷新都会轮换 refresh_token，
+// 新令牌需要写回配置，否则重启后使用的旧 refresh_token 已失效
+func (p *Pan115Provider) OnTokenRefresh(fn func(tokens map[string]string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onRefresh = fn
+}
+
+// renewToken 使用 refresh_token 换取新的令牌，其他请求已刷新（令牌不再是 stale）时直接返回。
+// 刷新后通过 onRefresh 通知新令牌
+func (p *Pan115Provider) renewToken(stale string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != stale {
+		return nil
+	}
+	if p.refreshToken == "" {
+		return fmt.Errorf("access_token 已失效且未配置 refresh_token")
+	}
+
+	req, err := http.NewRequest("POST", p.passportURL+"/open/refreshToken",
+		strings.NewReader(url.Values{"refresh_token": {p.refreshToken}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("刷新 access_token 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	data, err := pan115Data(body)
+	if err != nil {
+		return fmt.Errorf("刷新 access_token 失败: %w", err)
+	}
+	if data.Get("access_token").String() == "" {
+		return fmt.Errorf("刷新 access_token 失败: 未返回 access_token")
+	}
+
+	p.accessToken = data.Get("access_token").String()
+	if rt := data.Get("refresh_token").String(); rt != "" {
+		p.refreshToken = rt
+	}
+
+	log.Printf("[%s] 已刷新 access_token", p.Name())
+	if p.onRefresh != nil {
+		p.onRefresh(map[string]string{"access_token": p.accessToken, "refresh_token": p.refreshToken})
+	}
+	return nil
+}
+
+// pan115Error 开放平台接口返回的错误
+type pan115Error struct {
+	Code    int64
+	Message string
+}
+
+// Error 实现 error 接口
+func (e *pan115Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// authFailed 是否为 access_token 无效或过期（401 开头的错误码）
+func (e *pan115Error) authFailed() bool {
+	return e.Code/100000 == 401
+}
+
+// notFound 是否为文件或目录不存在
+func (e *pan115Error) notFound() bool {
+	return pan115NotFoundCodes[e.Code]
+}
+
+// pan115Data 检查响应的 state 字段，成功时返回 data
+func pan115Data(body []byte) (gjson.Result, error) {
+	result := gjson.ParseBytes(body)
+	if !result.Get("state").Exists() {
+		return gjson.Result{}, fmt.Errorf("无效的响应: %s", strings.TrimSpace(string(body)))
+	}
+	if result.Get("state").Bool() {
+		return result.Get("data"), nil
+	}
+
+	e := &pan115Error{Code: result.Get("code").Int(), Message: result.Get("message").String()}
+	if e.Code == 0 {
+		e.Code = result.Get("errno").Int()
+	}
+	if e.Message == "" {
+		e.Message = result.Get("error").String()
+	}
+	return gjson.Result{}, e
+}
+
+// pan115Hashes 计算文件的 SHA1 和前 128KB 的 SHA1（大写十六进制）
+func pan115Hashes(localPath string) (fileSHA1, preSHA1 string, err error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	full := sha1.New()
+	pre := sha1.New()
+	if _, err := io.Copy(io.MultiWriter(full, pre), io.LimitReader(file, pan115PreHashSize)); err != nil {
+		return "", "", err
+	}
+	if _, err := io.Copy(full, file); err != nil {
+		return "", "", err
+	}
+
+	return strings.ToUpper(hex.EncodeToString(full.Sum(nil))), strings.ToUpper(hex.EncodeToString(pre.Sum(nil))), nil
+}
+
+// pan115RangeSHA1 计算文件指定区间（如 "0-131071"，包含两端）的 SHA1，用于秒传二次校验
+func pan115RangeSHA1(localPath, signCheck string) (string, error) {
+	startStr, endStr, ok := strings.Cut(signCheck, "-")
+	start, err1 := strconv.ParseInt(startStr, 10, 64)
+	end, err2 := strconv.ParseInt(endStr, 10, 64)
+	if !ok || err1 != nil || err2 != nil || end < start {
+		return "", fmt.Errorf("无效的校验区间: %s", signCheck)
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(file, start, end-start+1)); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))), nil
+}
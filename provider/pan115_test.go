@@ -0,0 +1,102 @@
+package provider_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/provider"
+	"CloudFileSync/provider/providertest"
+)
+
+func TestPan115RapidUpload(t *testing.T) {
+	srv := providertest.NewPan115Server()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewPan115ProviderWithClient, srv.Server, srv.Tokens())
+	local := filepath.Join(t.TempDir(), "a.bin")
+	data := make([]byte, 300*1024)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	os.WriteFile(local, data, 0644)
+
+	if err := p.UploadFile(local, "/a/first.bin"); err != nil {
+		t.Fatal(err)
+	}
+	// 服务端已有相同内容，经二次校验后秒传，不再写入 OSS
+	if err := p.UploadFile(local, "/b/second.bin"); err != nil {
+		t.Fatalf("秒传失败: %v", err)
+	}
+	if n := srv.OSSUploads(); n != 1 {
+		t.Fatalf("写入 OSS %d 次，期望 1", n)
+	}
+
+	info, err := p.(provider.Stater).Stat("/b/second.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("Size = %d，期望 %d", info.Size, len(data))
+	}
+}
+
+func TestPan115RefreshToken(t *testing.T) {
+	srv := providertest.NewPan115Server()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewPan115ProviderWithClient, srv.Server, srv.Tokens())
+	srv.ExpireAccessToken()
+
+	if err := p.CreateDir("/after/refresh"); err != nil {
+		t.Fatalf("令牌过期后创建目录失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/after/refresh"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPan115RefreshTokenNotify(t *testing.T) {
+	srv := providertest.NewPan115Server()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewPan115ProviderWithClient, srv.Server, srv.Tokens())
+	var saved map[string]string
+	p.(provider.TokenRefresher).OnTokenRefresh(func(tokens map[string]string) {
+		saved = tokens
+	})
+
+	srv.ExpireAccessToken()
+	if _, err := p.(provider.Stater).Stat("/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CreateDir("/after/refresh"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换后的 refresh_token 需要写回配置，重启后才能继续使用
+	want := srv.Tokens()
+	if saved["refresh_token"] != want["refresh_token"] || saved["access_token"] != want["access_token"] {
+		t.Fatalf("通知的令牌为 %v，期望 %v", saved, want)
+	}
+}
+
+func TestPan115StatError(t *testing.T) {
+	srv := providertest.NewPan115Server()
+	defer srv.Close()
+
+	p := providertest.Connect(t, provider.NewPan115ProviderWithClient, srv.Server, srv.Tokens())
+	stater := p.(provider.Stater)
+
+	if _, err := stater.Stat("/missing.txt"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("Stat 不存在的文件返回 %v，期望 ErrNotFound", err)
+	}
+
+	// 限流等其他错误不能当作不存在，否则同步时会误以为远程文件已删除
+	srv.FailAPI("/open/folder/get_info", 990009, "访问过于频繁")
+	_, err := stater.Stat("/missing.txt")
+	if err == nil || errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("Stat 返回 %v，期望原始错误", err)
+	}
+}
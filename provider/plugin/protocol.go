@@ -0,0 +1,91 @@
+// Package plugin 定义 exec 提供商与外部插件进程之间的通信协议。
+//
+// 插件是一个独立的可执行文件，可以用任意语言编写。CloudFileSync 启动插件后，
+// 通过标准输入写入请求、从标准输出读取响应，每行一个 JSON 对象：
+//
+//	→ {"id":1,"method":"init","params":{"version":1,"tokens":{"root":"/data"}}}
+//	← {"id":1,"result":{"name":"我的存储","version":1}}
+//	→ {"id":2,"method":"stat","params":{"path":"/a.txt"}}
+//	← {"id":2,"error":{"code":"not_found","message":"文件不存在"}}
+//
+// 插件的标准错误输出会被转发到 CloudFileSync 的日志中，可用于调试。
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion 协议版本
+const ProtocolVersion = 1
+
+// 方法名
+const (
+	MethodInit   = "init"   // 初始化，参数 InitParams，结果 InitResult
+	MethodUpload = "upload" // 上传文件，参数 UploadParams
+	MethodDelete = "delete" // 删除文件或目录，参数 PathParams，不存在时应返回成功
+	MethodMkdir  = "mkdir"  // 递归创建目录，参数 PathParams
+	MethodList   = "list"   // 列举目录，参数 PathParams，结果 []FileInfo
+	MethodStat   = "stat"   // 获取文件信息，参数 PathParams，结果 FileInfo
+)
+
+// ErrCodeNotFound 文件不存在时的错误码
+const ErrCodeNotFound = "not_found"
+
+// Request 请求
+type Request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response 响应，Result 和 Error 只会有一个
+type Response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Error 插件返回的错误
+type Error struct {
+	Code    string `json:"code,omitempty"` // 错误码，如 not_found
+	Message string `json:"message"`        // 错误描述
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InitParams init 请求参数
+type InitParams struct {
+	Version int               `json:"version"` // 协议版本
+	Tokens  map[string]string `json:"tokens"`  // 云盘配置中的 tokens
+}
+
+// InitResult init 响应结果
+type InitResult struct {
+	Name    string `json:"name"`    // 插件名称，用于日志
+	Version int    `json:"version"` // 插件支持的协议版本
+}
+
+// UploadParams upload 请求参数
+type UploadParams struct {
+	LocalPath  string `json:"local_path"`  // 本地文件的绝对路径，插件直接读取
+	RemotePath string `json:"remote_path"` // 远程路径
+}
+
+// PathParams 只包含路径的请求参数
+type PathParams struct {
+	Path string `json:"path"`
+}
+
+// FileInfo 文件信息，字段与 provider.FileInfo 一致
+type FileInfo struct {
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"` // RFC 3339 格式
+	Hash    string    `json:"hash,omitempty"`
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNotFound Handler 返回此错误（或包装了此错误）时，响应错误码为 not_found
+var ErrNotFound = errors.New("文件不存在")
+
+// Handler 用 Go 编写插件时需要实现的接口
+type Handler interface {
+	// Init 使用云盘配置中的 tokens 初始化，返回插件名称
+	Init(tokens map[string]string) (string, error)
+	Upload(localPath, remotePath string) error
+	Delete(remotePath string) error
+	Mkdir(remotePath string) error
+	List(remotePath string) ([]FileInfo, error)
+	Stat(remotePath string) (*FileInfo, error)
+}
+
+// Main 在标准输入输出上运行插件，供插件的 main 函数调用
+func Main(h Handler) {
+	if err := Serve(h, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Serve 从 r 逐行读取请求，调用 Handler 处理后将响应写入 w，r 关闭时返回 nil
+func Serve(h Handler, r io.Reader, w io.Writer) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	encoder := json.NewEncoder(w)
+
+	for {
+		var req Request
+		if err := decoder.Decode(&req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("解析请求失败: %w", err)
+		}
+
+		result, err := dispatch(h, req)
+		resp := Response{ID: req.ID}
+		if err != nil {
+			resp.Error = &Error{Message: err.Error()}
+			if errors.Is(err, ErrNotFound) {
+				resp.Error.Code = ErrCodeNotFound
+			}
+		} else if result != nil {
+			resp.Result, err = json.Marshal(result)
+			if err != nil {
+				resp.Error = &Error{Message: err.Error()}
+			}
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			return fmt.Errorf("写入响应失败: %w", err)
+		}
+	}
+}
+
+// dispatch 根据方法名调用 Handler
+func dispatch(h Handler, req Request) (interface{}, error) {
+	switch req.Method {
+	case MethodInit:
+		var params InitParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		name, err := h.Init(params.Tokens)
+		if err != nil {
+			return nil, err
+		}
+		return InitResult{Name: name, Version: ProtocolVersion}, nil
+
+	case MethodUpload:
+		var params UploadParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, h.Upload(params.LocalPath, params.RemotePath)
+
+	case MethodDelete, MethodMkdir, MethodList, MethodStat:
+		var params PathParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		switch req.Method {
+		case MethodDelete:
+			return nil, h.Delete(params.Path)
+		case MethodMkdir:
+			return nil, h.Mkdir(params.Path)
+		case MethodList:
+			return h.List(params.Path)
+		default:
+			return h.Stat(params.Path)
+		}
+
+	default:
+		return nil, fmt.Errorf("不支持的方法: %s", req.Method)
+	}
+}
@@ -1,6 +1,12 @@
 package provider
 
-import "io"
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
 
 // Provider 云盘提供商接口
 type Provider interface {
@@ -35,3 +41,86 @@ type FileToUpload struct {
 	Size       int64
 	Reader     io.Reader
 }
+
+// FileInfo 远程文件信息
+type FileInfo struct {
+	Path    string    `json:"path"`     // 远程完整路径
+	Name    string    `json:"name"`     // 文件名
+	Size    int64     `json:"size"`     // 文件大小
+	IsDir   bool      `json:"is_dir"`   // 是否为目录
+	ModTime time.Time `json:"mod_time"` // 最后修改时间
+	Hash    string    `json:"hash"`     // 云盘提供的内容哈希（阿里云盘为 SHA1，百度为 MD5，WebDAV、S3 为 ETag）
+}
+
+// ErrNotFound 远程文件不存在
+var ErrNotFound = errors.New("远程文件不存在")
+
+// Stater 支持查询远程文件信息的提供商
+type Stater interface {
+	// Stat 获取远程文件信息，文件不存在时返回 ErrNotFound
+	Stat(remotePath string) (*FileInfo, error)
+}
+
+// Downloader 支持下载文件的提供商
+type Downloader interface {
+	// DownloadFile 下载远程文件到本地
+	DownloadFile(remotePath, localPath string) error
+}
+
+// Mover 支持移动（重命名）远程文件的提供商
+type Mover interface {
+	// MoveFile 移动远程文件或目录，目标父目录不存在时自动创建
+	MoveFile(oldPath, newPath string) error
+}
+
+// Lister 支持列举远程目录的提供商
+type Lister interface {
+	// List 列举远程目录下的文件，目录不存在时返回 ErrNotFound
+	List(remotePath string) ([]FileInfo, error)
+}
+
+// AccountInfo 云盘账号信息
+type AccountInfo struct {
+	UserName   string            `json:"user_name"`           // 账号名称
+	DriveIDs   map[string]string `json:"drive_ids,omitempty"` // 可用的网盘 ID（名称 -> ID）
+	UsedSpace  int64             `json:"used_space"`          // 已用空间（字节）
+	TotalSpace int64             `json:"total_space"`         // 总空间（字节）
+}
+
+// Verifier 支持校验凭证并查询账号信息的提供商
+type Verifier interface {
+	// Verify 调用云盘接口校验凭证，返回账号和容量信息
+	Verify() (*AccountInfo, error)
+}
+
+// Close 释放提供商持有的资源（插件进程、SSH 连接等）。需要释放资源的提供商实现 io.Closer，
+// 其他提供商无需关闭；关闭后不应再使用
+func Close(p Provider) error {
+	if closer, ok := p.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// writeLocalFile 将数据写入本地文件（先写临时文件再重命名，避免留下不完整文件）
+func writeLocalFile(localPath string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), ".cfs-download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), localPath)
+}
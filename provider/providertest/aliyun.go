@@ -0,0 +1,418 @@
+package providertest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// aliyunMaxLimit 列举接口单页最多返回的数量
+const aliyunMaxLimit = 100
+
+// AliYunServer 进程内模拟的阿里云盘开放平台接口，覆盖 AliYunProvider 使用的全部接口
+type AliYunServer struct {
+	*httptest.Server
+	AccessToken string
+	DriveID     string
+
+	mu      sync.Mutex
+	root    *fakeNode
+	nodes   map[string]*fakeNode
+	uploads map[string]*aliyunFakeUpload
+	calls   map[string]int
+	nextID  int
+}
+
+// aliyunFakeUpload 未完成的分片上传
+type aliyunFakeUpload struct {
+	file     *fakeNode
+	parentID string
+	parts    [][]byte
+}
+
+// NewAliYunServer 启动模拟服务，使用完毕后需调用 Close
+func NewAliYunServer() *AliYunServer {
+	s := &AliYunServer{
+		AccessToken: "test-access-token",
+		DriveID:     "10001",
+		root:        newFakeDir("root", ""),
+		uploads:     make(map[string]*aliyunFakeUpload),
+		calls:       make(map[string]int),
+	}
+	s.nodes = map[string]*fakeNode{"root": s.root}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Tokens 返回连接模拟服务所需的 tokens
+func (s *AliYunServer) Tokens() map[string]string {
+	return map[string]string{"access_token": s.AccessToken}
+}
+
+// Calls 返回接口被调用的次数，api 如 "/adrive/v1.0/openFile/list"
+func (s *AliYunServer) Calls(api string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[api]
+}
+
+// handle 分发请求
+func (s *AliYunServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[r.URL.Path]++
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/upload/") && r.Method == "PUT":
+		s.handleUploadPart(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, "/download/") && r.Method == "GET":
+		s.handleDownload(w, r)
+		return
+	case r.Method != "POST":
+		aliyunError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.AccessToken {
+		aliyunError(w, http.StatusUnauthorized, "AccessTokenInvalid", "AccessToken is invalid")
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	req := gjson.ParseBytes(body)
+
+	api := strings.TrimPrefix(r.URL.Path, "/adrive/v1.0")
+	if strings.HasPrefix(api, "/openFile/") && req.Get("drive_id").String() != s.DriveID {
+		aliyunError(w, http.StatusNotFound, "NotFound.Drive", "The resource drive cannot be found")
+		return
+	}
+
+	switch api {
+	case "/user/getDriveInfo":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"user_id":          "u1",
+			"name":             "测试用户",
+			"default_drive_id": s.DriveID,
+		})
+	case "/user/getSpaceInfo":
+		var used int64
+		s.root.walk(func(n *fakeNode) { used += int64(len(n.data)) })
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"personal_space_info": map[string]int64{"used_size": used, "total_size": 1 << 40},
+		})
+	case "/openFile/list":
+		s.handleList(w, req)
+	case "/openFile/get":
+		if n := s.nodes[req.Get("file_id").String()]; n != nil {
+			writeJSON(w, http.StatusOK, s.item(n))
+		} else {
+			aliyunNotFound(w)
+		}
+	case "/openFile/create":
+		s.handleCreate(w, req)
+	case "/openFile/complete":
+		s.handleComplete(w, req)
+	case "/openFile/delete":
+		s.handleDelete(w, req)
+	case "/openFile/move":
+		s.handleMove(w, req)
+	case "/openFile/getDownloadUrl":
+		n := s.nodes[req.Get("file_id").String()]
+		if n == nil || n.isDir {
+			aliyunNotFound(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"url":        s.URL + "/download/" + n.id,
+			"expiration": time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339),
+			"method":     "GET",
+		})
+	default:
+		aliyunError(w, http.StatusNotFound, "NotFound", "api not found: "+r.URL.Path)
+	}
+}
+
+// handleList 分页列举目录
+func (s *AliYunServer) handleList(w http.ResponseWriter, req gjson.Result) {
+	parent := s.nodes[req.Get("parent_file_id").String()]
+	if parent == nil || !parent.isDir {
+		aliyunNotFound(w)
+		return
+	}
+
+	limit := int(req.Get("limit").Int())
+	if limit == 0 {
+		limit = 50
+	}
+	if limit < 0 || limit > aliyunMaxLimit {
+		aliyunError(w, http.StatusBadRequest, "InvalidParameter.Limit", "limit must be between 1 and 100")
+		return
+	}
+
+	start := 0
+	if marker := req.Get("marker").String(); marker != "" {
+		start, _ = strconv.Atoi(marker)
+	}
+
+	children := parent.sortedChildren()
+	items := []map[string]interface{}{}
+	nextMarker := ""
+	for i := start; i < len(children); i++ {
+		if len(items) == limit {
+			nextMarker = strconv.Itoa(i)
+			break
+		}
+		items = append(items, s.item(children[i]))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "next_marker": nextMarker})
+}
+
+// handleCreate 创建文件夹或开始上传文件
+func (s *AliYunServer) handleCreate(w http.ResponseWriter, req gjson.Result) {
+	parent := s.nodes[req.Get("parent_file_id").String()]
+	if parent == nil || !parent.isDir {
+		aliyunNotFound(w)
+		return
+	}
+
+	name := req.Get("name").String()
+	if name == "" || strings.Contains(name, "/") {
+		aliyunError(w, http.StatusBadRequest, "InvalidParameter.Name", "invalid name: "+name)
+		return
+	}
+
+	mode := req.Get("check_name_mode").String()
+	if mode == "" {
+		mode = "ignore"
+	}
+	existing := parent.child(name)
+	switch mode {
+	case "ignore":
+	case "refuse":
+		if existing != nil {
+			item := s.item(existing)
+			item["exist"] = true
+			writeJSON(w, http.StatusOK, item)
+			return
+		}
+	case "auto_rename":
+		for i := 1; parent.child(name) != nil; i++ {
+			name = fmt.Sprintf("%s(%d)", req.Get("name").String(), i)
+		}
+	default:
+		aliyunError(w, http.StatusBadRequest, "InvalidParameter.CheckNameMode", "invalid check_name_mode: "+mode)
+		return
+	}
+
+	s.nextID++
+	node := &fakeNode{id: fmt.Sprintf("%040x", s.nextID), name: name, modTime: time.Now()}
+
+	switch req.Get("type").String() {
+	case "folder":
+		node.isDir = true
+		parent.attach(node)
+		s.nodes[node.id] = node
+		writeJSON(w, http.StatusOK, s.item(node))
+
+	case "file":
+		parts := req.Get("part_info_list").Array()
+		if len(parts) == 0 {
+			parts = []gjson.Result{gjson.Parse(`{"part_number":1}`)}
+		}
+
+		uploadID := fmt.Sprintf("upload-%d", s.nextID)
+		upload := &aliyunFakeUpload{file: node, parentID: parent.id, parts: make([][]byte, len(parts))}
+		s.uploads[uploadID] = upload
+
+		partInfoList := make([]map[string]interface{}, len(parts))
+		for i, part := range parts {
+			number := int(part.Get("part_number").Int())
+			if number != i+1 {
+				aliyunError(w, http.StatusBadRequest, "InvalidParameter.PartNumber", "part_number must be continuous")
+				return
+			}
+			partInfoList[i] = map[string]interface{}{
+				"part_number": number,
+				"upload_url":  fmt.Sprintf("%s/upload/%s/%d", s.URL, uploadID, number),
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"drive_id":       s.DriveID,
+			"file_id":        node.id,
+			"parent_file_id": parent.id,
+			"file_name":      name,
+			"upload_id":      uploadID,
+			"rapid_upload":   false,
+			"part_info_list": partInfoList,
+		})
+
+	default:
+		aliyunError(w, http.StatusBadRequest, "InvalidParameter.Type", "invalid type")
+	}
+}
+
+// handleUploadPart 接收分片数据
+func (s *AliYunServer) handleUploadPart(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/upload/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	upload := s.uploads[parts[0]]
+	number, _ := strconv.Atoi(parts[1])
+	if upload == nil || number < 1 || number > len(upload.parts) {
+		http.Error(w, "NoSuchUpload", http.StatusNotFound)
+		return
+	}
+
+	// 上传地址由 OSS 签名，带 Content-Type 会导致签名不匹配
+	if r.Header.Get("Content-Type") != "" {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	upload.parts[number-1] = data
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum(data)))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleComplete 合并分片，文件完成后才出现在目录中
+func (s *AliYunServer) handleComplete(w http.ResponseWriter, req gjson.Result) {
+	uploadID := req.Get("upload_id").String()
+	upload := s.uploads[uploadID]
+	if upload == nil || upload.file.id != req.Get("file_id").String() {
+		aliyunError(w, http.StatusNotFound, "NotFound.UploadId", "upload not found")
+		return
+	}
+
+	for i, part := range upload.parts {
+		if part == nil {
+			aliyunError(w, http.StatusBadRequest, "PartNotUploaded", fmt.Sprintf("part %d not uploaded", i+1))
+			return
+		}
+	}
+
+	parent := s.nodes[upload.parentID]
+	if parent == nil {
+		aliyunNotFound(w)
+		return
+	}
+
+	file := upload.file
+	file.data = bytes.Join(upload.parts, nil)
+	file.modTime = time.Now()
+	parent.attach(file)
+	s.nodes[file.id] = file
+	delete(s.uploads, uploadID)
+
+	writeJSON(w, http.StatusOK, s.item(file))
+}
+
+// handleDelete 删除文件或目录
+func (s *AliYunServer) handleDelete(w http.ResponseWriter, req gjson.Result) {
+	n := s.nodes[req.Get("file_id").String()]
+	if n == nil {
+		aliyunNotFound(w)
+		return
+	}
+	if n == s.root {
+		aliyunError(w, http.StatusForbidden, "Forbidden", "cannot delete root")
+		return
+	}
+
+	n.detach()
+	n.walk(func(c *fakeNode) { delete(s.nodes, c.id) })
+	writeJSON(w, http.StatusOK, map[string]string{"drive_id": s.DriveID, "file_id": n.id})
+}
+
+// handleMove 移动或重命名，目标已存在时不移动并返回 exist
+func (s *AliYunServer) handleMove(w http.ResponseWriter, req gjson.Result) {
+	n := s.nodes[req.Get("file_id").String()]
+	dest := s.nodes[req.Get("to_parent_file_id").String()]
+	if n == nil || dest == nil || !dest.isDir {
+		aliyunNotFound(w)
+		return
+	}
+	if n == s.root || n.contains(dest) {
+		aliyunError(w, http.StatusBadRequest, "InvalidParameter", "cannot move into itself")
+		return
+	}
+
+	name := req.Get("new_name").String()
+	if name == "" {
+		name = n.name
+	}
+	if existing := dest.child(name); existing != nil && existing != n {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"drive_id": s.DriveID, "file_id": existing.id, "exist": true})
+		return
+	}
+
+	n.detach()
+	n.name = name
+	dest.attach(n)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"drive_id": s.DriveID, "file_id": n.id, "exist": false})
+}
+
+// handleDownload 返回文件内容
+func (s *AliYunServer) handleDownload(w http.ResponseWriter, r *http.Request) {
+	n := s.nodes[strings.TrimPrefix(r.URL.Path, "/download/")]
+	if n == nil || n.isDir {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(n.data)))
+	w.Write(n.data)
+}
+
+// item 转换为接口返回的文件信息
+func (s *AliYunServer) item(n *fakeNode) map[string]interface{} {
+	item := map[string]interface{}{
+		"drive_id":   s.DriveID,
+		"file_id":    n.id,
+		"name":       n.name,
+		"type":       "folder",
+		"created_at": n.modTime.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"updated_at": n.modTime.UTC().Format("2006-01-02T15:04:05.000Z"),
+	}
+	if n.parent != nil {
+		item["parent_file_id"] = n.parent.id
+	}
+	if !n.isDir {
+		sum := sha1.Sum(n.data)
+		item["type"] = "file"
+		item["size"] = len(n.data)
+		item["content_hash"] = strings.ToUpper(hex.EncodeToString(sum[:]))
+		item["content_hash_name"] = "sha1"
+	}
+	return item
+}
+
+// aliyunError 写入错误响应
+func aliyunError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+// aliyunNotFound 文件不存在
+func aliyunNotFound(w http.ResponseWriter) {
+	aliyunError(w, http.StatusNotFound, "NotFound.File", "The resource file cannot be found. file not exist")
+}
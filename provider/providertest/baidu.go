@@ -0,0 +1,462 @@
+package providertest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// baiduBlockSize 普通用户分片大小上限
+const baiduBlockSize = 4 * 1024 * 1024
+
+// 百度网盘错误码
+const (
+	baiduErrAuth     = -6    // access_token 无效
+	baiduErrExists   = -8    // 文件或目录已存在
+	baiduErrNotFound = -9    // 文件或目录不存在
+	baiduErrParam    = 2     // 参数错误
+	baiduErrPartial  = 12    // 批量操作部分失败
+	baiduErrUpload   = 31363 // 分片缺失
+)
+
+// BaiduServer 进程内模拟的百度网盘 xpan 接口，覆盖 BaiduProvider 使用的全部接口
+type BaiduServer struct {
+	*httptest.Server
+	AccessToken string
+
+	mu      sync.Mutex
+	root    *fakeNode
+	nodes   map[string]*fakeNode // fs_id -> 节点
+	uploads map[string]*baiduFakeUpload
+	nextID  int64
+}
+
+// baiduFakeUpload 未完成的分片上传
+type baiduFakeUpload struct {
+	path   string
+	size   int64
+	blocks []string
+	parts  map[int][]byte
+}
+
+// NewBaiduServer 启动模拟服务，使用完毕后需调用 Close
+func NewBaiduServer() *BaiduServer {
+	s := &BaiduServer{
+		AccessToken: "test-access-token",
+		root:        newFakeDir("0", ""),
+		nodes:       make(map[string]*fakeNode),
+		uploads:     make(map[string]*baiduFakeUpload),
+		nextID:      100000,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Tokens 返回连接模拟服务所需的 tokens
+func (s *BaiduServer) Tokens() map[string]string {
+	return map[string]string{"access_token": s.AccessToken}
+}
+
+// handle 分发请求
+func (s *BaiduServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	if query.Get("access_token") != s.AccessToken {
+		baiduErrno(w, baiduErrAuth, "access token invalid")
+		return
+	}
+
+	method := query.Get("method")
+	switch {
+	case r.URL.Path == "/rest/2.0/xpan/file" && method == "meta":
+		n := s.root.lookup(query.Get("path"))
+		if n == nil || n == s.root {
+			baiduErrno(w, baiduErrNotFound, "file not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"errno": 0, "list": []interface{}{s.item(n)}})
+	case r.URL.Path == "/rest/2.0/xpan/file" && method == "list":
+		s.handleList(w, r)
+	case r.URL.Path == "/rest/2.0/xpan/file" && method == "precreate":
+		s.handlePrecreate(w, r)
+	case r.URL.Path == "/rest/2.0/xpan/file" && method == "create":
+		s.handleCreate(w, r)
+	case r.URL.Path == "/rest/2.0/xpan/file" && method == "filemanager":
+		s.handleFileManager(w, r)
+	case r.URL.Path == "/rest/2.0/pcs/superfile2" && method == "upload":
+		s.handleUploadBlock(w, r)
+	case r.URL.Path == "/rest/2.0/xpan/multimedia" && method == "filemetas":
+		s.handleFileMetas(w, r)
+	case r.URL.Path == "/rest/2.0/xpan/nas" && method == "uinfo":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"errno": 0, "baidu_name": "测试用户", "uk": 1, "vip_type": 0})
+	case r.URL.Path == "/api/quota":
+		var used int64
+		s.root.walk(func(n *fakeNode) { used += int64(len(n.data)) })
+		writeJSON(w, http.StatusOK, map[string]interface{}{"errno": 0, "total": int64(1 << 40), "used": used})
+	case strings.HasPrefix(r.URL.Path, "/file/"):
+		s.handleDownload(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleList 分页列举目录
+func (s *BaiduServer) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dir := s.root.lookup(query.Get("dir"))
+	if dir == nil || !dir.isDir {
+		baiduErrno(w, baiduErrNotFound, "dir not found")
+		return
+	}
+
+	start, _ := strconv.Atoi(query.Get("start"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	children := dir.sortedChildren()
+	list := []interface{}{}
+	for i := start; i < len(children) && len(list) < limit; i++ {
+		list = append(list, s.item(children[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errno": 0, "list": list})
+}
+
+// handlePrecreate 预上传，返回需要上传的全部分片
+func (s *BaiduServer) handlePrecreate(w http.ResponseWriter, r *http.Request) {
+	if !isForm(r) {
+		baiduErrno(w, baiduErrParam, "body must be form encoded")
+		return
+	}
+
+	remotePath := r.PostForm.Get("path")
+	size, err := strconv.ParseInt(r.PostForm.Get("size"), 10, 64)
+	if !strings.HasPrefix(remotePath, "/") || err != nil || r.PostForm.Get("autoinit") != "1" {
+		baiduErrno(w, baiduErrParam, "invalid param")
+		return
+	}
+
+	var blocks []string
+	if err := json.Unmarshal([]byte(r.PostForm.Get("block_list")), &blocks); err != nil || len(blocks) != blockCount(size) {
+		baiduErrno(w, baiduErrParam, "invalid block_list")
+		return
+	}
+
+	s.nextID++
+	uploadID := fmt.Sprintf("N1-%d", s.nextID)
+	s.uploads[uploadID] = &baiduFakeUpload{path: remotePath, size: size, blocks: blocks, parts: make(map[int][]byte)}
+
+	needed := make([]int, len(blocks))
+	for i := range needed {
+		needed[i] = i
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errno":       0,
+		"path":        remotePath,
+		"uploadid":    uploadID,
+		"return_type": 1,
+		"block_list":  needed,
+	})
+}
+
+// handleUploadBlock 接收分片
+func (s *BaiduServer) handleUploadBlock(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	upload := s.uploads[query.Get("uploadid")]
+	seq, err := strconv.Atoi(query.Get("partseq"))
+	if upload == nil || err != nil || seq < 0 || seq >= len(upload.blocks) || query.Get("type") != "tmpfile" || query.Get("path") != upload.path {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error_code": 31299, "error_msg": "invalid upload"})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error_code": 31299, "error_msg": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	data, _ := io.ReadAll(file)
+	if len(data) > baiduBlockSize {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error_code": 31299, "error_msg": "block too large"})
+		return
+	}
+
+	upload.parts[seq] = data
+	sum := md5.Sum(data)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"md5": hex.EncodeToString(sum[:]), "request_id": s.nextID})
+}
+
+// handleCreate 创建目录或合并分片创建文件
+func (s *BaiduServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if !isForm(r) {
+		baiduErrno(w, baiduErrParam, "body must be form encoded")
+		return
+	}
+
+	remotePath := r.PostForm.Get("path")
+	if !strings.HasPrefix(remotePath, "/") || path.Clean(remotePath) == "/" {
+		baiduErrno(w, baiduErrParam, "invalid path")
+		return
+	}
+	remotePath = path.Clean(remotePath)
+
+	existing := s.root.lookup(remotePath)
+	overwrite := r.PostForm.Get("rtype") == "3"
+
+	if r.PostForm.Get("isdir") == "1" {
+		if existing != nil {
+			baiduErrno(w, baiduErrExists, "file already exists")
+			return
+		}
+		n := s.add(remotePath, true, nil)
+		writeJSON(w, http.StatusOK, s.created(n))
+		return
+	}
+
+	upload := s.uploads[r.PostForm.Get("uploadid")]
+	if upload == nil || upload.path != remotePath {
+		baiduErrno(w, baiduErrParam, "invalid uploadid")
+		return
+	}
+
+	var blocks []string
+	json.Unmarshal([]byte(r.PostForm.Get("block_list")), &blocks)
+	size, _ := strconv.ParseInt(r.PostForm.Get("size"), 10, 64)
+	if size != upload.size || strings.Join(blocks, ",") != strings.Join(upload.blocks, ",") {
+		baiduErrno(w, baiduErrParam, "size or block_list mismatch")
+		return
+	}
+
+	var data [][]byte
+	for i, blockMD5 := range upload.blocks {
+		part, ok := upload.parts[i]
+		sum := md5.Sum(part)
+		if !ok || hex.EncodeToString(sum[:]) != blockMD5 {
+			baiduErrno(w, baiduErrUpload, fmt.Sprintf("block %d missing or md5 mismatch", i))
+			return
+		}
+		data = append(data, part)
+	}
+	content := bytes.Join(data, nil)
+	if int64(len(content)) != upload.size {
+		baiduErrno(w, baiduErrParam, "size mismatch")
+		return
+	}
+
+	if existing != nil {
+		if !overwrite || existing.isDir {
+			baiduErrno(w, baiduErrExists, "file already exists")
+			return
+		}
+		s.remove(existing)
+	}
+
+	n := s.add(remotePath, false, content)
+	delete(s.uploads, r.PostForm.Get("uploadid"))
+	writeJSON(w, http.StatusOK, s.created(n))
+}
+
+// handleFileManager 批量删除或移动
+func (s *BaiduServer) handleFileManager(w http.ResponseWriter, r *http.Request) {
+	if !isForm(r) {
+		baiduErrno(w, baiduErrParam, "body must be form encoded")
+		return
+	}
+
+	var info []map[string]interface{}
+	failed := false
+
+	switch r.URL.Query().Get("opera") {
+	case "delete":
+		var paths []string
+		if err := json.Unmarshal([]byte(r.PostForm.Get("filelist")), &paths); err != nil {
+			baiduErrno(w, baiduErrParam, "invalid filelist")
+			return
+		}
+		for _, p := range paths {
+			n := s.root.lookup(p)
+			if n == nil || n == s.root {
+				failed = true
+				info = append(info, map[string]interface{}{"errno": baiduErrNotFound, "path": p})
+				continue
+			}
+			s.remove(n)
+			info = append(info, map[string]interface{}{"errno": 0, "path": p})
+		}
+
+	case "move":
+		var items []struct {
+			Path    string `json:"path"`
+			Dest    string `json:"dest"`
+			NewName string `json:"newname"`
+			Ondup   string `json:"ondup"`
+		}
+		if err := json.Unmarshal([]byte(r.PostForm.Get("filelist")), &items); err != nil {
+			baiduErrno(w, baiduErrParam, "invalid filelist")
+			return
+		}
+		for _, item := range items {
+			n := s.root.lookup(item.Path)
+			dest := s.root.lookup(item.Dest)
+			switch {
+			case n == nil || n == s.root || dest == nil || !dest.isDir:
+				failed = true
+				info = append(info, map[string]interface{}{"errno": baiduErrNotFound, "path": item.Path})
+			case dest.child(item.NewName) != nil || n.contains(dest):
+				failed = true
+				info = append(info, map[string]interface{}{"errno": baiduErrExists, "path": item.Path})
+			default:
+				n.detach()
+				n.name = item.NewName
+				n.modTime = time.Now()
+				dest.attach(n)
+				info = append(info, map[string]interface{}{"errno": 0, "path": item.Path})
+			}
+		}
+
+	default:
+		baiduErrno(w, baiduErrParam, "invalid opera")
+		return
+	}
+
+	errno := 0
+	if failed {
+		errno = baiduErrPartial
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errno": errno, "info": info})
+}
+
+// handleFileMetas 查询文件信息和下载链接
+func (s *BaiduServer) handleFileMetas(w http.ResponseWriter, r *http.Request) {
+	var fsIDs []int64
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("fsids")), &fsIDs); err != nil {
+		baiduErrno(w, baiduErrParam, "invalid fsids")
+		return
+	}
+
+	list := []interface{}{}
+	for _, id := range fsIDs {
+		n := s.nodes[strconv.FormatInt(id, 10)]
+		if n == nil {
+			continue
+		}
+		item := s.item(n)
+		if r.URL.Query().Get("dlink") == "1" && !n.isDir {
+			item["dlink"] = fmt.Sprintf("%s/file/%s?fid=%s&sign=test", s.URL, n.id, n.id)
+		}
+		list = append(list, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errno": 0, "list": list})
+}
+
+// handleDownload 通过 dlink 下载，要求 User-Agent 为 pan.baidu.com
+func (s *BaiduServer) handleDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("User-Agent") != "pan.baidu.com" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	n := s.nodes[strings.TrimPrefix(r.URL.Path, "/file/")]
+	if n == nil || n.isDir {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(n.data)))
+	w.Write(n.data)
+}
+
+// add 在路径处创建节点，自动创建父目录
+func (s *BaiduServer) add(remotePath string, isDir bool, data []byte) *fakeNode {
+	parent := s.root
+	parts := strings.Split(strings.Trim(remotePath, "/"), "/")
+	for _, part := range parts[:len(parts)-1] {
+		child := parent.child(part)
+		if child == nil {
+			child = s.newNode(part, true, nil)
+			parent.attach(child)
+		}
+		parent = child
+	}
+
+	n := s.newNode(parts[len(parts)-1], isDir, data)
+	parent.attach(n)
+	return n
+}
+
+// newNode 创建节点并分配 fs_id
+func (s *BaiduServer) newNode(name string, isDir bool, data []byte) *fakeNode {
+	s.nextID++
+	n := &fakeNode{id: strconv.FormatInt(s.nextID, 10), name: name, isDir: isDir, data: data, modTime: time.Now()}
+	s.nodes[n.id] = n
+	return n
+}
+
+// remove 删除节点及其子孙节点
+func (s *BaiduServer) remove(n *fakeNode) {
+	n.detach()
+	n.walk(func(c *fakeNode) { delete(s.nodes, c.id) })
+}
+
+// item 转换为接口返回的文件信息
+func (s *BaiduServer) item(n *fakeNode) map[string]interface{} {
+	fsID, _ := strconv.ParseInt(n.id, 10, 64)
+	item := map[string]interface{}{
+		"fs_id":           fsID,
+		"path":            n.path(),
+		"server_filename": n.name,
+		"filename":        n.name,
+		"size":            len(n.data),
+		"isdir":           0,
+		"server_mtime":    n.modTime.Unix(),
+		"server_ctime":    n.modTime.Unix(),
+	}
+	if n.isDir {
+		item["isdir"] = 1
+	} else {
+		sum := md5.Sum(n.data)
+		item["md5"] = hex.EncodeToString(sum[:])
+	}
+	return item
+}
+
+// created create 接口的返回结果
+func (s *BaiduServer) created(n *fakeNode) map[string]interface{} {
+	item := s.item(n)
+	item["errno"] = 0
+	return item
+}
+
+// blockCount 文件大小对应的分片数，空文件为一个分片
+func blockCount(size int64) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + baiduBlockSize - 1) / baiduBlockSize)
+}
+
+// isForm 解析表单请求体，请求体不是表单时返回 false
+func isForm(r *http.Request) bool {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return false
+	}
+	return r.ParseForm() == nil
+}
+
+// baiduErrno 写入错误码响应
+func baiduErrno(w http.ResponseWriter, errno int, message string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errno": errno, "errmsg": message})
+}
@@ -0,0 +1,112 @@
+package providertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fakeNode 模拟云盘中的文件或目录
+type fakeNode struct {
+	id       string
+	name     string
+	isDir    bool
+	data     []byte
+	modTime  time.Time
+	parent   *fakeNode
+	children []*fakeNode
+}
+
+// newFakeDir 创建目录节点
+func newFakeDir(id, name string) *fakeNode {
+	return &fakeNode{id: id, name: name, isDir: true, modTime: time.Now()}
+}
+
+// path 节点的完整路径
+func (n *fakeNode) path() string {
+	if n.parent == nil {
+		return "/"
+	}
+	return path.Join(n.parent.path(), n.name)
+}
+
+// child 按名称查找子节点
+func (n *fakeNode) child(name string) *fakeNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// sortedChildren 按名称排序的子节点
+func (n *fakeNode) sortedChildren() []*fakeNode {
+	children := append([]*fakeNode(nil), n.children...)
+	sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+	return children
+}
+
+// attach 将节点加入目录
+func (n *fakeNode) attach(c *fakeNode) {
+	c.parent = n
+	n.children = append(n.children, c)
+	n.modTime = time.Now()
+}
+
+// detach 将节点从父目录中移除
+func (n *fakeNode) detach() {
+	if n.parent == nil {
+		return
+	}
+	siblings := n.parent.children
+	for i, c := range siblings {
+		if c == n {
+			n.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+	n.parent = nil
+}
+
+// contains 判断 c 是否为 n 自身或其子孙节点
+func (n *fakeNode) contains(c *fakeNode) bool {
+	for ; c != nil; c = c.parent {
+		if c == n {
+			return true
+		}
+	}
+	return false
+}
+
+// walk 遍历节点及其所有子孙节点
+func (n *fakeNode) walk(fn func(*fakeNode)) {
+	fn(n)
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+// lookup 按路径查找节点
+func (n *fakeNode) lookup(p string) *fakeNode {
+	current := n
+	for _, part := range strings.Split(strings.Trim(p, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		if current = current.child(part); current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,503 @@
+package providertest
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// oneDrivePageSize 列举接口单页最多返回的数量
+	oneDrivePageSize = 100
+	// oneDriveChunkUnit 上传会话的分片大小必须是 320 KiB 的整数倍（最后一片除外）
+	oneDriveChunkUnit = 320 * 1024
+)
+
+// OneDriveServer 进程内模拟的 Microsoft Graph 网盘接口和令牌接口，覆盖 OneDriveProvider 使用的全部接口
+type OneDriveServer struct {
+	*httptest.Server
+	ClientID     string
+	AccessToken  string
+	RefreshToken string
+
+	mu        sync.Mutex
+	root      *fakeNode
+	nodes     map[string]*fakeNode
+	sessions  map[string]*oneDriveFakeSession
+	refreshes int
+	nextID    int
+}
+
+// oneDriveFakeSession 未完成的上传会话
+type oneDriveFakeSession struct {
+	path string
+	size int64
+	data []byte
+}
+
+// NewOneDriveServer 启动模拟服务，使用完毕后需调用 Close
+func NewOneDriveServer() *OneDriveServer {
+	s := &OneDriveServer{
+		ClientID:     "test-client-id",
+		AccessToken:  "test-access-token",
+		RefreshToken: "test-refresh-token",
+		root:         newFakeDir("root", "root"),
+		sessions:     make(map[string]*oneDriveFakeSession),
+	}
+	s.nodes = map[string]*fakeNode{"root": s.root}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Tokens 返回连接模拟服务所需的 tokens
+func (s *OneDriveServer) Tokens() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]string{
+		"access_token":  s.AccessToken,
+		"refresh_token": s.RefreshToken,
+		"client_id":     s.ClientID,
+	}
+}
+
+// ExpireAccessToken 使当前的 access_token 失效，之后的请求返回 401，需要用 refresh_token 续期
+func (s *OneDriveServer) ExpireAccessToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	s.AccessToken = fmt.Sprintf("expired-%d", s.nextID)
+}
+
+// Refreshes 返回令牌被刷新的次数
+func (s *OneDriveServer) Refreshes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshes
+}
+
+// handle 分发请求
+func (s *OneDriveServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/oauth2/v2.0/token" && r.Method == "POST":
+		s.handleToken(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, "/upload/"):
+		s.handleUploadSession(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, "/download/") && r.Method == "GET":
+		s.handleDownload(w, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.AccessToken {
+		graphError(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "Access token has expired or is not yet valid.")
+		return
+	}
+
+	escaped := r.URL.EscapedPath()
+	switch {
+	case escaped == "/v1.0/me/drive" && r.Method == "GET":
+		s.handleDrive(w)
+	case strings.HasPrefix(escaped, "/v1.0/me/drive/root"):
+		itemPath, action, err := parseGraphPath(strings.TrimPrefix(escaped, "/v1.0/me/drive/root"))
+		if err != nil {
+			graphError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+			return
+		}
+		s.handleItem(w, r, itemPath, action)
+	default:
+		graphError(w, http.StatusNotFound, "itemNotFound", "api not found: "+r.URL.Path)
+	}
+}
+
+// parseGraphPath 解析 root 之后的寻址部分：""、"/children"、":/a/b.txt"、":/a/b.txt:/content"
+func parseGraphPath(rest string) (itemPath, action string, err error) {
+	switch {
+	case rest == "":
+	case strings.HasPrefix(rest, "/"):
+		action = rest[1:]
+	case strings.HasPrefix(rest, ":"):
+		rest = rest[1:]
+		if i := strings.Index(rest, ":"); i >= 0 {
+			rest, action = rest[:i], strings.TrimPrefix(rest[i+1:], "/")
+		}
+		if itemPath, err = url.PathUnescape(rest); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", fmt.Errorf("invalid path: %s", rest)
+	}
+	return path.Clean("/" + itemPath), action, nil
+}
+
+// handleToken 令牌接口，只支持 refresh_token 授权，每次刷新都会轮换 refresh_token
+func (s *OneDriveServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	if r.PostForm.Get("client_id") != s.ClientID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client", "error_description": "unknown client_id"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != s.RefreshToken {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "refresh token is invalid"})
+		return
+	}
+
+	s.refreshes++
+	s.AccessToken = fmt.Sprintf("access-%d", s.refreshes)
+	s.RefreshToken = fmt.Sprintf("refresh-%d", s.refreshes)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token_type":    "Bearer",
+		"access_token":  s.AccessToken,
+		"refresh_token": s.RefreshToken,
+		"expires_in":    3600,
+	})
+}
+
+// handleDrive 返回网盘信息
+func (s *OneDriveServer) handleDrive(w http.ResponseWriter) {
+	var used int64
+	s.root.walk(func(n *fakeNode) { used += int64(len(n.data)) })
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":        "drive1",
+		"driveType": "personal",
+		"owner":     map[string]interface{}{"user": map[string]string{"displayName": "测试用户"}},
+		"quota":     map[string]int64{"used": used, "total": 5 << 30},
+	})
+}
+
+// handleItem 处理按路径寻址的 driveItem 请求
+func (s *OneDriveServer) handleItem(w http.ResponseWriter, r *http.Request, itemPath, action string) {
+	n := s.root.lookup(itemPath)
+
+	switch {
+	case action == "" && r.Method == "GET":
+		if n == nil {
+			graphNotFound(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.item(n))
+
+	case action == "" && r.Method == "DELETE":
+		if n == nil {
+			graphNotFound(w)
+			return
+		}
+		if n == s.root {
+			graphError(w, http.StatusForbidden, "accessDenied", "cannot delete root")
+			return
+		}
+		n.detach()
+		n.walk(func(c *fakeNode) { delete(s.nodes, c.id) })
+		w.WriteHeader(http.StatusNoContent)
+
+	case action == "" && r.Method == "PATCH":
+		s.handleMove(w, r, n)
+
+	case action == "children" && r.Method == "GET":
+		s.handleList(w, r, itemPath, n)
+
+	case action == "children" && r.Method == "POST":
+		s.handleCreateFolder(w, r, n)
+
+	case action == "content" && r.Method == "GET":
+		if n == nil || n.isDir {
+			graphNotFound(w)
+			return
+		}
+		http.Redirect(w, r, s.URL+"/download/"+n.id, http.StatusFound)
+
+	case action == "content" && r.Method == "PUT":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			graphError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+			return
+		}
+		if len(data) > 4*1024*1024 {
+			graphError(w, http.StatusRequestEntityTooLarge, "invalidRequest", "use an upload session for files larger than 4MB")
+			return
+		}
+		file, status := s.putFile(itemPath, data)
+		if file == nil {
+			graphError(w, status, "nameAlreadyExists", "a folder with the same name exists")
+			return
+		}
+		writeJSON(w, status, s.item(file))
+
+	case action == "createUploadSession" && r.Method == "POST":
+		s.nextID++
+		id := fmt.Sprintf("session-%d", s.nextID)
+		s.sessions[id] = &oneDriveFakeSession{path: itemPath, size: -1}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"uploadUrl":          s.URL + "/upload/" + id,
+			"expirationDateTime": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		})
+
+	default:
+		graphError(w, http.StatusBadRequest, "invalidRequest", fmt.Sprintf("unsupported %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// handleList 分页列举目录，nextLink 为完整地址
+func (s *OneDriveServer) handleList(w http.ResponseWriter, r *http.Request, itemPath string, dir *fakeNode) {
+	if dir == nil || !dir.isDir {
+		graphNotFound(w)
+		return
+	}
+
+	top := oneDrivePageSize
+	if v, err := strconv.Atoi(r.URL.Query().Get("$top")); err == nil && v > 0 && v < top {
+		top = v
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("$skiptoken"))
+
+	children := dir.sortedChildren()
+	items := []map[string]interface{}{}
+	for i := start; i < len(children) && len(items) < top; i++ {
+		items = append(items, s.item(children[i]))
+	}
+
+	result := map[string]interface{}{"value": items}
+	if next := start + len(items); next < len(children) {
+		query := url.Values{}
+		query.Set("$top", strconv.Itoa(top))
+		query.Set("$skiptoken", strconv.Itoa(next))
+		result["@odata.nextLink"] = s.URL + r.URL.EscapedPath() + "?" + query.Encode()
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleCreateFolder 创建子目录，父目录必须存在
+func (s *OneDriveServer) handleCreateFolder(w http.ResponseWriter, r *http.Request, parent *fakeNode) {
+	if parent == nil || !parent.isDir {
+		graphNotFound(w)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	req := gjson.ParseBytes(body)
+	name := req.Get("name").String()
+	if name == "" || strings.Contains(name, "/") || !req.Get("folder").Exists() {
+		graphError(w, http.StatusBadRequest, "invalidRequest", "invalid folder: "+name)
+		return
+	}
+
+	if existing := parent.child(name); existing != nil {
+		if req.Get(`@microsoft\.graph\.conflictBehavior`).String() == "fail" {
+			graphError(w, http.StatusConflict, "nameAlreadyExists", "The specified item name already exists.")
+			return
+		}
+		existing.detach()
+	}
+
+	folder := s.newNode(name, true)
+	parent.attach(folder)
+	writeJSON(w, http.StatusCreated, s.item(folder))
+}
+
+// handleMove 移动或重命名，conflictBehavior=replace 时覆盖目标
+func (s *OneDriveServer) handleMove(w http.ResponseWriter, r *http.Request, n *fakeNode) {
+	if n == nil {
+		graphNotFound(w)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	req := gjson.ParseBytes(body)
+
+	dest := n.parent
+	if id := req.Get("parentReference.id").String(); id != "" {
+		dest = s.nodes[id]
+	}
+	if n == s.root || dest == nil || !dest.isDir || n.contains(dest) {
+		graphError(w, http.StatusBadRequest, "invalidRequest", "invalid destination")
+		return
+	}
+
+	name := req.Get("name").String()
+	if name == "" {
+		name = n.name
+	}
+	if existing := dest.child(name); existing != nil && existing != n {
+		if r.URL.Query().Get("@microsoft.graph.conflictBehavior") != "replace" {
+			graphError(w, http.StatusConflict, "nameAlreadyExists", "The specified item name already exists.")
+			return
+		}
+		existing.detach()
+	}
+
+	n.detach()
+	n.name = name
+	dest.attach(n)
+	writeJSON(w, http.StatusOK, s.item(n))
+}
+
+// handleUploadSession 接收上传会话的分片（PUT）或取消会话（DELETE），
+// 与真实接口一致，携带 Authorization 头时返回 401
+func (s *OneDriveServer) handleUploadSession(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/upload/")
+	session := s.sessions[id]
+	if session == nil {
+		graphError(w, http.StatusNotFound, "itemNotFound", "upload session not found")
+		return
+	}
+	if r.Header.Get("Authorization") != "" {
+		graphError(w, http.StatusUnauthorized, "unauthenticated", "upload URL must not be called with an Authorization header")
+		return
+	}
+
+	if r.Method == "DELETE" {
+		delete(s.sessions, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != "PUT" {
+		graphError(w, http.StatusMethodNotAllowed, "invalidRequest", "method not allowed")
+		return
+	}
+
+	var start, end, total int64
+	if _, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		graphError(w, http.StatusBadRequest, "invalidRange", "invalid Content-Range: "+r.Header.Get("Content-Range"))
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		graphError(w, http.StatusBadRequest, "invalidRequest", err.Error())
+		return
+	}
+
+	if session.size < 0 {
+		session.size = total
+	}
+	switch {
+	case total != session.size || start != int64(len(session.data)) || end-start+1 != int64(len(data)) || end >= total:
+		graphError(w, http.StatusRequestedRangeNotSatisfiable, "invalidRange", "unexpected Content-Range: "+r.Header.Get("Content-Range"))
+		return
+	case end+1 < total && len(data)%oneDriveChunkUnit != 0:
+		graphError(w, http.StatusBadRequest, "invalidRange", "fragment size must be a multiple of 320 KiB")
+		return
+	}
+	session.data = append(session.data, data...)
+
+	if int64(len(session.data)) < session.size {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"nextExpectedRanges": []string{fmt.Sprintf("%d-", len(session.data))},
+		})
+		return
+	}
+
+	delete(s.sessions, id)
+	file, status := s.putFile(session.path, session.data)
+	if file == nil {
+		graphError(w, status, "nameAlreadyExists", "a folder with the same name exists")
+		return
+	}
+	writeJSON(w, status, s.item(file))
+}
+
+// handleDownload 返回文件内容（content 接口重定向的目标）
+func (s *OneDriveServer) handleDownload(w http.ResponseWriter, r *http.Request) {
+	n := s.nodes[strings.TrimPrefix(r.URL.Path, "/download/")]
+	if n == nil || n.isDir {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(n.data)))
+	w.Write(n.data)
+}
+
+// putFile 写入文件，与真实接口一致自动创建缺失的父目录，同名文件直接替换。
+// 路径上存在同名文件夹时返回 nil
+func (s *OneDriveServer) putFile(itemPath string, data []byte) (*fakeNode, int) {
+	dir := s.root
+	for _, part := range strings.Split(strings.Trim(path.Dir(itemPath), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		next := dir.child(part)
+		if next == nil {
+			next = s.newNode(part, true)
+			dir.attach(next)
+		}
+		if !next.isDir {
+			return nil, http.StatusConflict
+		}
+		dir = next
+	}
+
+	name := path.Base(itemPath)
+	if existing := dir.child(name); existing != nil {
+		if existing.isDir {
+			return nil, http.StatusConflict
+		}
+		existing.data = data
+		existing.modTime = time.Now()
+		return existing, http.StatusOK
+	}
+
+	file := s.newNode(name, false)
+	file.data = data
+	dir.attach(file)
+	return file, http.StatusCreated
+}
+
+// newNode 创建节点并分配 ID
+func (s *OneDriveServer) newNode(name string, isDir bool) *fakeNode {
+	s.nextID++
+	n := &fakeNode{id: fmt.Sprintf("ITEM%d", s.nextID), name: name, isDir: isDir, modTime: time.Now()}
+	s.nodes[n.id] = n
+	return n
+}
+
+// item 转换为接口返回的 driveItem
+func (s *OneDriveServer) item(n *fakeNode) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":                   n.id,
+		"name":                 n.name,
+		"size":                 len(n.data),
+		"lastModifiedDateTime": n.modTime.UTC().Format(time.RFC3339),
+	}
+	if n.parent != nil {
+		item["parentReference"] = map[string]string{"id": n.parent.id, "path": "/drive/root:" + strings.TrimSuffix(n.parent.path(), "/")}
+	}
+	if n.isDir {
+		var size int64
+		n.walk(func(c *fakeNode) { size += int64(len(c.data)) })
+		item["size"] = size
+		item["folder"] = map[string]int{"childCount": len(n.children)}
+	} else {
+		item["file"] = map[string]interface{}{
+			"mimeType": "application/octet-stream",
+			"hashes":   map[string]string{"sha1Hash": fmt.Sprintf("%X", sha1.Sum(n.data))},
+		}
+	}
+	return item
+}
+
+// graphNotFound 返回文件不存在错误
+func graphNotFound(w http.ResponseWriter) {
+	graphError(w, http.StatusNotFound, "itemNotFound", "The resource could not be found.")
+}
+
+// graphError 返回 Graph 格式的错误
+func graphError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
@@ -0,0 +1,582 @@
+package providertest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// pan115Bucket 模拟的 OSS bucket
+	pan115Bucket = "fake-bucket"
+	// pan115MaxLimit 列举接口单页最多返回的数量
+	pan115MaxLimit = 1150
+)
+
+// Pan115Server 进程内模拟的 115 开放平台接口，包括刷新令牌、秒传（含二次校验）和带回调的 OSS 上传，
+// 覆盖 Pan115Provider 使用的全部接口
+type Pan115Server struct {
+	*httptest.Server
+	AccessToken  string
+	RefreshToken string
+
+	mu         sync.Mutex
+	root       *fakeNode
+	nodes      map[string]*fakeNode
+	pending    map[string]*pan115FakeUpload // OSS 对象名 -> 等待回调登记的文件
+	multiparts map[string]*pan115FakeMultipart
+	signs      map[string]string      // sign_key -> 期望的 sign_val
+	downloadUA string                 // 获取下载地址时的 User-Agent，下载时必须一致
+	faults     map[string]pan115Fault // 接口路径 -> 注入的错误，见 FailAPI
+	ossPuts    int
+	nextID     int
+}
+
+// pan115Fault 注入的接口错误
+type pan115Fault struct {
+	code    int
+	message string
+}
+
+// pan115FakeUpload 初始化上传后等待 OSS 回调的文件
+type pan115FakeUpload struct {
+	parent *fakeNode
+	name   string
+	sha1   string
+}
+
+// pan115FakeMultipart 未完成的 OSS 分片上传
+type pan115FakeMultipart struct {
+	object string
+	parts  map[int][]byte
+}
+
+// NewPan115Server 启动模拟服务，使用完毕后需调用 Close
+func NewPan115Server() *Pan115Server {
+	s := &Pan115Server{
+		AccessToken:  "test-access-token",
+		RefreshToken: "test-refresh-token",
+		root:         newFakeDir("0", ""),
+		pending:      make(map[string]*pan115FakeUpload),
+		multiparts:   make(map[string]*pan115FakeMultipart),
+		signs:        make(map[string]string),
+		faults:       make(map[string]pan115Fault),
+	}
+	s.nodes = map[string]*fakeNode{"0": s.root}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Tokens 返回连接模拟服务所需的 tokens
+func (s *Pan115Server) Tokens() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]string{"access_token": s.AccessToken, "refresh_token": s.RefreshToken}
+}
+
+// ExpireAccessToken 使当前的 access_token 失效，需要用 refresh_token 续期
+func (s *Pan115Server) ExpireAccessToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	s.AccessToken = fmt.Sprintf("expired-%d", s.nextID)
+}
+
+// FailAPI 让之后对接口 api（如 /open/folder/get_info）的请求都返回指定错误，code 为 0 时取消
+func (s *Pan115Server) FailAPI(api string, code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if code == 0 {
+		delete(s.faults, api)
+		return
+	}
+	s.faults[api] = pan115Fault{code: code, message: message}
+}
+
+// OSSUploads 返回写入 OSS 的文件数（秒传不计入）
+func (s *Pan115Server) OSSUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ossPuts
+}
+
+// handle 分发请求
+func (s *Pan115Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/open/refreshToken" && r.Method == "POST":
+		s.handleRefresh(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, "/"+pan115Bucket+"/"):
+		s.handleOSS(w, r, strings.TrimPrefix(r.URL.Path, "/"+pan115Bucket+"/"))
+		return
+	case strings.HasPrefix(r.URL.Path, "/download/") && r.Method == "GET":
+		s.handleDownload(w, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.AccessToken {
+		pan115Fail(w, 40140125, "access_token 无效")
+		return
+	}
+
+	if fault, ok := s.faults[r.URL.Path]; ok {
+		pan115Fail(w, fault.code, fault.message)
+		return
+	}
+
+	r.ParseForm()
+	switch r.Method + " " + r.URL.Path {
+	case "GET /open/user/info":
+		var used int64
+		s.root.walk(func(n *fakeNode) { used += int64(len(n.data)) })
+		pan115OK(w, map[string]interface{}{
+			"user_id":   1,
+			"user_name": "测试用户",
+			"rt_space_info": map[string]interface{}{
+				"all_total": map[string]int64{"size": 1 << 40},
+				"all_use":   map[string]int64{"size": used},
+			},
+		})
+	case "GET /open/folder/get_info":
+		if n := s.root.lookup(r.Form.Get("path")); n != nil && r.Form.Get("path") != "" {
+			pan115OK(w, s.info(n))
+		} else {
+			pan115Fail(w, 70005, "文件不存在")
+		}
+	case "GET /open/ufile/files":
+		s.handleList(w, r)
+	case "POST /open/folder/add":
+		s.handleMkdir(w, r)
+	case "POST /open/ufile/delete":
+		for _, id := range strings.Split(r.Form.Get("file_ids"), ",") {
+			if n := s.nodes[id]; n != nil && n != s.root {
+				n.detach()
+				n.walk(func(c *fakeNode) { delete(s.nodes, c.id) })
+			}
+		}
+		pan115OK(w, []interface{}{})
+	case "POST /open/ufile/move":
+		s.handleMove(w, r)
+	case "POST /open/ufile/update":
+		n := s.nodes[r.Form.Get("file_id")]
+		if n == nil || n == s.root || r.Form.Get("file_name") == "" {
+			pan115Fail(w, 70005, "文件不存在")
+			return
+		}
+		n.name = r.Form.Get("file_name")
+		pan115OK(w, map[string]string{"file_name": n.name})
+	case "POST /open/ufile/downurl":
+		s.handleDownURL(w, r)
+	case "POST /open/upload/init":
+		s.handleUploadInit(w, r)
+	case "GET /open/upload/get_token":
+		pan115OK(w, map[string]string{
+			"endpoint":        s.URL,
+			"AccessKeyId":     "STS.test",
+			"AccessKeySecret": "test-secret",
+			"SecurityToken":   "test-security-token",
+			"Expiration":      time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleRefresh 刷新令牌，每次刷新都会轮换 refresh_token
+func (s *Pan115Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.PostForm.Get("refresh_token") != s.RefreshToken {
+		pan115Fail(w, 40140116, "refresh_token 无效")
+		return
+	}
+
+	s.nextID++
+	s.AccessToken = fmt.Sprintf("access-%d", s.nextID)
+	s.RefreshToken = fmt.Sprintf("refresh-%d", s.nextID)
+	pan115OK(w, map[string]interface{}{
+		"access_token":  s.AccessToken,
+		"refresh_token": s.RefreshToken,
+		"expires_in":    7200,
+	})
+}
+
+// handleList 分页列举目录
+func (s *Pan115Server) handleList(w http.ResponseWriter, r *http.Request) {
+	dir := s.nodes[r.Form.Get("cid")]
+	if dir == nil || !dir.isDir {
+		pan115Fail(w, 70005, "目录不存在")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+	if limit <= 0 || limit > pan115MaxLimit {
+		limit = pan115MaxLimit
+	}
+	offset, _ := strconv.Atoi(r.Form.Get("offset"))
+
+	children := dir.sortedChildren()
+	items := []map[string]interface{}{}
+	for i := offset; i < len(children) && len(items) < limit; i++ {
+		n := children[i]
+		item := map[string]interface{}{
+			"fid": n.id,
+			"pid": dir.id,
+			"fn":  n.name,
+			"fs":  len(n.data),
+			"fc":  "1",
+			"upt": n.modTime.Unix(),
+			"pc":  "pc" + n.id,
+		}
+		if n.isDir {
+			item["fc"] = "0"
+		} else {
+			item["sha1"] = pan115SHA1(n.data)
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state": true, "code": 0, "message": "", "data": items,
+		"count": len(children), "offset": offset, "limit": limit,
+	})
+}
+
+// handleMkdir 创建目录，同名目录已存在时报错
+func (s *Pan115Server) handleMkdir(w http.ResponseWriter, r *http.Request) {
+	parent := s.nodes[r.Form.Get("pid")]
+	name := r.Form.Get("file_name")
+	if parent == nil || !parent.isDir {
+		pan115Fail(w, 70005, "父目录不存在")
+		return
+	}
+	if name == "" || strings.Contains(name, "/") {
+		pan115Fail(w, 20001, "目录名称不合法")
+		return
+	}
+	if existing := parent.child(name); existing != nil && existing.isDir {
+		pan115Fail(w, 20004, "该目录名称已存在")
+		return
+	}
+
+	dir := s.newNode(name, true)
+	parent.attach(dir)
+	pan115OK(w, map[string]string{"file_name": name, "file_id": dir.id})
+}
+
+// handleMove 移动文件到目标目录，允许同名
+func (s *Pan115Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	dest := s.nodes[r.Form.Get("to_cid")]
+	if dest == nil || !dest.isDir {
+		pan115Fail(w, 70005, "目标目录不存在")
+		return
+	}
+
+	for _, id := range strings.Split(r.Form.Get("file_ids"), ",") {
+		n := s.nodes[id]
+		if n == nil || n == s.root || n.contains(dest) {
+			pan115Fail(w, 70005, "文件不存在")
+			return
+		}
+		n.detach()
+		dest.attach(n)
+	}
+	pan115OK(w, []interface{}{})
+}
+
+// handleDownURL 返回下载地址，以文件 ID 为键
+func (s *Pan115Server) handleDownURL(w http.ResponseWriter, r *http.Request) {
+	var file *fakeNode
+	for _, n := range s.nodes {
+		if "pc"+n.id == r.Form.Get("pick_code") && !n.isDir {
+			file = n
+		}
+	}
+	if file == nil {
+		pan115Fail(w, 70005, "文件不存在")
+		return
+	}
+
+	s.downloadUA = r.UserAgent()
+	pan115OK(w, map[string]interface{}{
+		file.id: map[string]interface{}{
+			"file_name": file.name,
+			"file_size": len(file.data),
+			"pick_code": "pc" + file.id,
+			"sha1":      pan115SHA1(file.data),
+			"url":       map[string]string{"url": s.URL + "/download/" + file.id},
+		},
+	})
+}
+
+// handleDownload 返回文件内容，User-Agent 必须与获取下载地址时一致
+func (s *Pan115Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	n := s.nodes[strings.TrimPrefix(r.URL.Path, "/download/")]
+	if n == nil || n.isDir {
+		http.NotFound(w, r)
+		return
+	}
+	if r.UserAgent() != s.downloadUA {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(n.data)))
+	w.Write(n.data)
+}
+
+// handleUploadInit 初始化上传：已有相同 SHA1 的文件时要求二次校验后秒传，否则返回 OSS 上传参数
+func (s *Pan115Server) handleUploadInit(w http.ResponseWriter, r *http.Request) {
+	parent := s.nodes[strings.TrimPrefix(r.Form.Get("target"), "U_1_")]
+	name := r.Form.Get("file_name")
+	fileSHA1 := r.Form.Get("fileid")
+	size, _ := strconv.ParseInt(r.Form.Get("file_size"), 10, 64)
+	if parent == nil || !parent.isDir || !strings.HasPrefix(r.Form.Get("target"), "U_1_") {
+		pan115Fail(w, 70005, "目标目录不存在")
+		return
+	}
+	if name == "" || len(fileSHA1) != 40 || fileSHA1 != strings.ToUpper(fileSHA1) || r.Form.Get("preid") == "" {
+		pan115Fail(w, 10002, "参数错误")
+		return
+	}
+
+	// 秒传：服务端已有相同内容
+	var existing []byte
+	found := false
+	s.root.walk(func(n *fakeNode) {
+		if !found && !n.isDir && int64(len(n.data)) == size && pan115SHA1(n.data) == fileSHA1 {
+			existing, found = n.data, true
+		}
+	})
+	if found {
+		if key := r.Form.Get("sign_key"); key != "" || size == 0 {
+			if key != "" && s.signs[key] != r.Form.Get("sign_val") {
+				pan115Fail(w, 10003, "校验失败")
+				return
+			}
+			delete(s.signs, key)
+
+			file := s.newNode(name, false)
+			file.data = existing
+			parent.attach(file)
+			pan115OK(w, map[string]interface{}{"status": 2, "file_id": file.id, "pick_code": "pc" + file.id})
+			return
+		}
+
+		// 要求校验文件中间的一段，防止只凭 SHA1 秒传他人的文件
+		start := size / 3
+		end := start + 127
+		if end >= size {
+			end = size - 1
+		}
+		s.nextID++
+		key := fmt.Sprintf("sign-%d", s.nextID)
+		s.signs[key] = pan115SHA1(existing[start : end+1])
+		pan115OK(w, map[string]interface{}{
+			"status":     7,
+			"sign_key":   key,
+			"sign_check": fmt.Sprintf("%d-%d", start, end),
+		})
+		return
+	}
+
+	s.nextID++
+	object := fmt.Sprintf("tmp/%d/%s", s.nextID, fileSHA1)
+	s.pending[object] = &pan115FakeUpload{parent: parent, name: name, sha1: fileSHA1}
+
+	callback, _ := json.Marshal(map[string]string{
+		"callbackUrl":  s.URL + "/open/upload/callback",
+		"callbackBody": "bucket=${bucket}&object=${object}&sha1=${sha1}&cid=${x:cid}",
+	})
+	callbackVar, _ := json.Marshal(map[string]string{"x:cid": parent.id})
+	pan115OK(w, map[string]interface{}{
+		"status": 1,
+		"bucket": pan115Bucket,
+		"object": object,
+		"callback": map[string]string{
+			"callback":     string(callback),
+			"callback_var": string(callbackVar),
+		},
+	})
+}
+
+// handleOSS 模拟 OSS 简单上传和分片上传，上传完成时按回调登记文件
+func (s *Pan115Server) handleOSS(w http.ResponseWriter, r *http.Request, object string) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "OSS STS.test:") ||
+		r.Header.Get("X-Oss-Security-Token") != "test-security-token" || r.Header.Get("Date") == "" {
+		ossFail(w, http.StatusForbidden, "AccessDenied", "invalid signature or security token")
+		return
+	}
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == "PUT" && query.Get("uploadId") == "":
+		data, _ := io.ReadAll(r.Body)
+		s.ossPuts++
+		s.finishUpload(w, r, object, data)
+
+	case r.Method == "POST" && query.Has("uploads"):
+		s.nextID++
+		uploadID := fmt.Sprintf("%032X", s.nextID)
+		s.multiparts[uploadID] = &pan115FakeMultipart{object: object, parts: make(map[int][]byte)}
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`,
+			pan115Bucket, object, uploadID)
+
+	case r.Method == "PUT":
+		upload := s.multiparts[query.Get("uploadId")]
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if upload == nil || upload.object != object || number < 1 {
+			ossFail(w, http.StatusNotFound, "NoSuchUpload", "upload not found")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		upload.parts[number] = data
+		w.Header().Set("ETag", fmt.Sprintf(`"%X"`, sha1.Sum(data)))
+
+	case r.Method == "POST":
+		uploadID := query.Get("uploadId")
+		upload := s.multiparts[uploadID]
+		if upload == nil || upload.object != object {
+			ossFail(w, http.StatusNotFound, "NoSuchUpload", "upload not found")
+			return
+		}
+
+		var complete struct {
+			Parts []struct {
+				PartNumber int    `xml:"PartNumber"`
+				ETag       string `xml:"ETag"`
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil || len(complete.Parts) == 0 {
+			ossFail(w, http.StatusBadRequest, "MalformedXML", "invalid CompleteMultipartUpload")
+			return
+		}
+		if !sort.SliceIsSorted(complete.Parts, func(i, j int) bool { return complete.Parts[i].PartNumber < complete.Parts[j].PartNumber }) {
+			ossFail(w, http.StatusBadRequest, "InvalidPartOrder", "parts must be in ascending order")
+			return
+		}
+
+		var data []byte
+		for _, part := range complete.Parts {
+			chunk, ok := upload.parts[part.PartNumber]
+			if !ok || part.ETag != fmt.Sprintf(`"%X"`, sha1.Sum(chunk)) {
+				ossFail(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %d not uploaded", part.PartNumber))
+				return
+			}
+			data = append(data, chunk...)
+		}
+		delete(s.multiparts, uploadID)
+		s.ossPuts++
+		s.finishUpload(w, r, object, data)
+
+	case r.Method == "DELETE":
+		delete(s.multiparts, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		ossFail(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	}
+}
+
+// finishUpload 对象写入完成，模拟 OSS 回调网盘登记文件，并把回调结果返回给上传方
+func (s *Pan115Server) finishUpload(w http.ResponseWriter, r *http.Request, object string, data []byte) {
+	upload := s.pending[object]
+	if upload == nil {
+		ossFail(w, http.StatusForbidden, "AccessDenied", "object was not initialized by upload/init")
+		return
+	}
+
+	callback, err := base64.StdEncoding.DecodeString(r.Header.Get("X-Oss-Callback"))
+	if err != nil || !gjson.GetBytes(callback, "callbackUrl").Exists() {
+		ossFail(w, http.StatusBadRequest, "InvalidArgument", "missing x-oss-callback")
+		return
+	}
+	callbackVar, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-Oss-Callback-Var"))
+	if gjson.GetBytes(callbackVar, `x:cid`).String() != upload.parent.id {
+		ossFail(w, http.StatusBadRequest, "InvalidArgument", "invalid x-oss-callback-var")
+		return
+	}
+
+	// 回调时网盘会校验内容与初始化时的 SHA1 一致
+	if pan115SHA1(data) != upload.sha1 {
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		fmt.Fprint(w, `<Error><Code>CallbackFailed</Code><Message>sha1 mismatch</Message></Error>`)
+		return
+	}
+	delete(s.pending, object)
+
+	file := s.newNode(upload.name, false)
+	file.data = data
+	upload.parent.attach(file)
+	pan115OK(w, map[string]interface{}{"file_id": file.id, "pick_code": "pc" + file.id, "file_name": file.name})
+}
+
+// newNode 创建节点并分配 ID
+func (s *Pan115Server) newNode(name string, isDir bool) *fakeNode {
+	s.nextID++
+	n := &fakeNode{id: strconv.Itoa(1000 + s.nextID), name: name, isDir: isDir, modTime: time.Now()}
+	s.nodes[n.id] = n
+	return n
+}
+
+// info 转换为 get_info 返回的文件详情
+func (s *Pan115Server) info(n *fakeNode) map[string]interface{} {
+	info := map[string]interface{}{
+		"file_id":       n.id,
+		"file_name":     n.name,
+		"file_category": "1",
+		"size":          fmt.Sprintf("%dB", len(n.data)),
+		"size_byte":     len(n.data),
+		"pick_code":     "pc" + n.id,
+		"utime":         n.modTime.Unix(),
+		"ptime":         n.modTime.Unix(),
+	}
+	if n.isDir {
+		info["file_category"] = "0"
+	} else {
+		info["sha1"] = pan115SHA1(n.data)
+	}
+	return info
+}
+
+// pan115SHA1 计算大写十六进制 SHA1
+func pan115SHA1(data []byte) string {
+	return fmt.Sprintf("%X", sha1.Sum(data))
+}
+
+// pan115OK 返回成功响应
+func pan115OK(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"state": true, "code": 0, "message": "", "data": data})
+}
+
+// pan115Fail 返回错误响应，与真实接口一致 HTTP 状态码仍为 200
+func pan115Fail(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"state": false, "code": code, "message": message, "data": []interface{}{}})
+}
+
+// ossFail 返回 OSS 格式的错误
+func ossFail(w http.ResponseWriter, status int, code, message string) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, message)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,274 @@
+// Package providertest 提供所有云盘提供商共用的一致性测试。
+//
+// 新的提供商（包括第三方注册的提供商和 exec 插件）可在测试中调用 Run，
+// 验证上传、覆盖、删除、多级目录、非 ASCII 文件名等行为与其他提供商一致：
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.Run(t, func(t *testing.T) provider.Provider { ... }, providertest.Options{})
+//	}
+//
+// 包中还提供阿里云盘（NewAliYunServer）、百度网盘（NewBaiduServer）、OneDrive（NewOneDriveServer）、115 网盘（NewPan115Server）
+// 和 S3（NewS3Server）的进程内模拟服务，用 Connect 配合 NewAliYunProviderWithClient 等构造函数即可在不访问真实云盘的情况下测试。
+package providertest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"CloudFileSync/provider"
+)
+
+// Options 一致性测试选项
+type Options struct {
+	// Root 测试使用的远程根目录，默认为 /cfs-conformance
+	Root string
+	// LargeFileSize 大文件测试的文件大小，为 0 时跳过，用于覆盖分片上传等逻辑
+	LargeFileSize int64
+}
+
+// Run 对 newProvider 创建的提供商运行一致性测试
+func Run(t *testing.T, newProvider func(t *testing.T) provider.Provider, opts Options) {
+	if opts.Root == "" {
+		opts.Root = "/cfs-conformance"
+	}
+
+	p := newProvider(t)
+	c := &checker{p: p, root: opts.Root, dir: t.TempDir()}
+
+	t.Run("UploadAndStat", c.testUploadAndStat)
+	t.Run("Overwrite", c.testOverwrite)
+	t.Run("NestedDirs", c.testNestedDirs)
+	t.Run("Unicode", c.testUnicode)
+	t.Run("Delete", c.testDelete)
+	t.Run("DeleteDir", c.testDeleteDir)
+	t.Run("Move", c.testMove)
+	if opts.LargeFileSize > 0 {
+		t.Run("LargeFile", func(t *testing.T) { c.testLargeFile(t, opts.LargeFileSize) })
+	}
+}
+
+// Constructor 使用指定接口地址和 HTTP 客户端创建提供商的构造函数，如 provider.NewAliYunProviderWithClient
+type Constructor func(tokens map[string]string, baseURL string, httpClient *http.Client) (provider.Provider, error)
+
+// Connect 使用 tokens 创建连接模拟服务 srv 的提供商，创建失败时结束测试
+func Connect(t testing.TB, newProvider Constructor, srv *httptest.Server, tokens map[string]string) provider.Provider {
+	t.Helper()
+
+	p, err := newProvider(tokens, srv.URL, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// checker 一致性测试上下文
+type checker struct {
+	p    provider.Provider
+	root string
+	dir  string // 本地临时目录
+}
+
+// remote 生成测试用的远程路径
+func (c *checker) remote(parts ...string) string {
+	return path.Join(append([]string{c.root}, parts...)...)
+}
+
+// writeLocal 写入本地临时文件
+func (c *checker) writeLocal(t *testing.T, data []byte) string {
+	t.Helper()
+	name := filepath.Join(c.dir, "upload-"+randomName())
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatalf("写入本地文件失败: %v", err)
+	}
+	return name
+}
+
+// upload 上传内容到远程路径
+func (c *checker) upload(t *testing.T, remotePath string, data []byte) {
+	t.Helper()
+	if err := c.p.UploadFile(c.writeLocal(t, data), remotePath); err != nil {
+		t.Fatalf("上传 %s 失败: %v", remotePath, err)
+	}
+}
+
+// expectFile 检查远程文件存在且内容一致（提供商不支持查询时跳过对应检查）
+func (c *checker) expectFile(t *testing.T, remotePath string, data []byte) {
+	t.Helper()
+
+	if stater, ok := c.p.(provider.Stater); ok {
+		info, err := stater.Stat(remotePath)
+		if err != nil {
+			t.Fatalf("Stat %s 失败: %v", remotePath, err)
+		}
+		if info.IsDir {
+			t.Fatalf("Stat %s: 期望文件，实际为目录", remotePath)
+		}
+		if info.Size != int64(len(data)) {
+			t.Fatalf("Stat %s: 大小为 %d，期望 %d", remotePath, info.Size, len(data))
+		}
+	}
+
+	if downloader, ok := c.p.(provider.Downloader); ok {
+		localPath := filepath.Join(c.dir, "download-"+randomName())
+		if err := downloader.DownloadFile(remotePath, localPath); err != nil {
+			t.Fatalf("下载 %s 失败: %v", remotePath, err)
+		}
+		got, err := os.ReadFile(localPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("下载 %s: 内容不一致（%d 字节，期望 %d 字节）", remotePath, len(got), len(data))
+		}
+	}
+}
+
+// expectMissing 检查远程文件不存在
+func (c *checker) expectMissing(t *testing.T, remotePath string) {
+	t.Helper()
+
+	stater, ok := c.p.(provider.Stater)
+	if !ok {
+		return
+	}
+	if _, err := stater.Stat(remotePath); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("Stat %s: 期望 ErrNotFound，实际为 %v", remotePath, err)
+	}
+}
+
+// expectList 检查目录下的文件名
+func (c *checker) expectList(t *testing.T, remotePath string, names ...string) {
+	t.Helper()
+
+	lister, ok := c.p.(provider.Lister)
+	if !ok {
+		return
+	}
+	files, err := lister.List(remotePath)
+	if err != nil {
+		t.Fatalf("List %s 失败: %v", remotePath, err)
+	}
+
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Name)
+		if f.Path != path.Join(remotePath, f.Name) {
+			t.Errorf("List %s: %s 的路径为 %s", remotePath, f.Name, f.Path)
+		}
+	}
+	sort.Strings(got)
+	sort.Strings(names)
+	if len(got) != len(names) {
+		t.Fatalf("List %s: 得到 %v，期望 %v", remotePath, got, names)
+	}
+	for i := range got {
+		if got[i] != names[i] {
+			t.Fatalf("List %s: 得到 %v，期望 %v", remotePath, got, names)
+		}
+	}
+}
+
+func (c *checker) testUploadAndStat(t *testing.T) {
+	remotePath := c.remote("upload", "a.txt")
+	c.upload(t, remotePath, []byte("hello"))
+	c.expectFile(t, remotePath, []byte("hello"))
+}
+
+func (c *checker) testOverwrite(t *testing.T) {
+	remotePath := c.remote("overwrite", "a.txt")
+	c.upload(t, remotePath, []byte("first version"))
+	c.upload(t, remotePath, []byte("second"))
+	c.expectFile(t, remotePath, []byte("second"))
+	c.expectList(t, c.remote("overwrite"), "a.txt")
+}
+
+func (c *checker) testNestedDirs(t *testing.T) {
+	if err := c.p.CreateDir(c.remote("nested", "x", "y", "z")); err != nil {
+		t.Fatalf("创建多级目录失败: %v", err)
+	}
+	// 重复创建不应报错
+	if err := c.p.CreateDir(c.remote("nested", "x", "y", "z")); err != nil {
+		t.Fatalf("重复创建目录失败: %v", err)
+	}
+
+	// 父目录不存在时上传应自动创建
+	c.upload(t, c.remote("nested", "p", "q", "f.txt"), []byte("deep"))
+	c.expectFile(t, c.remote("nested", "p", "q", "f.txt"), []byte("deep"))
+	c.expectList(t, c.remote("nested", "p"), "q")
+}
+
+func (c *checker) testUnicode(t *testing.T) {
+	remotePath := c.remote("unicode", "中文 目录", "文件 (1) #&+.txt")
+	c.upload(t, remotePath, []byte("你好"))
+	c.expectFile(t, remotePath, []byte("你好"))
+	c.expectList(t, c.remote("unicode", "中文 目录"), "文件 (1) #&+.txt")
+}
+
+func (c *checker) testDelete(t *testing.T) {
+	remotePath := c.remote("delete", "a.txt")
+	c.upload(t, remotePath, []byte("bye"))
+	c.upload(t, c.remote("delete", "b.txt"), []byte("keep"))
+
+	if err := c.p.DeleteFile(remotePath); err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	c.expectMissing(t, remotePath)
+	c.expectList(t, c.remote("delete"), "b.txt")
+
+	// 删除不存在的文件不应报错
+	if err := c.p.DeleteFile(c.remote("delete", "missing.txt")); err != nil {
+		t.Fatalf("删除不存在的文件失败: %v", err)
+	}
+}
+
+func (c *checker) testDeleteDir(t *testing.T) {
+	c.upload(t, c.remote("deldir", "d", "a.txt"), []byte("a"))
+	c.upload(t, c.remote("deldir", "d", "sub", "b.txt"), []byte("b"))
+
+	if err := c.p.DeleteFile(c.remote("deldir", "d")); err != nil {
+		t.Fatalf("删除目录失败: %v", err)
+	}
+	c.expectMissing(t, c.remote("deldir", "d", "a.txt"))
+	c.expectMissing(t, c.remote("deldir", "d", "sub", "b.txt"))
+}
+
+func (c *checker) testMove(t *testing.T) {
+	mover, ok := c.p.(provider.Mover)
+	if !ok {
+		t.Skip("提供商不支持移动")
+	}
+
+	c.upload(t, c.remote("move", "a.txt"), []byte("moving"))
+	if err := mover.MoveFile(c.remote("move", "a.txt"), c.remote("move", "dest", "b.txt")); err != nil {
+		t.Fatalf("移动失败: %v", err)
+	}
+	c.expectMissing(t, c.remote("move", "a.txt"))
+	c.expectFile(t, c.remote("move", "dest", "b.txt"), []byte("moving"))
+}
+
+func (c *checker) testLargeFile(t *testing.T, size int64) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	remotePath := c.remote("large", "big.bin")
+	c.upload(t, remotePath, data)
+	c.expectFile(t, remotePath, data)
+}
+
+// randomName 生成随机文件名
+func randomName() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,351 @@
+package providertest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Server 进程内模拟的 S3 兼容对象存储（路径风格），覆盖 S3Provider 使用的全部接口
+type S3Server struct {
+	*httptest.Server
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	mu      sync.Mutex
+	objects map[string]*s3FakeObject
+	uploads map[string]*s3FakeUpload
+	nextID  int
+}
+
+// s3FakeObject 对象
+type s3FakeObject struct {
+	data     []byte
+	etag     string
+	meta     http.Header
+	modified time.Time
+}
+
+// s3FakeUpload 未完成的分片上传
+type s3FakeUpload struct {
+	key   string
+	meta  http.Header
+	parts map[int][]byte
+}
+
+// NewS3Server 启动模拟服务，使用完毕后需调用 Close
+func NewS3Server() *S3Server {
+	s := &S3Server{
+		Bucket:    "test-bucket",
+		AccessKey: "test-access-key",
+		SecretKey: "test-secret-key",
+		objects:   make(map[string]*s3FakeObject),
+		uploads:   make(map[string]*s3FakeUpload),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Tokens 返回连接模拟服务所需的 tokens
+func (s *S3Server) Tokens() map[string]string {
+	return map[string]string{
+		"endpoint":   s.URL,
+		"bucket":     s.Bucket,
+		"access_key": s.AccessKey,
+		"secret_key": s.SecretKey,
+		"path_style": "true",
+	}
+}
+
+// handle 分发请求
+func (s *S3Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/") {
+		s3FakeError(w, http.StatusForbidden, "InvalidAccessKeyId", "invalid access key")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.Bucket {
+		s3FakeError(w, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != "UNSIGNED-PAYLOAD" {
+		sum := sha256.Sum256(body)
+		if hash != hex.EncodeToString(sum[:]) {
+			s3FakeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash mismatch")
+			return
+		}
+	}
+
+	query := r.URL.Query()
+	switch {
+	case key == "" && r.Method == "GET":
+		s.handleList(w, query)
+	case key == "":
+		s3FakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	case r.Method == "POST" && query.Has("uploads"):
+		s.nextID++
+		uploadID := fmt.Sprintf("upload-%d", s.nextID)
+		s.uploads[uploadID] = &s3FakeUpload{key: key, meta: s3Meta(r.Header), parts: make(map[int][]byte)}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: s.Bucket, Key: key, UploadId: uploadID})
+	case r.Method == "PUT" && query.Has("uploadId"):
+		upload := s.uploads[query.Get("uploadId")]
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		if upload == nil || upload.key != key || partNumber < 1 {
+			s3FakeError(w, http.StatusNotFound, "NoSuchUpload", "upload not found")
+			return
+		}
+		upload.parts[partNumber] = body
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case r.Method == "POST" && query.Has("uploadId"):
+		s.handleComplete(w, key, query.Get("uploadId"), body)
+	case r.Method == "DELETE" && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT" && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.handleCopy(w, r, key)
+	case r.Method == "PUT":
+		if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+			sum := md5.Sum(body)
+			if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+				s3FakeError(w, http.StatusBadRequest, "BadDigest", "Content-MD5 mismatch")
+				return
+			}
+		}
+		obj := &s3FakeObject{data: body, etag: md5Hex(body), meta: s3Meta(r.Header), modified: time.Now()}
+		s.objects[key] = obj
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+	case r.Method == "GET" || r.Method == "HEAD":
+		obj := s.objects[key]
+		if obj == nil {
+			s3FakeError(w, http.StatusNotFound, "NoSuchKey", "key not found")
+			return
+		}
+		for k, v := range obj.meta {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == "GET" {
+			w.Write(obj.data)
+		}
+	case r.Method == "DELETE":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3FakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	}
+}
+
+// handleList 实现 ListObjectsV2
+func (s *S3Server) handleList(w http.ResponseWriter, query url.Values) {
+	if query.Get("list-type") != "2" {
+		s3FakeError(w, http.StatusNotImplemented, "NotImplemented", "only ListObjectsV2 is supported")
+		return
+	}
+
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	maxKeys := 1000
+	if v, err := strconv.Atoi(query.Get("max-keys")); err == nil && v >= 0 && v < maxKeys {
+		maxKeys = v
+	}
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) && key > query.Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+		StorageClass string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	var result struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Name                  string
+		Prefix                string
+		KeyCount              int
+		MaxKeys               int
+		IsTruncated           bool
+		Contents              []content
+		CommonPrefixes        []commonPrefix
+		NextContinuationToken string `xml:",omitempty"`
+	}
+	result.Name = s.Bucket
+	result.Prefix = prefix
+	result.MaxKeys = maxKeys
+
+	// 同一公共前缀下的键在排序后连续出现，续传标记记录最后处理的键
+	for i := 0; i < len(keys); i++ {
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[i-1]
+			break
+		}
+
+		rest := strings.TrimPrefix(keys[i], prefix)
+		if idx := strings.Index(rest, delimiter); delimiter != "" && idx >= 0 {
+			common := prefix + rest[:idx+len(delimiter)]
+			for i+1 < len(keys) && strings.HasPrefix(keys[i+1], common) {
+				i++
+			}
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: common})
+		} else {
+			obj := s.objects[keys[i]]
+			result.Contents = append(result.Contents, content{
+				Key:          keys[i],
+				LastModified: obj.modified.UTC().Format("2006-01-02T15:04:05.000Z"),
+				ETag:         `"` + obj.etag + `"`,
+				Size:         len(obj.data),
+				StorageClass: "STANDARD",
+			})
+		}
+		result.KeyCount++
+	}
+
+	writeXML(w, result)
+}
+
+// handleComplete 完成分片上传
+func (s *S3Server) handleComplete(w http.ResponseWriter, key, uploadID string, body []byte) {
+	upload := s.uploads[uploadID]
+	if upload == nil || upload.key != key {
+		s3FakeError(w, http.StatusNotFound, "NoSuchUpload", "upload not found")
+		return
+	}
+
+	var complete struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &complete); err != nil || len(complete.Parts) == 0 {
+		s3FakeError(w, http.StatusBadRequest, "MalformedXML", "invalid complete request")
+		return
+	}
+
+	var data, sums []byte
+	for i, part := range complete.Parts {
+		partData, ok := upload.parts[part.PartNumber]
+		if !ok || part.PartNumber != i+1 || strings.Trim(part.ETag, `"`) != md5Hex(partData) {
+			// 与 S3 一致，返回 200 但响应体为错误
+			writeXML(w, struct {
+				XMLName xml.Name `xml:"Error"`
+				Code    string
+				Message string
+			}{Code: "InvalidPart", Message: fmt.Sprintf("part %d is invalid", part.PartNumber)})
+			return
+		}
+		data = append(data, partData...)
+		sum := md5.Sum(partData)
+		sums = append(sums, sum[:]...)
+	}
+
+	etag := fmt.Sprintf("%s-%d", md5Hex(sums), len(complete.Parts))
+	s.objects[key] = &s3FakeObject{data: data, etag: etag, meta: upload.meta, modified: time.Now()}
+	delete(s.uploads, uploadID)
+
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{Bucket: s.Bucket, Key: key, ETag: `"` + etag + `"`})
+}
+
+// handleCopy 服务端复制对象
+func (s *S3Server) handleCopy(w http.ResponseWriter, r *http.Request, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		s3FakeError(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source")
+		return
+	}
+
+	bucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	src := s.objects[srcKey]
+	if bucket != s.Bucket || src == nil {
+		s3FakeError(w, http.StatusNotFound, "NoSuchKey", "source key not found")
+		return
+	}
+
+	obj := *src
+	obj.data = bytes.Clone(src.data)
+	obj.modified = time.Now()
+	s.objects[key] = &obj
+
+	writeXML(w, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		ETag         string
+		LastModified string
+	}{ETag: `"` + obj.etag + `"`, LastModified: obj.modified.UTC().Format("2006-01-02T15:04:05.000Z")})
+}
+
+// s3Meta 提取 X-Amz-Meta- 开头的元数据
+func s3Meta(header http.Header) http.Header {
+	meta := make(http.Header)
+	for k, v := range header {
+		if strings.HasPrefix(k, "X-Amz-Meta-") {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
+// md5Hex 计算 MD5 的十六进制字符串
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeXML 写入 XML 响应
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+// s3FakeError 写入 S3 错误响应
+func s3FakeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"CloudFileSync/config"
+)
+
+// Constructor 根据 Tokens 创建提供商
+type Constructor func(tokens map[string]string) (Provider, error)
+
+// Field Tokens 中的一个配置项，Web 界面据此生成表单
+type Field struct {
+	Key         string   `json:"key"`                   // Tokens 中的键
+	Label       string   `json:"label"`                 // 表单标签
+	Type        string   `json:"type"`                  // 输入类型: text、password、number、select、checkbox，默认 text
+	Required    bool     `json:"required,omitempty"`    // 是否必填
+	Placeholder string   `json:"placeholder,omitempty"` // 输入提示
+	Help        string   `json:"help,omitempty"`        // 帮助文字
+	Options     []Option `json:"options,omitempty"`     // select 类型的可选项
+}
+
+// Option select 字段的可选项
+type Option struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// Schema 提供商的配置描述
+type Schema struct {
+	DisplayName string   `json:"display_name"`     // 显示名称
+	Icon        string   `json:"icon,omitempty"`   // 图标（emoji）
+	Fields      []Field  `json:"fields"`           // Tokens 配置项
+	AnyOf       []string `json:"any_of,omitempty"` // 至少填写其中一项，如 access_token 或用于 OAuth 授权的 client_id
+
+	// ConfigFileOnly 只能在配置文件中添加或修改，Web 界面只能原样保留（如会在本机执行命令的 exec）
+	ConfigFileOnly bool `json:"config_file_only,omitempty"`
+}
+
+// Validate 检查 Tokens 是否包含必填项
+func (s Schema) Validate(tokens map[string]string) error {
+	if errs := s.fieldErrors(tokens); len(errs) > 0 {
+		return errors.New(errs[0].Message)
+	}
+	return nil
+}
+
+// fieldErrors 返回 Tokens 的字段级错误，字段路径相对于云盘配置（如 tokens.access_token）
+func (s Schema) fieldErrors(tokens map[string]string) config.ValidationErrors {
+	var errs config.ValidationErrors
+	for _, f := range s.Fields {
+		if f.Required && strings.TrimSpace(tokens[f.Key]) == "" {
+			errs.Add("tokens."+f.Key, fmt.Sprintf("缺少 %s", f.Key))
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		for _, key := range s.AnyOf {
+			if strings.TrimSpace(tokens[key]) != "" {
+				return errs
+			}
+		}
+		errs.Add("tokens", fmt.Sprintf("至少需要填写 %s 中的一项", strings.Join(s.AnyOf, "、")))
+	}
+
+	return errs
+}
+
+// registration 已注册的提供商
+type registration struct {
+	constructor Constructor
+	schema      Schema
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]registration)
+)
+
+// Register 注册提供商类型，通常在提供商包的 init 中调用，
+// 第三方包可通过空白导入（import _ "example.com/foo"）添加新的云盘类型。
+// 类型重复注册时 panic。
+func Register(providerType string, constructor Constructor, schema Schema) {
+	if providerType == "" {
+		panic("provider: 注册的类型为空")
+	}
+	if constructor == nil {
+		panic("provider: 类型 " + providerType + " 的构造函数为 nil")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[providerType]; exists {
+		panic("provider: 类型 " + providerType + " 重复注册")
+	}
+	registry[providerType] = registration{constructor: constructor, schema: schema}
+}
+
+// Types 返回已注册的提供商类型（按名称排序）
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Lookup 获取提供商类型的配置描述
+func Lookup(providerType string) (Schema, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	reg, ok := registry[providerType]
+	return reg.schema, ok
+}
+
+// ValidateConfig 按注册的配置描述检查云盘配置
+func ValidateConfig(providerCfg config.ProviderConfig) error {
+	schema, ok := Lookup(providerCfg.Type)
+	if !ok {
+		return fmt.Errorf("不支持的云盘类型: %s", providerCfg.Type)
+	}
+	return schema.Validate(providerCfg.Tokens)
+}
+
+func init() {
+	config.RegisterValidator(validateProviders)
+	config.RegisterSecretKeys(isSecretField)
+}
+
+// validateProviders 检查每个云盘配置的类型是否已注册、令牌引用能否解析、Tokens 是否完整
+func validateProviders(c *config.Config) config.ValidationErrors {
+	var errs config.ValidationErrors
+	for i, p := range c.Providers {
+		if p.Type == "" {
+			continue // 由 config.Validate 报告
+		}
+		schema, ok := Lookup(p.Type)
+		if !ok {
+			errs.Add(config.ProviderField(i, "type"), fmt.Sprintf("不支持的云盘类型: %s", p.Type))
+			continue
+		}
+
+		// 无法解析的引用只报告一次，检查必填项时按已填写处理
+		keys := make([]string, 0, len(p.Tokens))
+		for key := range p.Tokens {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		tokens := make(map[string]string, len(p.Tokens))
+		for _, key := range keys {
+			value := p.Tokens[key]
+			v, err := config.ResolveToken(value)
+			if err != nil {
+				errs.Add(config.ProviderField(i, "tokens."+key), err.Error())
+				v = value
+			}
+			tokens[key] = v
+		}
+		for _, fe := range schema.fieldErrors(tokens) {
+			errs.Add(config.ProviderField(i, fe.Field), fe.Message)
+		}
+	}
+	return errs
+}
+
+// isSecretField 配置描述中的密码字段视为敏感令牌
+func isSecretField(providerType, key string) bool {
+	schema, ok := Lookup(providerType)
+	if !ok {
+		return false
+	}
+	for _, f := range schema.Fields {
+		if f.Key == key {
+			return f.Type == "password"
+		}
+	}
+	return false
+}
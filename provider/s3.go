@@ -0,0 +1,755 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3MultipartThreshold 超过此大小的文件使用分片上传
+	s3MultipartThreshold = 64 * 1024 * 1024
+	// s3PartSize 默认分片大小
+	s3PartSize = 16 * 1024 * 1024
+	// s3MaxParts 单次分片上传的最大分片数
+	s3MaxParts = 10000
+	// s3MD5Meta 保存文件 MD5 的自定义元数据，分片上传的 ETag 不是 MD5，需要单独记录
+	s3MD5Meta = "X-Amz-Meta-Md5"
+	// s3EmptyHash 空请求体的 SHA256
+	s3EmptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Provider S3 兼容对象存储提供商（AWS S3、阿里云 OSS、腾讯云 COS、七牛、MinIO 等）
+type S3Provider struct {
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	prefix     string
+	accessKey  string
+	secretKey  string
+	pathStyle  bool // 使用 endpoint/bucket/key 形式的地址（MinIO 需要）
+	httpClient *http.Client
+}
+
+func init() {
+	Register("s3", NewS3Provider, Schema{
+		DisplayName: "S3 兼容对象存储",
+		Icon:        "🪣",
+		Fields: []Field{
+			{Key: "endpoint", Label: "Endpoint", Required: true, Placeholder: "https://oss-cn-hangzhou.aliyuncs.com 或 http://127.0.0.1:9000"},
+			{Key: "region", Label: "Region", Placeholder: "us-east-1"},
+			{Key: "bucket", Label: "Bucket", Required: true},
+			{Key: "prefix", Label: "键前缀", Placeholder: "可选，如 backup/"},
+			{Key: "access_key", Label: "Access Key", Required: true},
+			{Key: "secret_key", Label: "Secret Key", Type: "password", Required: true},
+			{Key: "path_style", Label: "使用路径风格地址（MinIO 需要勾选）", Type: "checkbox"},
+		},
+	})
+}
+
+// NewS3Provider 创建 S3 兼容对象存储提供商
+func NewS3Provider(tokens map[string]string) (Provider, error) {
+	for _, key := range []string{"endpoint", "bucket", "access_key", "secret_key"} {
+		if tokens[key] == "" {
+			return nil, fmt.Errorf("缺少 %s", key)
+		}
+	}
+
+	endpoint := tokens["endpoint"]
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 endpoint: %w", err)
+	}
+
+	region := tokens["region"]
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	pathStyle, _ := strconv.ParseBool(tokens["path_style"])
+
+	return &S3Provider{
+		endpoint:  u,
+		region:    region,
+		bucket:    tokens["bucket"],
+		prefix:    strings.Trim(tokens["prefix"], "/"),
+		accessKey: tokens["access_key"],
+		secretKey: tokens["secret_key"],
+		pathStyle: pathStyle,
+		httpClient: &http.Client{
+			Timeout: 0, // 分片上传耗时不确定，不设置整体超时
+		},
+	}, nil
+}
+
+// Name 返回提供商名称
+func (s *S3Provider) Name() string {
+	return "S3"
+}
+
+// UploadFile 上传文件，MD5 与云端一致时跳过
+func (s *S3Provider) UploadFile(localPath, remotePath string) error {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	if fileInfo.IsDir() {
+		return s.CreateDir(remotePath)
+	}
+
+	fileMD5, err := s.calculateMD5(localPath)
+	if err != nil {
+		return fmt.Errorf("计算文件 MD5 失败: %w", err)
+	}
+
+	key := s.key(remotePath)
+	if remoteMD5, err := s.remoteMD5(key); err == nil && remoteMD5 == fileMD5 {
+		log.Printf("[%s] 文件未变化，跳过上传: %s", s.Name(), remotePath)
+		return nil
+	}
+
+	log.Printf("[%s] 上传文件: %s -> %s", s.Name(), localPath, remotePath)
+
+	if fileInfo.Size() > s3MultipartThreshold {
+		err = s.uploadMultipart(localPath, key, fileMD5, fileInfo.Size())
+	} else {
+		err = s.putObject(localPath, key, fileMD5)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%s] 上传完成: %s", s.Name(), remotePath)
+	return nil
+}
+
+// DeleteFile 删除对象，路径为目录时删除该前缀下的所有对象
+func (s *S3Provider) DeleteFile(remotePath string) error {
+	if cleanRemotePath(remotePath) == "/" {
+		return fmt.Errorf("不能删除根目录")
+	}
+	key := s.key(remotePath)
+
+	objects, _, err := s.listObjects(s.dirPrefix(key), "")
+	if err != nil {
+		return err
+	}
+	objects = append(objects, s3Object{Key: key})
+
+	for _, obj := range objects {
+		resp, err := s.do("DELETE", obj.Key, nil, nil, nil, 0, "")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		// S3 删除不存在的对象同样返回 204
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("删除文件失败 %s: %s", obj.Key, resp.Status)
+		}
+	}
+
+	log.Printf("[%s] 删除文件: %s", s.Name(), remotePath)
+	return nil
+}
+
+// CreateDir 对象存储中目录只是键前缀，无需创建
+func (s *S3Provider) CreateDir(remotePath string) error {
+	return nil
+}
+
+// Stat 获取对象信息，对象不存在但有同名前缀时视为目录
+func (s *S3Provider) Stat(remotePath string) (*FileInfo, error) {
+	key := s.key(remotePath)
+	remote := cleanRemotePath(remotePath)
+
+	if key != "" {
+		resp, err := s.do("HEAD", key, nil, nil, nil, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+			return &FileInfo{
+				Path:    remote,
+				Name:    path.Base(remote),
+				Size:    resp.ContentLength,
+				ModTime: modTime,
+				Hash:    strings.Trim(resp.Header.Get("ETag"), `"`),
+			}, nil
+		}
+		if resp.StatusCode != http.StatusNotFound {
+			return nil, fmt.Errorf("获取文件信息失败: %s", resp.Status)
+		}
+	}
+
+	// 检查是否存在该前缀下的对象
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", s.dirPrefix(key))
+	query.Set("max-keys", "1")
+	var result s3ListResult
+	if err := s.getXML("", query, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Contents) == 0 && len(result.CommonPrefixes) == 0 && key != "" {
+		return nil, ErrNotFound
+	}
+
+	return &FileInfo{Path: remote, Name: path.Base(remote), IsDir: true}, nil
+}
+
+// List 列举目录
+func (s *S3Provider) List(remotePath string) ([]FileInfo, error) {
+	prefix := s.dirPrefix(s.key(remotePath))
+	objects, dirs, err := s.listObjects(prefix, "/")
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cleanRemotePath(remotePath)
+	files := make([]FileInfo, 0, len(objects)+len(dirs))
+	for _, d := range dirs {
+		name := strings.TrimSuffix(strings.TrimPrefix(d, prefix), "/")
+		files = append(files, FileInfo{Path: path.Join(dir, name), Name: name, IsDir: true})
+	}
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.Key, prefix)
+		if name == "" {
+			continue
+		}
+		files = append(files, FileInfo{
+			Path:    path.Join(dir, name),
+			Name:    name,
+			Size:    obj.Size,
+			ModTime: obj.LastModified,
+			Hash:    strings.Trim(obj.ETag, `"`),
+		})
+	}
+
+	if len(files) == 0 && dir != "/" {
+		if _, err := s.Stat(remotePath); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// DownloadFile 下载文件
+func (s *S3Provider) DownloadFile(remotePath, localPath string) error {
+	resp, err := s.do("GET", s.key(remotePath), nil, nil, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, "下载文件失败")
+	}
+
+	log.Printf("[%s] 下载文件: %s -> %s", s.Name(), remotePath, localPath)
+	return writeLocalFile(localPath, resp.Body)
+}
+
+// MoveFile 移动文件，S3 不支持重命名，通过复制后删除实现
+func (s *S3Provider) MoveFile(oldPath, newPath string) error {
+	oldKey := s.key(oldPath)
+	newKey := s.key(newPath)
+
+	info, err := s.Stat(oldPath)
+	if err != nil {
+		return err
+	}
+
+	// 目录需要逐个移动前缀下的对象
+	moves := map[string]string{oldKey: newKey}
+	if info.IsDir {
+		moves = make(map[string]string)
+		objects, _, err := s.listObjects(s.dirPrefix(oldKey), "")
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			moves[obj.Key] = s.dirPrefix(newKey) + strings.TrimPrefix(obj.Key, s.dirPrefix(oldKey))
+		}
+	}
+
+	for src, dest := range moves {
+		if err := s.copyObject(src, dest); err != nil {
+			return err
+		}
+		resp, err := s.do("DELETE", src, nil, nil, nil, 0, "")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	log.Printf("[%s] 移动文件: %s -> %s", s.Name(), oldPath, newPath)
+	return nil
+}
+
+// Verify 校验密钥和存储桶是否可访问
+func (s *S3Provider) Verify() (*AccountInfo, error) {
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("max-keys", "1")
+	var result s3ListResult
+	if err := s.getXML("", query, &result); err != nil {
+		return nil, err
+	}
+
+	return &AccountInfo{UserName: s.bucket}, nil
+}
+
+// calculateMD5 计算文件 MD5
+func (s *S3Provider) calculateMD5(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// remoteMD5 获取云端对象的 MD5，优先使用上传时记录的元数据，其次使用单次上传的 ETag
+func (s *S3Provider) remoteMD5(key string) (string, error) {
+	resp, err := s.do("HEAD", key, nil, nil, nil, 0, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("获取文件信息失败: %s", resp.Status)
+	}
+
+	if md5Value := resp.Header.Get(s3MD5Meta); md5Value != "" {
+		return strings.ToLower(md5Value), nil
+	}
+
+	// 分片上传的 ETag 形如 xxx-3，不是 MD5
+	etag := strings.Trim(resp.Header.Get("ETag"), `"`)
+	if strings.Contains(etag, "-") {
+		return "", nil
+	}
+	return strings.ToLower(etag), nil
+}
+
+// putObject 单次上传
+func (s *S3Provider) putObject(localPath, key, fileMD5 string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	md5Bytes, _ := hex.DecodeString(fileMD5)
+	headers := map[string]string{
+		"Content-MD5": base64.StdEncoding.EncodeToString(md5Bytes),
+		s3MD5Meta:     fileMD5,
+	}
+
+	resp, err := s.do("PUT", key, nil, headers, bytes.NewReader(data), int64(len(data)), sha256Hex(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, "上传失败")
+	}
+	return nil
+}
+
+// uploadMultipart 分片上传
+func (s *S3Provider) uploadMultipart(localPath, key, fileMD5 string, fileSize int64) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	// 超过最大分片数时增大分片
+	partSize := int64(s3PartSize)
+	for fileSize/partSize >= s3MaxParts {
+		partSize *= 2
+	}
+
+	// 初始化分片上传
+	query := url.Values{}
+	query.Set("uploads", "")
+	resp, err := s.do("POST", key, query, map[string]string{s3MD5Meta: fileMD5}, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	var initResult struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = decodeS3XML(resp, "初始化分片上传失败", &initResult)
+	if err != nil {
+		return err
+	}
+
+	uploadID := initResult.UploadID
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+
+	buf := make([]byte, partSize)
+	for partNumber := 1; ; partNumber++ {
+		n, readErr := io.ReadFull(file, buf)
+		if n == 0 {
+			break
+		}
+		data := buf[:n]
+
+		partQuery := url.Values{}
+		partQuery.Set("partNumber", strconv.Itoa(partNumber))
+		partQuery.Set("uploadId", uploadID)
+		resp, err := s.do("PUT", key, partQuery, nil, bytes.NewReader(data), int64(n), sha256Hex(data))
+		if err != nil {
+			s.abortMultipart(key, uploadID)
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			s.abortMultipart(key, uploadID)
+			return fmt.Errorf("上传分片 %d 失败: %s", partNumber, resp.Status)
+		}
+
+		parts = append(parts, completedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+		log.Printf("[%s] 上传进度: %d/%d 字节", s.Name(), min(int64(partNumber)*partSize, fileSize), fileSize)
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			s.abortMultipart(key, uploadID)
+			return fmt.Errorf("读取文件失败: %w", readErr)
+		}
+	}
+
+	// 完成分片上传
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+
+	completeQuery := url.Values{}
+	completeQuery.Set("uploadId", uploadID)
+	resp, err = s.do("POST", key, completeQuery, nil, bytes.NewReader(body), int64(len(body)), sha256Hex(body))
+	if err != nil {
+		s.abortMultipart(key, uploadID)
+		return err
+	}
+
+	// 完成接口可能返回 200 但响应体中包含错误
+	var completeResult struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := decodeS3XML(resp, "完成分片上传失败", &completeResult); err != nil {
+		s.abortMultipart(key, uploadID)
+		return err
+	}
+	if completeResult.XMLName.Local == "Error" {
+		s.abortMultipart(key, uploadID)
+		return fmt.Errorf("完成分片上传失败: %s: %s", completeResult.Code, completeResult.Message)
+	}
+
+	return nil
+}
+
+// abortMultipart 取消分片上传，释放已上传的分片
+func (s *S3Provider) abortMultipart(key, uploadID string) {
+	query := url.Values{}
+	query.Set("uploadId", uploadID)
+	if resp, err := s.do("DELETE", key, query, nil, nil, 0, ""); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// copyObject 服务端复制对象
+func (s *S3Provider) copyObject(srcKey, destKey string) error {
+	source := "/" + s.bucket + "/" + s3EscapePath(srcKey)
+	resp, err := s.do("PUT", destKey, nil, map[string]string{"X-Amz-Copy-Source": source}, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, "复制文件失败")
+	}
+	return nil
+}
+
+// s3Object 对象列表项
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	ETag         string    `xml:"ETag"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// s3ListResult ListObjectsV2 返回结果
+type s3ListResult struct {
+	Contents       []s3Object `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// listObjects 分页列举前缀下的对象，delimiter 为 / 时只列举一层
+func (s *S3Provider) listObjects(prefix, delimiter string) ([]s3Object, []string, error) {
+	var objects []s3Object
+	var dirs []string
+	token := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		var result s3ListResult
+		if err := s.getXML("", query, &result); err != nil {
+			return nil, nil, err
+		}
+
+		objects = append(objects, result.Contents...)
+		for _, p := range result.CommonPrefixes {
+			dirs = append(dirs, p.Prefix)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return objects, dirs, nil
+}
+
+// getXML 发送 GET 请求并解析 XML 结果
+func (s *S3Provider) getXML(key string, query url.Values, v interface{}) error {
+	resp, err := s.do("GET", key, query, nil, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	return decodeS3XML(resp, "请求失败", v)
+}
+
+// key 将远程路径转换为对象键
+func (s *S3Provider) key(remotePath string) string {
+	return strings.TrimPrefix(path.Join(s.prefix, cleanRemotePath(remotePath)), "/")
+}
+
+// dirPrefix 目录对应的键前缀
+func (s *S3Provider) dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// do 发送经过 SigV4 签名的请求，payloadHash 为空时按空请求体签名
+func (s *S3Provider) do(method, key string, query url.Values, headers map[string]string, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix("/"+s.bucket+"/"+key, "/")
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	// 使用与签名一致的编码，避免与 Go 默认的路径编码不同
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if payloadHash == "" {
+		payloadHash = s3EmptyHash
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	return s.httpClient.Do(req)
+}
+
+// sign 使用 AWS Signature Version 4 签名请求
+func (s *S3Provider) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// 签名 Host 和请求中设置的所有头
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// s3CanonicalQuery 按 SigV4 要求编码并排序查询参数
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3EscapePath 按 SigV4 要求编码对象键，保留路径分隔符
+func s3EscapePath(key string) string {
+	return s3Escape(key, false)
+}
+
+// s3Escape 按 RFC 3986 编码，仅保留非保留字符
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// decodeS3XML 检查响应状态并解析 XML 响应体
+func decodeS3XML(resp *http.Response, action string, v interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, action)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: 解析响应失败: %w", action, err)
+	}
+	return nil
+}
+
+// s3Error 提取 S3 错误响应中的错误码和描述
+func s3Error(resp *http.Response, action string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("%s: %s: %s", action, e.Code, e.Message)
+	}
+	return fmt.Errorf("%s: %s", action, resp.Status)
+}
+
+// sha256Hex 计算 SHA256 十六进制字符串
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
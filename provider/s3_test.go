@@ -0,0 +1,88 @@
+package provider_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/provider"
+	"CloudFileSync/provider/providertest"
+)
+
+func TestS3(t *testing.T) {
+	srv := providertest.NewS3Server()
+	defer srv.Close()
+
+	p, err := provider.NewS3Provider(srv.Tokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("hello"), 0644)
+
+	if err := p.UploadFile(local, "/docs/子目录/报告.txt"); err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	info, err := p.(provider.Stater).Stat("/docs/子目录/报告.txt")
+	if err != nil || info.Size != 5 {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+
+	// 对象存储没有真正的目录，前缀也视为目录
+	list, err := p.(provider.Lister).List("/docs")
+	if err != nil || len(list) != 1 || list[0].Name != "子目录" || !list[0].IsDir {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+
+	if err := p.(provider.Mover).MoveFile("/docs/子目录/报告.txt", "/moved/r.txt"); err != nil {
+		t.Fatalf("移动失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/docs/子目录/报告.txt"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("移动后原对象仍存在: %v", err)
+	}
+
+	// 根目录对应空前缀，删除会清空整个存储桶
+	for _, root := range []string{"", "/"} {
+		if err := p.DeleteFile(root); err == nil {
+			t.Fatalf("删除根目录 %q 应当失败", root)
+		}
+	}
+
+	// 删除目录时删除前缀下的全部对象
+	if err := p.DeleteFile("/moved"); err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/moved/r.txt"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("删除后对象仍存在: %v", err)
+	}
+}
+
+func TestS3Multipart(t *testing.T) {
+	srv := providertest.NewS3Server()
+	defer srv.Close()
+
+	p, err := provider.NewS3Provider(srv.Tokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过分片上传阈值（64MB）
+	data := bytes.Repeat([]byte("0123456789abcdef"), (65<<20)/16)
+	local := filepath.Join(t.TempDir(), "big.bin")
+	os.WriteFile(local, data, 0644)
+
+	if err := p.UploadFile(local, "/big.bin"); err != nil {
+		t.Fatalf("分片上传失败: %v", err)
+	}
+
+	downloaded := filepath.Join(t.TempDir(), "big.out")
+	if err := p.(provider.Downloader).DownloadFile("/big.bin", downloaded); err != nil {
+		t.Fatalf("下载失败: %v", err)
+	}
+	if got, _ := os.ReadFile(downloaded); !bytes.Equal(got, data) {
+		t.Fatal("下载内容与上传内容不一致")
+	}
+}
@@ -0,0 +1,476 @@
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPProvider SFTP 提供商，将文件推送到 SSH 主机
+type SFTPProvider struct {
+	addr      string
+	root      string
+	sshConfig *ssh.ClientConfig
+
+	mu        sync.Mutex
+	sshClient *ssh.Client
+	client    *sftp.Client
+}
+
+func init() {
+	Register("sftp", NewSFTPProvider, Schema{
+		DisplayName: "SFTP",
+		Icon:        "🖥️",
+		Fields: []Field{
+			{Key: "host", Label: "主机", Required: true, Placeholder: "example.com"},
+			{Key: "port", Label: "端口", Type: "number", Placeholder: "22"},
+			{Key: "username", Label: "用户名", Required: true},
+			{Key: "password", Label: "密码", Type: "password", Placeholder: "使用私钥时可留空"},
+			{Key: "private_key", Label: "私钥路径", Placeholder: "~/.ssh/id_ed25519"},
+			{Key: "passphrase", Label: "私钥密码", Type: "password"},
+			{Key: "known_hosts", Label: "known_hosts", Placeholder: "~/.ssh/known_hosts",
+				Help: "主机密钥必须已在 known_hosts 中，可先执行 ssh-keyscan 主机 >> ~/.ssh/known_hosts"},
+			{Key: "root", Label: "根目录", Placeholder: "可选，如 /srv/backup"},
+		},
+		AnyOf: []string{"password", "private_key"},
+	})
+}
+
+// NewSFTPProvider 创建 SFTP 提供商
+func NewSFTPProvider(tokens map[string]string) (Provider, error) {
+	host := tokens["host"]
+	if host == "" {
+		return nil, fmt.Errorf("缺少 host")
+	}
+	if tokens["username"] == "" {
+		return nil, fmt.Errorf("缺少 username")
+	}
+
+	port := tokens["port"]
+	if port == "" {
+		port = "22"
+	}
+
+	var auths []ssh.AuthMethod
+	if tokens["private_key"] != "" {
+		signer, err := loadPrivateKey(tokens["private_key"], tokens["passphrase"])
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if tokens["password"] != "" {
+		auths = append(auths, ssh.Password(tokens["password"]))
+	}
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("缺少 password 或 private_key")
+	}
+
+	hostKeyCallback, err := hostKeyCallback(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SFTPProvider{
+		addr: net.JoinHostPort(host, port),
+		root: tokens["root"],
+		sshConfig: &ssh.ClientConfig{
+			User:            tokens["username"],
+			Auth:            auths,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         30 * time.Second,
+		},
+	}, nil
+}
+
+// loadPrivateKey 加载私钥，可以是文件路径或 PEM 内容
+func loadPrivateKey(key, passphrase string) (ssh.Signer, error) {
+	data := []byte(key)
+	if !strings.Contains(key, "PRIVATE KEY") {
+		var err error
+		data, err = os.ReadFile(expandHome(key))
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %w", err)
+		}
+	}
+
+	var signer ssh.Signer
+	var err error
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return signer, nil
+}
+
+// hostKeyCallback 根据 known_hosts 校验主机密钥，默认使用 ~/.ssh/known_hosts
+func hostKeyCallback(tokens map[string]string) (ssh.HostKeyCallback, error) {
+	if tokens["insecure_ignore_host_key"] == "true" {
+		log.Printf("[SFTP] 警告: 已关闭主机密钥校验，连接可能被中间人劫持")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	knownHostsFile := tokens["known_hosts"]
+	if knownHostsFile == "" {
+		knownHostsFile = "~/.ssh/known_hosts"
+	}
+
+	callback, err := knownhosts.New(expandHome(knownHostsFile))
+	if err != nil {
+		return nil, fmt.Errorf("加载 known_hosts 失败: %w", err)
+	}
+	return callback, nil
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// Name 返回提供商名称
+func (s *SFTPProvider) Name() string {
+	return "SFTP"
+}
+
+// UploadFile 上传文件，先写入临时文件再重命名，避免读取方看到不完整的文件
+func (s *SFTPProvider) UploadFile(localPath, remotePath string) error {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	if fileInfo.IsDir() {
+		return s.CreateDir(remotePath)
+	}
+
+	dest := s.fullPath(remotePath)
+
+	return s.withClient(func(c *sftp.Client) error {
+		// 大小和修改时间一致时视为未变化
+		if destInfo, err := c.Stat(dest); err == nil && !destInfo.IsDir() &&
+			destInfo.Size() == fileInfo.Size() && destInfo.ModTime().Unix() == fileInfo.ModTime().Unix() {
+			log.Printf("[%s] 文件已存在，跳过上传: %s", s.Name(), remotePath)
+			return nil
+		}
+
+		log.Printf("[%s] 上传文件: %s -> %s", s.Name(), localPath, remotePath)
+
+		if err := c.MkdirAll(path.Dir(dest)); err != nil {
+			return fmt.Errorf("创建父目录失败: %w", err)
+		}
+
+		tmpPath := path.Join(path.Dir(dest), "."+path.Base(dest)+".cfs-upload-"+randomSuffix())
+		if err := s.writeFile(c, localPath, tmpPath); err != nil {
+			c.Remove(tmpPath)
+			return err
+		}
+
+		// 保留修改时间，便于后续比较
+		if err := c.Chtimes(tmpPath, fileInfo.ModTime(), fileInfo.ModTime()); err != nil {
+			log.Printf("[%s] 设置修改时间失败: %v", s.Name(), err)
+		}
+
+		if err := s.rename(c, tmpPath, dest); err != nil {
+			c.Remove(tmpPath)
+			return fmt.Errorf("重命名临时文件失败: %w", err)
+		}
+
+		log.Printf("[%s] 上传完成: %s", s.Name(), remotePath)
+		return nil
+	})
+}
+
+// writeFile 将本地文件写入远程路径
+func (s *SFTPProvider) writeFile(c *sftp.Client, localPath, remotePath string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := c.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("创建远程文件失败: %w", err)
+	}
+
+	if _, err := dst.ReadFrom(src); err != nil {
+		dst.Close()
+		return fmt.Errorf("写入远程文件失败: %w", err)
+	}
+	return dst.Close()
+}
+
+// rename 覆盖式重命名，服务器不支持 posix-rename 扩展时先删除目标
+func (s *SFTPProvider) rename(c *sftp.Client, oldPath, newPath string) error {
+	if _, ok := c.HasExtension("posix-rename@openssh.com"); ok {
+		return c.PosixRename(oldPath, newPath)
+	}
+
+	if _, err := c.Stat(newPath); err == nil {
+		if err := c.Remove(newPath); err != nil {
+			return err
+		}
+	}
+	return c.Rename(oldPath, newPath)
+}
+
+// DeleteFile 删除文件或目录
+func (s *SFTPProvider) DeleteFile(remotePath string) error {
+	if cleanRemotePath(remotePath) == "/" {
+		return fmt.Errorf("不能删除根目录")
+	}
+	dest := s.fullPath(remotePath)
+
+	return s.withClient(func(c *sftp.Client) error {
+		info, err := c.Lstat(dest)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("[%s] 文件不存在，跳过删除: %s", s.Name(), remotePath)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			err = c.RemoveAll(dest)
+		} else {
+			err = c.Remove(dest)
+		}
+		if err != nil {
+			return fmt.Errorf("删除文件失败: %w", err)
+		}
+
+		log.Printf("[%s] 删除文件: %s", s.Name(), remotePath)
+		return nil
+	})
+}
+
+// CreateDir 递归创建目录
+func (s *SFTPProvider) CreateDir(remotePath string) error {
+	return s.withClient(func(c *sftp.Client) error {
+		if err := c.MkdirAll(s.fullPath(remotePath)); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// Stat 获取文件信息
+func (s *SFTPProvider) Stat(remotePath string) (*FileInfo, error) {
+	var result *FileInfo
+	err := s.withClient(func(c *sftp.Client) error {
+		info, err := c.Stat(s.fullPath(remotePath))
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		result = s.fileInfo(cleanRemotePath(remotePath), info)
+		return nil
+	})
+	return result, err
+}
+
+// List 列举目录
+func (s *SFTPProvider) List(remotePath string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := s.withClient(func(c *sftp.Client) error {
+		entries, err := c.ReadDir(s.fullPath(remotePath))
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		dir := cleanRemotePath(remotePath)
+		files = make([]FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			files = append(files, *s.fileInfo(path.Join(dir, entry.Name()), entry))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// DownloadFile 下载文件
+func (s *SFTPProvider) DownloadFile(remotePath, localPath string) error {
+	return s.withClient(func(c *sftp.Client) error {
+		file, err := c.Open(s.fullPath(remotePath))
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("打开远程文件失败: %w", err)
+		}
+		defer file.Close()
+
+		log.Printf("[%s] 下载文件: %s -> %s", s.Name(), remotePath, localPath)
+		return writeLocalFile(localPath, file)
+	})
+}
+
+// MoveFile 移动文件
+func (s *SFTPProvider) MoveFile(oldPath, newPath string) error {
+	src := s.fullPath(oldPath)
+	dest := s.fullPath(newPath)
+
+	return s.withClient(func(c *sftp.Client) error {
+		if _, err := c.Lstat(src); errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+
+		if err := c.MkdirAll(path.Dir(dest)); err != nil {
+			return fmt.Errorf("创建目标目录失败: %w", err)
+		}
+
+		if err := s.rename(c, src, dest); err != nil {
+			return fmt.Errorf("移动文件失败: %w", err)
+		}
+
+		log.Printf("[%s] 移动文件: %s -> %s", s.Name(), oldPath, newPath)
+		return nil
+	})
+}
+
+// Verify 校验连接并获取容量（服务器支持 statvfs 扩展时）
+func (s *SFTPProvider) Verify() (*AccountInfo, error) {
+	info := &AccountInfo{UserName: s.sshConfig.User + "@" + s.addr}
+	err := s.withClient(func(c *sftp.Client) error {
+		root := s.fullPath("/")
+		if _, err := c.Stat(root); err != nil {
+			return fmt.Errorf("根目录不可用: %w", err)
+		}
+
+		if vfs, err := c.StatVFS(root); err == nil {
+			info.TotalSpace = int64(vfs.TotalSpace())
+			info.UsedSpace = int64(vfs.TotalSpace() - vfs.FreeSpace())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// withClient 获取 SFTP 连接并执行操作，连接断开时重连并重试一次
+func (s *SFTPProvider) withClient(fn func(c *sftp.Client) error) error {
+	for attempt := 0; ; attempt++ {
+		c, err := s.connect()
+		if err != nil {
+			return err
+		}
+
+		err = fn(c)
+		if attempt == 0 && isConnectionLost(err) {
+			log.Printf("[%s] 连接已断开，正在重连: %v", s.Name(), err)
+			s.disconnect()
+			continue
+		}
+		return err
+	}
+}
+
+// connect 建立或复用 SFTP 连接
+func (s *SFTPProvider) connect() (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	sshClient, err := ssh.Dial("tcp", s.addr, s.sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("连接 SSH 服务器失败: %w", err)
+	}
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("启动 SFTP 会话失败: %w", err)
+	}
+
+	s.sshClient = sshClient
+	s.client = client
+	return client, nil
+}
+
+// disconnect 关闭当前连接
+func (s *SFTPProvider) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+	if s.sshClient != nil {
+		s.sshClient.Close()
+		s.sshClient = nil
+	}
+}
+
+// Close 实现 io.Closer 接口，关闭 SSH 连接
+func (s *SFTPProvider) Close() error {
+	s.disconnect()
+	return nil
+}
+
+// isConnectionLost 判断错误是否由连接断开引起
+func isConnectionLost(err error) bool {
+	return errors.Is(err, sftp.ErrSSHFxConnectionLost) || errors.Is(err, io.EOF) ||
+		errors.Is(err, net.ErrClosed)
+}
+
+// fullPath 将远程路径映射到 root 下
+func (s *SFTPProvider) fullPath(remotePath string) string {
+	if s.root == "" {
+		return cleanRemotePath(remotePath)
+	}
+	return path.Join(s.root, cleanRemotePath(remotePath))
+}
+
+// fileInfo 转换文件信息
+func (s *SFTPProvider) fileInfo(remotePath string, info os.FileInfo) *FileInfo {
+	return &FileInfo{
+		Path:    remotePath,
+		Name:    path.Base(remotePath),
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// randomSuffix 生成临时文件名后缀
+func randomSuffix() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
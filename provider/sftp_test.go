@@ -0,0 +1,161 @@
+package provider_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"CloudFileSync/provider"
+)
+
+func TestSFTP(t *testing.T) {
+	addr := startSFTPServer(t, "test", "secret")
+	host, port, _ := net.SplitHostPort(addr)
+	root := t.TempDir()
+
+	p, err := provider.NewSFTPProvider(map[string]string{
+		"host":                     host,
+		"port":                     port,
+		"username":                 "test",
+		"password":                 "secret",
+		"insecure_ignore_host_key": "true",
+		"root":                     root,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("hello"), 0644)
+
+	// 上级目录不存在时自动创建，文件写入 root 下
+	if err := p.UploadFile(local, "/docs/子目录/报告.txt"); err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "docs", "子目录", "报告.txt")); err != nil || string(data) != "hello" {
+		t.Fatalf("服务端文件内容为 %q, %v", data, err)
+	}
+	info, err := p.(provider.Stater).Stat("/docs/子目录/报告.txt")
+	if err != nil || info.Size != 5 {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+
+	if err := p.(provider.Mover).MoveFile("/docs/子目录/报告.txt", "/moved/r.txt"); err != nil {
+		t.Fatalf("移动失败: %v", err)
+	}
+	if err := p.DeleteFile("/"); err == nil {
+		t.Fatal("删除根目录应当失败")
+	}
+	if err := p.DeleteFile("/moved"); err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/moved/r.txt"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("删除后文件仍存在: %v", err)
+	}
+}
+
+func TestSFTPWrongPassword(t *testing.T) {
+	addr := startSFTPServer(t, "test", "secret")
+	host, port, _ := net.SplitHostPort(addr)
+
+	p, err := provider.NewSFTPProvider(map[string]string{
+		"host":                     host,
+		"port":                     port,
+		"username":                 "test",
+		"password":                 "wrong",
+		"insecure_ignore_host_key": "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.(provider.Verifier).Verify(); err == nil {
+		t.Fatal("密码错误时期望校验失败")
+	}
+}
+
+// startSFTPServer 启动使用密码认证的 SFTP 服务，直接读写本机文件系统
+func startSFTPServer(t *testing.T, user, password string) string {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if conn.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("密码错误")
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// serveSFTP 处理一个 SSH 连接上的 sftp 子系统请求
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "只支持 session")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}(requests)
+
+		go func() {
+			server, err := sftp.NewServer(channel)
+			if err != nil {
+				channel.Close()
+				return
+			}
+			server.Serve()
+			server.Close()
+		}()
+	}
+}
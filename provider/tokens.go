@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"log"
+	"sync"
+)
+
+// TokenRefresher 会自行刷新令牌的提供商。刷新后调用 fn 通知新的令牌（键与 Tokens 相同），以便写回配置
+type TokenRefresher interface {
+	OnTokenRefresh(fn func(tokens map[string]string))
+}
+
+// TokenSaver 保存云盘配置 providerName 刷新后的令牌
+type TokenSaver func(providerName string, tokens map[string]string) error
+
+var (
+	tokenSaverMu sync.RWMutex
+	tokenSaver   TokenSaver
+)
+
+// SetTokenSaver 设置保存刷新后令牌的函数，通常由持有配置文件路径的 main 包调用。
+// 之后 NewProvider 创建的 TokenRefresher 刷新令牌时都会调用它
+func SetTokenSaver(fn TokenSaver) {
+	tokenSaverMu.Lock()
+	defer tokenSaverMu.Unlock()
+	tokenSaver = fn
+}
+
+// watchTokens 让提供商刷新令牌后通过 TokenSaver 写回配置，保存失败只记录日志
+func watchTokens(p Provider, providerName string) {
+	refresher, ok := p.(TokenRefresher)
+	if !ok {
+		return
+	}
+
+	refresher.OnTokenRefresh(func(tokens map[string]string) {
+		tokenSaverMu.RLock()
+		save := tokenSaver
+		tokenSaverMu.RUnlock()
+
+		if save == nil {
+			return
+		}
+		if err := save(providerName, tokens); err != nil {
+			log.Printf("[%s] 保存刷新后的令牌失败: %v", p.Name(), err)
+			return
+		}
+		log.Printf("[%s] 刷新后的令牌已写回配置: %s", p.Name(), providerName)
+	})
+}
@@ -0,0 +1,616 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webdavChunkThreshold 超过此大小的文件使用分块传输编码上传，避免整个文件进入内存
+const webdavChunkThreshold = 32 * 1024 * 1024
+
+// WebDAVProvider WebDAV 提供商（Nextcloud、坚果云、群晖等）
+type WebDAVProvider struct {
+	username   string
+	password   string
+	authType   string // basic、digest，为空时根据服务器质询自动选择
+	httpClient *http.Client
+	baseURL    string
+
+	mu     sync.Mutex
+	digest *digestChallenge // 服务器返回的 Digest 质询
+	nc     int              // Digest 请求计数
+	probed bool             // 是否已用不带请求体的请求探测过认证方式
+}
+
+func init() {
+	Register("webdav", NewWebDAVProvider, Schema{
+		DisplayName: "WebDAV",
+		Icon:        "🗄️",
+		Fields: []Field{
+			{Key: "url", Label: "服务器地址", Required: true, Placeholder: "https://dav.jianguoyun.com/dav/"},
+			{Key: "username", Label: "用户名"},
+			{Key: "password", Label: "密码", Type: "password", Placeholder: "密码或应用专用密码"},
+			{Key: "auth", Label: "认证方式", Type: "select", Options: []Option{
+				{Value: "", Label: "自动"},
+				{Value: "basic", Label: "Basic"},
+				{Value: "digest", Label: "Digest"},
+			}},
+		},
+	})
+}
+
+// WebDAV 连接的超时时间。大文件上传耗时不确定，不设置整体超时，只限制建立连接和等待响应，避免服务器无响应时一直阻塞
+const (
+	webdavDialTimeout     = 30 * time.Second
+	webdavResponseTimeout = 2 * time.Minute // 请求发送完成后等待响应头的时间，包括服务器处理上传内容的时间
+	webdavIdleTimeout     = 90 * time.Second
+)
+
+// NewWebDAVProvider 创建 WebDAV 提供商
+func NewWebDAVProvider(tokens map[string]string) (Provider, error) {
+	return NewWebDAVProviderWithClient(tokens, nil)
+}
+
+// NewWebDAVProviderWithClient 使用指定的 HTTP 客户端创建 WebDAV 提供商，用于测试或代理，
+// httpClient 为空时使用带连接和响应超时的默认客户端
+func NewWebDAVProviderWithClient(tokens map[string]string, httpClient *http.Client) (Provider, error) {
+	baseURL := tokens["url"]
+	if baseURL == "" {
+		return nil, fmt.Errorf("缺少 url")
+	}
+
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("无效的 url: %w", err)
+	}
+
+	authType := strings.ToLower(tokens["auth"])
+	if authType != "" && authType != "basic" && authType != "digest" {
+		return nil, fmt.Errorf("不支持的认证方式: %s", tokens["auth"])
+	}
+
+	if httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{
+			Timeout:   webdavDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.ResponseHeaderTimeout = webdavResponseTimeout
+		transport.IdleConnTimeout = webdavIdleTimeout
+		httpClient = &http.Client{Transport: transport}
+	}
+
+	return &WebDAVProvider{
+		username:   tokens["username"],
+		password:   tokens["password"],
+		authType:   authType,
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// Name 返回提供商名称
+func (d *WebDAVProvider) Name() string {
+	return "WebDAV"
+}
+
+// UploadFile 上传文件
+func (d *WebDAVProvider) UploadFile(localPath, remotePath string) error {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	if fileInfo.IsDir() {
+		return d.CreateDir(remotePath)
+	}
+
+	log.Printf("[%s] 上传文件: %s -> %s", d.Name(), localPath, remotePath)
+
+	if err := d.CreateDir(path.Dir(cleanRemotePath(remotePath))); err != nil {
+		return fmt.Errorf("创建父目录失败: %w", err)
+	}
+
+	resp, err := d.do("PUT", remotePath, nil, func() (io.Reader, int64, error) {
+		file, err := os.Open(localPath)
+		if err != nil {
+			return nil, 0, err
+		}
+		// 大文件使用分块传输编码（长度为 -1）
+		if fileInfo.Size() > webdavChunkThreshold {
+			return file, -1, nil
+		}
+		return file, fileInfo.Size(), nil
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("上传失败: %s", resp.Status)
+	}
+
+	log.Printf("[%s] 上传完成: %s", d.Name(), remotePath)
+	return nil
+}
+
+// DeleteFile 删除文件或目录
+func (d *WebDAVProvider) DeleteFile(remotePath string) error {
+	if cleanRemotePath(remotePath) == "/" {
+		return fmt.Errorf("不能删除根目录")
+	}
+
+	resp, err := d.do("DELETE", remotePath, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusAccepted:
+	case http.StatusNotFound:
+		log.Printf("[%s] 文件不存在，跳过删除: %s", d.Name(), remotePath)
+		return nil
+	default:
+		return fmt.Errorf("删除文件失败: %s", resp.Status)
+	}
+
+	log.Printf("[%s] 删除文件: %s", d.Name(), remotePath)
+	return nil
+}
+
+// CreateDir 逐级创建目录（MKCOL）
+func (d *WebDAVProvider) CreateDir(remotePath string) error {
+	current := ""
+	for _, part := range strings.Split(strings.Trim(cleanRemotePath(remotePath), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		current += "/" + part
+
+		resp, err := d.do("MKCOL", current, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		// 405 表示目录已存在
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("创建目录失败 %s: %s", current, resp.Status)
+		}
+	}
+	return nil
+}
+
+// Stat 获取文件信息（PROPFIND Depth: 0）
+func (d *WebDAVProvider) Stat(remotePath string) (*FileInfo, error) {
+	files, err := d.propfind(remotePath, "0")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return &files[0], nil
+}
+
+// List 列举目录（PROPFIND Depth: 1）
+func (d *WebDAVProvider) List(remotePath string) ([]FileInfo, error) {
+	files, err := d.propfind(remotePath, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	// 结果中包含目录自身
+	dir := cleanRemotePath(remotePath)
+	result := make([]FileInfo, 0, len(files))
+	for _, f := range files {
+		if f.Path != dir {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// DownloadFile 下载文件
+func (d *WebDAVProvider) DownloadFile(remotePath, localPath string) error {
+	resp, err := d.do("GET", remotePath, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载文件失败: %s", resp.Status)
+	}
+
+	log.Printf("[%s] 下载文件: %s -> %s", d.Name(), remotePath, localPath)
+	return writeLocalFile(localPath, resp.Body)
+}
+
+// MoveFile 移动文件（MOVE）
+func (d *WebDAVProvider) MoveFile(oldPath, newPath string) error {
+	if err := d.CreateDir(path.Dir(cleanRemotePath(newPath))); err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	headers := map[string]string{
+		"Destination": d.url(newPath),
+		"Overwrite":   "T",
+	}
+	resp, err := d.do("MOVE", oldPath, headers, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusNoContent:
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("移动文件失败: %s", resp.Status)
+	}
+
+	log.Printf("[%s] 移动文件: %s -> %s", d.Name(), oldPath, newPath)
+	return nil
+}
+
+// Verify 校验账号并获取容量（RFC 4331 quota 属性，服务器不支持时为 0）
+func (d *WebDAVProvider) Verify() (*AccountInfo, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:quota-available-bytes/><d:quota-used-bytes/></d:prop></d:propfind>`
+
+	ms, err := d.propfindRaw("/", "0", body)
+	if err != nil {
+		return nil, err
+	}
+	if ms == nil {
+		return nil, ErrNotFound
+	}
+
+	info := &AccountInfo{UserName: d.username}
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			used, _ := strconv.ParseInt(strings.TrimSpace(ps.Prop.QuotaUsed), 10, 64)
+			available, _ := strconv.ParseInt(strings.TrimSpace(ps.Prop.QuotaAvailable), 10, 64)
+			if used > 0 || available > 0 {
+				info.UsedSpace = used
+				info.TotalSpace = used + available
+			}
+		}
+	}
+	return info, nil
+}
+
+// webdavMultistatus PROPFIND 返回结果
+type webdavMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				DisplayName    string `xml:"displayname"`
+				ContentLength  string `xml:"getcontentlength"`
+				LastModified   string `xml:"getlastmodified"`
+				ETag           string `xml:"getetag"`
+				QuotaUsed      string `xml:"quota-used-bytes"`
+				QuotaAvailable string `xml:"quota-available-bytes"`
+				ResourceType   struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// propfind 查询文件属性
+func (d *WebDAVProvider) propfind(remotePath, depth string) ([]FileInfo, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:displayname/><d:getcontentlength/><d:getlastmodified/><d:getetag/><d:resourcetype/></d:prop></d:propfind>`
+
+	ms, err := d.propfindRaw(remotePath, depth, body)
+	if err != nil || ms == nil {
+		return nil, err
+	}
+
+	basePath := d.basePath()
+	files := make([]FileInfo, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, err := url.PathUnescape(r.Href)
+		if err != nil {
+			href = r.Href
+		}
+		// href 可能是完整 URL
+		if u, err := url.Parse(href); err == nil && u.Host != "" {
+			href = u.Path
+		}
+		remote := cleanRemotePath(strings.TrimPrefix(href, basePath))
+
+		info := FileInfo{Path: remote, Name: path.Base(remote)}
+		for _, ps := range r.Propstat {
+			if ps.Status != "" && !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			prop := ps.Prop
+			info.IsDir = prop.ResourceType.Collection != nil
+			info.Size, _ = strconv.ParseInt(prop.ContentLength, 10, 64)
+			info.ModTime, _ = http.ParseTime(prop.LastModified)
+			info.Hash = strings.Trim(prop.ETag, `"`)
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// propfindRaw 发送 PROPFIND 请求，路径不存在时返回 nil
+func (d *WebDAVProvider) propfindRaw(remotePath, depth, body string) (*webdavMultistatus, error) {
+	headers := map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml; charset=utf-8",
+	}
+	resp, err := d.do("PROPFIND", remotePath, headers, func() (io.Reader, int64, error) {
+		return strings.NewReader(body), int64(len(body)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("查询文件属性失败: %s", resp.Status)
+	}
+
+	var ms webdavMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("解析 PROPFIND 结果失败: %w", err)
+	}
+	return &ms, nil
+}
+
+// do 发送请求，遇到 Digest 质询时自动重试
+func (d *WebDAVProvider) do(method, remotePath string, headers map[string]string, body func() (io.Reader, int64, error)) (*http.Response, error) {
+	// 带请求体时先探测认证方式，避免整个文件在第一次 401 时白白上传
+	if body != nil {
+		if err := d.probeAuth(); err != nil {
+			return nil, err
+		}
+	}
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, d.url(remotePath), nil)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			// 请求体由 Transport 负责关闭，需要重发时通过 GetBody 重新打开
+			req.Body, req.ContentLength, err = openBody(body)
+			if err != nil {
+				return nil, err
+			}
+			req.GetBody = func() (io.ReadCloser, error) {
+				rc, _, err := openBody(body)
+				return rc, err
+			}
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		d.setAuthHeader(req)
+
+		return d.httpClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+
+	// 服务器要求 Digest 认证时解析质询并重试一次
+	if resp.StatusCode == http.StatusUnauthorized && d.username != "" && d.authType != "basic" {
+		challenge := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+		if challenge != nil {
+			resp.Body.Close()
+
+			d.mu.Lock()
+			d.digest = challenge
+			d.nc = 0
+			d.mu.Unlock()
+
+			return send()
+		}
+	}
+
+	return resp, nil
+}
+
+// probeAuth 尚未取得 Digest 质询时，用不带请求体的 PROPFIND 探测一次服务器的认证方式
+func (d *WebDAVProvider) probeAuth() error {
+	d.mu.Lock()
+	skip := d.username == "" || d.authType == "basic" || d.digest != nil || d.probed
+	d.mu.Unlock()
+	if skip {
+		return nil
+	}
+
+	resp, err := d.do("PROPFIND", "/", map[string]string{"Depth": "0"}, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	d.mu.Lock()
+	d.probed = true
+	d.mu.Unlock()
+	return nil
+}
+
+// openBody 打开请求体，不是 io.ReadCloser 时包装为 io.NopCloser
+func openBody(body func() (io.Reader, int64, error)) (io.ReadCloser, int64, error) {
+	reader, length, err := body()
+	if err != nil {
+		return nil, 0, err
+	}
+	if rc, ok := reader.(io.ReadCloser); ok {
+		return rc, length, nil
+	}
+	return io.NopCloser(reader), length, nil
+}
+
+// setAuthHeader 设置认证头
+func (d *WebDAVProvider) setAuthHeader(req *http.Request) {
+	if d.username == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.digest == nil {
+		if d.authType != "digest" {
+			req.SetBasicAuth(d.username, d.password)
+		}
+		return
+	}
+
+	d.nc++
+	req.Header.Set("Authorization", d.digest.authorization(d.username, d.password, req.Method, req.URL.RequestURI(), d.nc))
+}
+
+// url 生成远程路径对应的完整地址
+func (d *WebDAVProvider) url(remotePath string) string {
+	var escaped []string
+	for _, part := range strings.Split(cleanRemotePath(remotePath), "/") {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return d.baseURL + strings.Join(escaped, "/")
+}
+
+// basePath 获取 baseURL 中的路径部分
+func (d *WebDAVProvider) basePath() string {
+	u, err := url.Parse(d.baseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// digestChallenge Digest 认证质询
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	qop       string
+	algorithm string
+}
+
+// parseDigestChallenge 解析 WWW-Authenticate 中的 Digest 质询
+func parseDigestChallenge(header string) *digestChallenge {
+	if !strings.HasPrefix(strings.ToLower(header), "digest ") {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, part := range splitDigestParams(header[len("digest "):]) {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	c := &digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: params["algorithm"],
+	}
+	// 多个 qop 时优先使用 auth
+	for _, qop := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			c.qop = "auth"
+		}
+	}
+	return c
+}
+
+// splitDigestParams 按逗号拆分参数，忽略引号内的逗号
+func splitDigestParams(s string) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i, ch := range s {
+		switch ch {
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// authorization 计算 Digest 认证头
+func (c *digestChallenge) authorization(username, password, method, uri string, nc int) string {
+	ha1 := md5Hex(username + ":" + c.realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+
+	cnonceBytes := make([]byte, 8)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	if strings.EqualFold(c.algorithm, "MD5-sess") {
+		ha1 = md5Hex(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+
+	var response string
+	if c.qop == "auth" {
+		response = md5Hex(ha1 + ":" + c.nonce + ":" + ncValue + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	} else {
+		response = md5Hex(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		username, c.realm, c.nonce, uri, response)
+	if c.algorithm != "" {
+		fmt.Fprintf(&b, `, algorithm=%s`, c.algorithm)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, c.opaque)
+	}
+	if c.qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce="%s"`, c.qop, ncValue, cnonce)
+	}
+	return b.String()
+}
+
+// md5Hex 计算 MD5 十六进制字符串
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package provider_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"CloudFileSync/provider"
+)
+
+// newWebDAVServer 启动进程内的 WebDAV 服务，文件保存在内存中
+func newWebDAVServer(t *testing.T, username, password string) *httptest.Server {
+	t.Helper()
+
+	handler := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); username != "" && (!ok || user != username || pass != password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebDAV(t *testing.T) {
+	srv := newWebDAVServer(t, "user", "pass")
+	p, err := provider.NewWebDAVProvider(map[string]string{"url": srv.URL, "username": "user", "password": "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("hello"), 0644)
+
+	// 上级目录不存在时自动创建
+	if err := p.UploadFile(local, "/docs/子目录/报告.txt"); err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	info, err := p.(provider.Stater).Stat("/docs/子目录/报告.txt")
+	if err != nil || info.Size != 5 || info.IsDir {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+	list, err := p.(provider.Lister).List("/docs/子目录")
+	if err != nil || len(list) != 1 || list[0].Name != "报告.txt" {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+
+	downloaded := filepath.Join(t.TempDir(), "b.txt")
+	if err := p.(provider.Downloader).DownloadFile("/docs/子目录/报告.txt", downloaded); err != nil {
+		t.Fatalf("下载失败: %v", err)
+	}
+	if data, _ := os.ReadFile(downloaded); string(data) != "hello" {
+		t.Fatalf("下载内容为 %q", data)
+	}
+
+	if err := p.(provider.Mover).MoveFile("/docs/子目录/报告.txt", "/moved/r.txt"); err != nil {
+		t.Fatalf("移动失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/docs/子目录/报告.txt"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("移动后原文件仍存在: %v", err)
+	}
+
+	if err := p.DeleteFile("/"); err == nil {
+		t.Fatal("删除根目录应当失败")
+	}
+	if err := p.DeleteFile("/moved"); err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if _, err := p.(provider.Stater).Stat("/moved/r.txt"); !errors.Is(err, provider.ErrNotFound) {
+		t.Fatalf("删除后文件仍存在: %v", err)
+	}
+}
+
+func TestWebDAVDigestProbe(t *testing.T) {
+	// 只接受 Digest 认证的服务器，记录被 401 拒绝的请求体大小
+	var mu sync.Mutex
+	var rejected []int64
+	handler := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, `Digest username="user"`) || !strings.Contains(auth, `nonce="abc"`) {
+			n, _ := io.Copy(io.Discard, r.Body)
+			mu.Lock()
+			rejected = append(rejected, n)
+			mu.Unlock()
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc", qop="auth"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := provider.NewWebDAVProvider(map[string]string{"url": srv.URL, "username": "user", "password": "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(local, []byte("hello"), 0644)
+	if err := p.UploadFile(local, "/a.txt"); err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	info, err := p.(provider.Stater).Stat("/a.txt")
+	if err != nil || info.Size != 5 {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+
+	// 只有不带请求体的探测请求收到 401，文件内容只上传一次
+	if len(rejected) != 1 || rejected[0] != 0 {
+		t.Errorf("被拒绝的请求体大小 %v，期望只有一个空请求", rejected)
+	}
+}
+
+func TestWebDAVWrongPassword(t *testing.T) {
+	srv := newWebDAVServer(t, "user", "pass")
+	p, err := provider.NewWebDAVProvider(map[string]string{"url": srv.URL, "username": "user", "password": "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.(provider.Verifier).Verify(); err == nil {
+		t.Fatal("密码错误时期望校验失败")
+	}
+}
+
+func TestWebDAVServerNotResponding(t *testing.T) {
+	// 服务器接受连接但不返回响应
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	client := srv.Client()
+	client.Transport.(*http.Transport).ResponseHeaderTimeout = 100 * time.Millisecond
+	p, err := provider.NewWebDAVProviderWithClient(map[string]string{"url": srv.URL}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.(provider.Verifier).Verify(); err == nil {
+		t.Fatal("服务器无响应时期望校验失败")
+	}
+}
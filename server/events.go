@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"CloudFileSync/events"
+)
+
+// eventKeepAlive SSE 保活间隔，避免代理断开空闲连接
+const eventKeepAlive = 30 * time.Second
+
+// eventsSince 读取请求中最后收到的事件 ID：Last-Event-ID 请求头（浏览器断线重连时自动带上）或 since 参数
+func eventsSince(r *http.Request) uint64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("since")
+	}
+	id, _ := strconv.ParseUint(value, 10, 64)
+	return id
+}
+
+// handleEvents 返回缓冲区中 ID 大于 since 的同步事件
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.sendSuccess(w, "获取同步事件成功", s.eventLog.Since(eventsSince(r)))
+}
+
+// handleEventStream 以 Server-Sent Events 推送同步事件：先发送缓冲区中 ID 大于 since 的事件，再实时推送
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.sendError(w, "不支持事件流", http.StatusInternalServerError)
+		return
+	}
+
+	backlog, ch, cancel := s.eventLog.Subscribe(eventsSince(r))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	for _, e := range backlog {
+		writeEvent(w, e)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				// 推送跟不上时订阅被关闭，浏览器会带上 Last-Event-ID 重连并补齐
+				return
+			}
+			writeEvent(w, e)
+			flusher.Flush()
+
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent 写入一条 SSE 消息，id 用于断线重连
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.ID, data)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"CloudFileSync/config"
+	"CloudFileSync/engine"
+	"CloudFileSync/events"
+)
+
+func TestEventStream(t *testing.T) {
+	s := NewServer(engine.New(&config.Config{WatchDir: t.TempDir()}), t.TempDir()+"/config.json", "127.0.0.1:0")
+	s.eventLog = events.NewLog(10)
+	s.eventLog.Publish(events.Event{Kind: events.KindQueued, Path: "a.txt"})
+	s.eventLog.Publish(events.Event{Kind: events.KindDone, Provider: "p", Path: "a.txt"})
+
+	ts := httptest.NewServer(s.httpServer.Handler)
+	defer ts.Close()
+
+	// 从第 1 个事件之后开始
+	req, _ := http.NewRequest("GET", ts.URL+"/api/events/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type 为 %s", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() events.Event {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				var e events.Event
+				if err := json.Unmarshal([]byte(data), &e); err != nil {
+					t.Fatal(err)
+				}
+				return e
+			}
+		}
+	}
+
+	if e := next(); e.ID != 2 || e.Provider != "p" || e.Kind != events.KindDone {
+		t.Fatalf("收到 %+v，期望第 2 个事件", e)
+	}
+
+	s.eventLog.Publish(events.Event{Kind: events.KindFailed, Provider: "p", Path: "b.txt", Message: "网络错误"})
+	if e := next(); e.ID != 3 || e.Level != events.LevelError || e.Path != "b.txt" {
+		t.Fatalf("收到 %+v，期望实时推送的第 3 个事件", e)
+	}
+}
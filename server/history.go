@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"CloudFileSync/history"
+)
+
+// parseHistoryTime 解析筛选时间，支持 RFC3339 和 2006-01-02（本地时间零点）
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// handleHistory 分页查询同步历史，支持按云盘、动作、结果、路径和时间筛选
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := history.Query{
+		Provider: params.Get("provider"),
+		Action:   params.Get("action"),
+		Result:   history.Result(params.Get("result")),
+		Search:   params.Get("q"),
+	}
+	q.Page, _ = strconv.Atoi(params.Get("page"))
+	q.PageSize, _ = strconv.Atoi(params.Get("page_size"))
+
+	if value := params.Get("from"); value != "" {
+		since, err := parseHistoryTime(value)
+		if err != nil {
+			s.sendError(w, "起始时间格式错误: "+value, http.StatusBadRequest)
+			return
+		}
+		q.Since = since
+	}
+	if value := params.Get("to"); value != "" {
+		until, err := parseHistoryTime(value)
+		if err != nil {
+			s.sendError(w, "结束时间格式错误: "+value, http.StatusBadRequest)
+			return
+		}
+		// 只给出日期时包含当天
+		if len(value) == len("2006-01-02") {
+			until = until.AddDate(0, 0, 1)
+		}
+		q.Until = until
+	}
+
+	page, err := history.New(s.engine.Config().GetHistoryFile()).Query(q)
+	if err != nil {
+		s.sendError(w, "查询同步历史失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.sendSuccess(w, "获取同步历史成功", page)
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"CloudFileSync/config"
+	"CloudFileSync/engine"
+	"CloudFileSync/history"
+)
+
+func TestHistory(t *testing.T) {
+	cfg := &config.Config{WatchDir: t.TempDir(), HistoryFile: filepath.Join(t.TempDir(), "history.jsonl")}
+	s := NewServer(engine.New(cfg), t.TempDir()+"/config.json", "127.0.0.1:0")
+	c := &testClient{t: t, handler: s.httpServer.Handler}
+
+	store := history.New(cfg.HistoryFile)
+	store.Append(history.Record{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local), Provider: "a", Path: "a.txt", Action: "upload", Result: history.ResultSuccess})
+	store.Append(history.Record{Time: time.Date(2024, 5, 2, 10, 0, 0, 0, time.Local), Provider: "b", Path: "b.txt", Action: "upload", Result: history.ResultFailed})
+
+	code, resp := c.do("GET", "/api/history?provider=b", "", nil)
+	if code != http.StatusOK {
+		t.Fatalf("查询同步历史返回 %d %+v", code, resp)
+	}
+	page := resp.Data.(map[string]interface{})
+	if page["total"] != 1.0 {
+		t.Fatalf("按云盘筛选: %+v", page)
+	}
+
+	// 只给出日期时结束日期包含当天
+	if _, resp := c.do("GET", "/api/history?from=2024-05-01&to=2024-05-01", "", nil); resp.Data.(map[string]interface{})["total"] != 1.0 {
+		t.Fatalf("按日期筛选: %+v", resp.Data)
+	}
+
+	if code, _ := c.do("GET", "/api/history?from=yesterday", "", nil); code != http.StatusBadRequest {
+		t.Fatalf("时间格式错误时返回 %d，期望 400", code)
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"CloudFileSync/auth"
+	"CloudFileSync/config"
+)
+
+// oauthSessionTTL 授权会话有效期
+const oauthSessionTTL = 10 * time.Minute
+
+// oauthSession 进行中的授权
+type oauthSession struct {
+	providerType string
+	providerName string
+	verifier     string
+	client       *auth.Client
+	createdAt    time.Time
+}
+
+// handleOAuth 处理 OAuth 授权路由：/api/oauth/{provider}/start 和 /api/oauth/{provider}/callback
+func (s *Server) handleOAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/oauth/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "start":
+		s.handleOAuthStart(w, r, parts[0])
+	case "callback":
+		s.handleOAuthCallback(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleOAuthStart 开始授权：读取云盘配置中的 client_id，跳转到授权页面
+func (s *Server) handleOAuthStart(w http.ResponseWriter, r *http.Request, providerType string) {
+	name := r.URL.Query().Get("name")
+
+	providerCfg, ok := s.findProviderConfig(providerType, name)
+
+	if !ok {
+		s.sendError(w, "未找到云盘配置，请先添加并保存云盘的 client_id", http.StatusNotFound)
+		return
+	}
+
+	tokens, err := providerCfg.ResolvedTokens()
+	if err != nil {
+		s.sendError(w, "解析令牌失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := tokens["redirect_uri"]
+	if redirectURL == "" {
+		redirectURL = fmt.Sprintf("%s://%s/api/oauth/%s/callback", requestScheme(r), r.Host, providerType)
+	}
+
+	client, err := auth.NewClient(providerType, tokens, redirectURL)
+	if err != nil {
+		s.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session := &oauthSession{
+		providerType: providerType,
+		providerName: providerCfg.Name,
+		client:       client,
+		createdAt:    time.Now(),
+	}
+	if client.Endpoint.PKCE {
+		session.verifier = auth.NewVerifier()
+	}
+
+	state := auth.NewState()
+
+	s.mu.Lock()
+	s.cleanupOAuthSessions()
+	s.oauthPending[state] = session
+	s.mu.Unlock()
+
+	log.Printf("开始 OAuth 授权: %s (%s)", providerCfg.Name, providerType)
+	http.Redirect(w, r, client.AuthCodeURL(state, session.verifier), http.StatusFound)
+}
+
+// handleOAuthCallback 授权回调：用授权码换取令牌并写入云盘配置
+func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request, providerType string) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	session, ok := s.oauthPending[query.Get("state")]
+	delete(s.oauthPending, query.Get("state"))
+	s.mu.Unlock()
+
+	if !ok || session.providerType != providerType || time.Since(session.createdAt) > oauthSessionTTL {
+		s.sendOAuthPage(w, false, "授权会话无效或已过期，请重新发起授权")
+		return
+	}
+
+	if errMsg := query.Get("error"); errMsg != "" {
+		s.sendOAuthPage(w, false, "授权被拒绝: "+errMsg)
+		return
+	}
+
+	token, err := session.client.Exchange(query.Get("code"), session.verifier)
+	if err != nil {
+		s.sendOAuthPage(w, false, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 写入对应云盘配置并保存
+	current := s.engine.Config()
+	newConfig := *current
+	newConfig.Providers = make([]config.ProviderConfig, len(current.Providers))
+	copy(newConfig.Providers, current.Providers)
+
+	found := false
+	for i, p := range newConfig.Providers {
+		if p.Name != session.providerName || p.Type != providerType {
+			continue
+		}
+		tokens := make(map[string]string, len(p.Tokens))
+		for k, v := range p.Tokens {
+			tokens[k] = v
+		}
+		token.Apply(tokens)
+		newConfig.Providers[i].Tokens = tokens
+		found = true
+	}
+
+	if !found {
+		s.sendOAuthPage(w, false, "云盘配置已被删除: "+session.providerName)
+		return
+	}
+
+	if err := config.SaveConfig(s.configPath, &newConfig); err != nil {
+		s.sendOAuthPage(w, false, "保存配置失败: "+err.Error())
+		return
+	}
+	if err := s.engine.Apply(&newConfig); err != nil {
+		log.Printf("新令牌应用到同步服务失败: %v", err)
+	}
+
+	log.Printf("OAuth 授权成功: %s (%s)", session.providerName, providerType)
+	s.sendOAuthPage(w, true, fmt.Sprintf("「%s」的 Access Token 已保存到配置文件", session.providerName))
+}
+
+// findProviderConfig 按类型和名称查找云盘配置，名称为空时返回该类型的第一个配置
+func (s *Server) findProviderConfig(providerType, name string) (config.ProviderConfig, bool) {
+	for _, p := range s.engine.Config().Providers {
+		if p.Type == providerType && (name == "" || p.Name == name) {
+			return p, true
+		}
+	}
+	return config.ProviderConfig{}, false
+}
+
+// cleanupOAuthSessions 清理过期的授权会话，调用方需持有写锁
+func (s *Server) cleanupOAuthSessions() {
+	for state, session := range s.oauthPending {
+		if time.Since(session.createdAt) > oauthSessionTTL {
+			delete(s.oauthPending, state)
+		}
+	}
+}
+
+// sendOAuthPage 返回授权结果页面
+func (s *Server) sendOAuthPage(w http.ResponseWriter, success bool, message string) {
+	title := "✅ 授权成功"
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if !success {
+		title = "❌ 授权失败"
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	fmt.Fprintf(w, `<html>
+<head><title>%s</title></head>
+<body style="font-family: Arial; padding: 50px; text-align: center;">
+    <h1>%s</h1>
+    <p>%s</p>
+    <p>您可以关闭此页面</p>
+</body>
+</html>`, title, title, html.EscapeString(message))
+}
+
+// requestScheme 获取请求协议
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
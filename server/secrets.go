@@ -0,0 +1,105 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+)
+
+// redactedToken 接口返回的敏感令牌占位符，保存或验证时换回原值
+const redactedToken = "******"
+
+// redactConfig 返回隐藏敏感令牌和登录配置后的配置副本
+func redactConfig(cfg *config.Config) *config.Config {
+	c := *cfg
+	c.Providers = redactProviders(cfg.Providers)
+	c.Web = nil
+	return &c
+}
+
+// redactProviders 返回隐藏敏感令牌后的云盘配置副本，引用（${ENV}、file:、secret:）不含令牌本身，原样返回
+func redactProviders(providers []config.ProviderConfig) []config.ProviderConfig {
+	redacted := make([]config.ProviderConfig, len(providers))
+	for i, p := range providers {
+		tokens := make(map[string]string, len(p.Tokens))
+		for key, value := range p.Tokens {
+			if value != "" && !config.IsTokenReference(value) && config.IsSecretKey(p.Type, key) {
+				value = redactedToken
+			}
+			tokens[key] = value
+		}
+		p.Tokens = tokens
+		redacted[i] = p
+	}
+	return redacted
+}
+
+// restoreTokens 把新配置中的占位符换回当前配置中的原值。按云盘名称匹配，
+// 找不到时（云盘改名）按相同位置的同类型云盘匹配；仍找不到时返回需要重新填写的字段
+func restoreTokens(next, current *config.Config) error {
+	var errs config.ValidationErrors
+	for i, p := range next.Providers {
+		original, ok := findProvider(current, p.Name)
+		if !ok && i < len(current.Providers) && current.Providers[i].Type == p.Type {
+			original, ok = current.Providers[i], true
+		}
+		for _, key := range restoreProviderTokens(p.Tokens, original, ok) {
+			errs.Add(config.ProviderField(i, "tokens."+key), "令牌已隐藏，请重新填写")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// restoreProviderTokens 把 tokens 中的占位符换回 original 中的原值，返回无法恢复的键
+func restoreProviderTokens(tokens map[string]string, original config.ProviderConfig, ok bool) []string {
+	var missing []string
+	for key, value := range tokens {
+		if value != redactedToken {
+			continue
+		}
+		if v := original.Tokens[key]; ok && v != "" {
+			tokens[key] = v
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// findProvider 按名称查找云盘配置
+func findProvider(cfg *config.Config, name string) (config.ProviderConfig, bool) {
+	for _, p := range cfg.Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return config.ProviderConfig{}, false
+}
+
+// lockedProviders 返回 next 中新增或修改过的只能在配置文件中设置的云盘（如 exec）。
+// 通过 Web 界面保存时，这类云盘的类型和令牌必须与当前配置中的同名云盘一致
+func lockedProviders(next, current *config.Config) []string {
+	var names []string
+	for _, p := range next.Providers {
+		if !configFileOnly(p.Type) {
+			continue
+		}
+		if original, ok := findProvider(current, p.Name); !ok || original.Type != p.Type || !reflect.DeepEqual(original.Tokens, p.Tokens) {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// configFileOnly 云盘类型是否只能在配置文件中添加或修改
+func configFileOnly(providerType string) bool {
+	schema, _ := provider.Lookup(providerType)
+	return schema.ConfigFileOnly
+}
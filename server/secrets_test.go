@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"CloudFileSync/config"
+	"CloudFileSync/engine"
+	"CloudFileSync/provider"
+)
+
+// refreshingProvider 验证时轮换令牌的测试云盘
+type refreshingProvider struct {
+	provider.Provider
+	onRefresh func(tokens map[string]string)
+}
+
+func (p *refreshingProvider) Name() string { return "refreshing" }
+
+func (p *refreshingProvider) OnTokenRefresh(fn func(tokens map[string]string)) {
+	p.onRefresh = fn
+}
+
+func (p *refreshingProvider) Verify() (*provider.AccountInfo, error) {
+	if p.onRefresh != nil {
+		p.onRefresh(map[string]string{"refresh_token": "rotated"})
+	}
+	return &provider.AccountInfo{UserName: "test"}, nil
+}
+
+func init() {
+	provider.Register("refreshing", func(tokens map[string]string) (provider.Provider, error) {
+		return &refreshingProvider{}, nil
+	}, provider.Schema{})
+}
+
+func TestRedactAndRestoreTokens(t *testing.T) {
+	current := &config.Config{Providers: []config.ProviderConfig{{
+		Type:   "s3",
+		Name:   "s3",
+		Tokens: map[string]string{"bucket": "b", "secret_key": "sk", "refresh_token": "${RT}"},
+	}}}
+
+	redacted := redactConfig(current)
+	tokens := redacted.Providers[0].Tokens
+	if tokens["secret_key"] != redactedToken || tokens["bucket"] != "b" || tokens["refresh_token"] != "${RT}" {
+		t.Fatalf("隐藏后的 Tokens 为 %v", tokens)
+	}
+	if current.Providers[0].Tokens["secret_key"] != "sk" {
+		t.Fatal("redactConfig 修改了原配置")
+	}
+
+	// 改名后按位置匹配
+	redacted.Providers[0].Name = "renamed"
+	if err := restoreTokens(redacted, current); err != nil {
+		t.Fatal(err)
+	}
+	if tokens["secret_key"] != "sk" {
+		t.Errorf("恢复后的 secret_key 为 %q", tokens["secret_key"])
+	}
+
+	// 新增的云盘没有原值
+	next := &config.Config{Providers: []config.ProviderConfig{current.Providers[0], {
+		Type:   "webdav",
+		Name:   "dav",
+		Tokens: map[string]string{"password": redactedToken},
+	}}}
+	var errs config.ValidationErrors
+	if err := restoreTokens(next, current); !errors.As(err, &errs) || errs[0].Field != "providers[1].tokens.password" {
+		t.Errorf("期望 providers[1].tokens.password 错误，实际为 %v", err)
+	}
+}
+
+func TestVerifyProviderSavesTokensUnderName(t *testing.T) {
+	var saved []string
+	provider.SetTokenSaver(func(name string, tokens map[string]string) error {
+		saved = append(saved, name)
+		return nil
+	})
+	t.Cleanup(func() { provider.SetTokenSaver(nil) })
+
+	cfg := &config.Config{WatchDir: t.TempDir(), Providers: []config.ProviderConfig{{
+		Type:   "refreshing",
+		Name:   "mine",
+		Tokens: map[string]string{"refresh_token": "old"},
+	}}}
+	s := NewServer(engine.New(cfg), t.TempDir()+"/config.json", "127.0.0.1:0")
+	c := &testClient{t: t, handler: s.httpServer.Handler}
+	c.do("GET", "/api/session", "", nil)
+
+	// 已保存的云盘按名称写回刷新后的令牌
+	body := `{"type":"refreshing","name":"mine","tokens":{"refresh_token":"` + redactedToken + `"}}`
+	if code, resp := c.do("POST", "/api/provider/verify", body, nil); code != http.StatusOK {
+		t.Fatalf("验证失败: %d %+v", code, resp)
+	}
+	if len(saved) != 1 || saved[0] != "mine" {
+		t.Fatalf("令牌写回 %v，期望 [mine]", saved)
+	}
+
+	// 尚未保存的云盘不写回
+	saved = nil
+	body = `{"type":"refreshing","name":"new","tokens":{"refresh_token":"typed"}}`
+	if code, resp := c.do("POST", "/api/provider/verify", body, nil); code != http.StatusOK {
+		t.Fatalf("验证失败: %d %+v", code, resp)
+	}
+	if len(saved) != 0 {
+		t.Fatalf("未保存的云盘写回了令牌: %v", saved)
+	}
+}
+
+func TestSaveConfigRejectsExec(t *testing.T) {
+	exec := config.ProviderConfig{
+		Type:   "exec",
+		Name:   "plugin",
+		Enable: true,
+		Tokens: map[string]string{"command": "/usr/local/bin/cfs-plugin-dir"},
+		Target: "/",
+	}
+	cfg := &config.Config{WatchDir: t.TempDir(), Providers: []config.ProviderConfig{exec}}
+	s := NewServer(engine.New(cfg), t.TempDir()+"/config.json", "127.0.0.1:0")
+	c := &testClient{t: t, handler: s.httpServer.Handler}
+	c.do("GET", "/api/session", "", nil)
+
+	save := func(providers ...config.ProviderConfig) int {
+		next := *cfg
+		next.Providers = providers
+		data, _ := json.Marshal(next)
+		code, _ := c.do("POST", "/api/config/save", string(data), nil)
+		return code
+	}
+
+	// 原样保留配置文件中的插件可以保存
+	if code := save(exec); code != http.StatusOK {
+		t.Fatalf("保留已有插件时返回 %d", code)
+	}
+
+	// 修改插件命令或新增插件会在本机执行任意命令，拒绝
+	changed := exec
+	changed.Tokens = map[string]string{"command": "/bin/sh", "args": "-c id"}
+	if code := save(changed); code != http.StatusForbidden {
+		t.Fatalf("修改插件命令时返回 %d，期望 403", code)
+	}
+	added := changed
+	added.Name = "evil"
+	if code := save(exec, added); code != http.StatusForbidden {
+		t.Fatalf("新增插件时返回 %d，期望 403", code)
+	}
+
+	body := `{"type":"exec","name":"new","tokens":{"command":"/bin/sh"}}`
+	if code, _ := c.do("POST", "/api/provider/verify", body, nil); code != http.StatusForbidden {
+		t.Fatalf("验证界面填写的插件时返回 %d，期望 403", code)
+	}
+}
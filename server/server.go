@@ -2,24 +2,37 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 
+	"CloudFileSync/auth"
 	"CloudFileSync/config"
+	"CloudFileSync/engine"
+	"CloudFileSync/events"
+	"CloudFileSync/provider"
+	"CloudFileSync/syncer"
 )
 
 // Server Web 服务器
 type Server struct {
-	config     *config.Config
+	engine     *engine.Engine // 同步引擎，同时持有当前配置
 	configPath string
 	httpServer *http.Server
-	mu         sync.RWMutex
-	// 服务状态
-	isRunning bool
+	mu         sync.RWMutex // 保护授权会话，并保证同一时间只有一个请求修改配置
+	// 进行中的 OAuth 授权（state -> 授权信息）
+	oauthPending map[string]*oauthSession
+
+	sessionMu     sync.Mutex
+	sessions      map[string]*session      // 浏览器会话（会话 ID -> 会话）
+	loginFailures map[string]*loginFailure // 登录失败记录（来源地址 -> 记录），受 sessionMu 保护
+
+	eventLog *events.Log // 同步事件
 }
 
 // Response API 响应
@@ -29,46 +42,82 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// NewServer 创建 Web 服务器
-func NewServer(cfg *config.Config, configPath string, port int) *Server {
+// NewServer 创建 Web 服务器，addr 为监听地址，如 127.0.0.1:8080
+func NewServer(e *engine.Engine, configPath string, addr string) *Server {
 	s := &Server{
-		config:     cfg,
+		engine:     e,
 		configPath: configPath,
-		isRunning:  false,
+
+		oauthPending:  make(map[string]*oauthSession),
+		sessions:      make(map[string]*session),
+		loginFailures: make(map[string]*loginFailure),
+		eventLog:      events.Default,
 	}
 
 	s.httpServer = &http.Server{
-		Addr: fmt.Sprintf(":%d", port),
+		Addr: addr,
 	}
 
 	// 注册路由
-	s.setupRoutes()
+	s.httpServer.Handler = s.protect(s.setupRoutes())
 
 	return s
 }
 
 // setupRoutes 设置路由
-func (s *Server) setupRoutes() {
+func (s *Server) setupRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+
 	// 静态文件路由（必须放在 "/" 之前）
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
-	http.Handle("/tools/", http.StripPrefix("/tools/", http.FileServer(http.Dir("tools"))))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
+	mux.Handle("/tools/", http.StripPrefix("/tools/", http.FileServer(http.Dir("tools"))))
+
+	// 登录
+	mux.HandleFunc("/api/session", s.handleSession)
+	mux.HandleFunc("/api/login", s.handleLogin)
+	mux.HandleFunc("/api/logout", s.handleLogout)
 
 	// API 路由
-	http.HandleFunc("/api/config", s.handleConfig)
-	http.HandleFunc("/api/config/save", s.handleSaveConfig)
-	http.HandleFunc("/api/providers", s.handleProviders)
-	http.HandleFunc("/api/provider/verify", s.handleVerifyProvider)
-	http.HandleFunc("/api/service/status", s.handleServiceStatus)
-	http.HandleFunc("/api/service/start", s.handleStartService)
-	http.HandleFunc("/api/service/stop", s.handleStopService)
+	mux.HandleFunc("/api/config", s.handleConfig)
+	mux.HandleFunc("/api/config/save", s.handleSaveConfig)
+	mux.HandleFunc("/api/providers", s.handleProviders)
+	mux.HandleFunc("/api/provider/verify", s.handleVerifyProvider)
+	mux.HandleFunc("/api/provider/types", s.handleProviderTypes)
+	mux.HandleFunc("/api/service/status", s.handleServiceStatus)
+	mux.HandleFunc("/api/service/start", s.handleStartService)
+	mux.HandleFunc("/api/service/stop", s.handleStopService)
+	mux.HandleFunc("/api/conflicts", s.handleConflicts)
+	mux.HandleFunc("/api/conflicts/resolve", s.handleResolveConflict)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	mux.HandleFunc("/api/events/stream", s.handleEventStream)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/oauth/", s.handleOAuth)
 
 	// 首页路由（必须放在最后，作为默认路由）
-	http.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/", s.handleIndex)
+
+	return mux
 }
 
 // Start 启动服务器
 func (s *Server) Start() error {
-	log.Printf("Web 界面启动成功: http://localhost%s", s.httpServer.Addr)
+	if !isLoopback(s.httpServer.Addr) && !s.credentials().enabled() {
+		log.Printf("警告: Web 界面监听 %s 且未设置登录（配置中的 web.username/web.password 或 web.token），局域网内的任何人都可以修改配置", s.httpServer.Addr)
+	}
+	tlsEnabled := s.httpServer.TLSConfig != nil
+	if !isLoopback(s.httpServer.Addr) && !tlsEnabled {
+		log.Printf("警告: Web 界面监听 %s 且未启用 HTTPS，登录密码和云盘令牌将以明文在网络上传输", s.httpServer.Addr)
+	}
+
+	host := s.httpServer.Addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	if tlsEnabled {
+		log.Printf("Web 界面启动成功，请在浏览器中访问: https://%s", host)
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+	log.Printf("Web 界面启动成功，请在浏览器中访问: http://%s", host)
 	return s.httpServer.ListenAndServe()
 }
 
@@ -94,10 +143,7 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	s.sendSuccess(w, "获取配置成功", s.config)
+	s.sendSuccess(w, "获取配置成功", redactConfig(s.engine.Config()))
 }
 
 // handleSaveConfig 处理配置保存
@@ -114,34 +160,38 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证配置
-	if newConfig.WatchDir == "" {
-		s.sendError(w, "监听目录不能为空", http.StatusBadRequest)
+	// 界面中的敏感令牌是占位符，换回原值；登录配置不在界面中编辑，沿用当前配置
+	newConfig.Web = s.engine.Config().Web
+	if err := restoreTokens(&newConfig, s.engine.Config()); err != nil {
+		s.sendValidationError(w, err)
 		return
 	}
-
-	// 检查目录是否存在
-	if _, err := os.Stat(newConfig.WatchDir); os.IsNotExist(err) {
-		s.sendError(w, "监听目录不存在", http.StatusBadRequest)
+	if names := lockedProviders(&newConfig, s.engine.Config()); len(names) > 0 {
+		s.sendError(w, "以下云盘只能在配置文件中添加或修改: "+strings.Join(names, "、"), http.StatusForbidden)
 		return
 	}
 
-	// 保存到文件
-	data, err := json.MarshalIndent(newConfig, "", "  ")
-	if err != nil {
-		s.sendError(w, "序列化配置失败: "+err.Error(), http.StatusInternalServerError)
+	// 验证配置，逐字段返回错误供界面标出
+	if err := newConfig.Validate(); err != nil {
+		s.sendValidationError(w, err)
 		return
 	}
 
-	err = os.WriteFile(s.configPath, data, 0644)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 保存到文件
+	err = config.SaveConfig(s.configPath, &newConfig)
 	if err != nil {
 		s.sendError(w, "保存配置失败: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	s.mu.Lock()
-	s.config = &newConfig
-	s.mu.Unlock()
+	// 同步服务运行中时立即生效
+	if err := s.engine.Apply(&newConfig); err != nil {
+		s.sendError(w, "配置已保存，但应用到同步服务失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	s.sendSuccess(w, "配置保存成功", nil)
 }
@@ -153,10 +203,31 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sendSuccess(w, "获取提供商列表成功", redactProviders(s.engine.Config().Providers))
+}
+
+// providerType 提供商类型及其配置描述
+type providerType struct {
+	Type string `json:"type"`
+	provider.Schema
+	OAuth bool `json:"oauth"` // 是否支持 OAuth 授权
+}
+
+// handleProviderTypes 返回已注册的提供商类型，Web 界面据此生成配置表单
+func (s *Server) handleProviderTypes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var types []providerType
+	for _, t := range provider.Types() {
+		schema, _ := provider.Lookup(t)
+		_, oauth := auth.Endpoints[t]
+		types = append(types, providerType{Type: t, Schema: schema, OAuth: oauth})
+	}
 
-	s.sendSuccess(w, "获取提供商列表成功", s.config.Providers)
+	s.sendSuccess(w, "获取提供商类型成功", types)
 }
 
 // handleVerifyProvider 处理提供商验证
@@ -168,6 +239,7 @@ func (s *Server) handleVerifyProvider(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		Type   string            `json:"type"`
+		Name   string            `json:"name"` // 编辑已有云盘时为原名称，用于换回隐藏的令牌
 		Tokens map[string]string `json:"tokens"`
 	}
 
@@ -177,31 +249,48 @@ func (s *Server) handleVerifyProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 这里可以添加实际的验证逻辑
-	// 现在简单检查 token 是否存在
-	valid := false
-	message := "验证失败"
+	original, ok := findProvider(s.engine.Config(), req.Name)
+	existing := ok && original.Type == req.Type
+	if missing := restoreProviderTokens(req.Tokens, original, existing); len(missing) > 0 {
+		s.sendError(w, "令牌已隐藏，请重新填写: "+strings.Join(missing, "、"), http.StatusBadRequest)
+		return
+	}
+	// 只能在配置文件中设置的云盘只能验证已保存的配置，不能用界面填写的参数创建
+	if configFileOnly(req.Type) && (!existing || !reflect.DeepEqual(req.Tokens, original.Tokens)) {
+		s.sendError(w, "该类型的云盘只能在配置文件中添加或修改", http.StatusForbidden)
+		return
+	}
 
-	switch req.Type {
-	case "aliyun":
-		if req.Tokens["access_token"] != "" {
-			valid = true
-			message = "验证成功"
-		}
-	case "baidu":
-		if req.Tokens["access_token"] != "" {
-			valid = true
-			message = "验证成功"
-		}
-	default:
-		message = "不支持的提供商类型"
+	pvd, err := provider.NewProvider(config.ProviderConfig{
+		Type:   req.Type,
+		Name:   req.Name,
+		Tokens: req.Tokens,
+	})
+	if err != nil {
+		s.sendError(w, "验证失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer provider.Close(pvd)
+
+	// 验证时刷新的令牌只有对应已保存的云盘时才写回配置，尚未保存的云盘没有可写入的配置
+	if refresher, ok := pvd.(provider.TokenRefresher); ok && !existing {
+		refresher.OnTokenRefresh(nil)
+	}
+
+	verifier, ok := pvd.(provider.Verifier)
+	if !ok {
+		s.sendError(w, "暂不支持此云盘类型的验证", http.StatusBadRequest)
+		return
 	}
 
-	if valid {
-		s.sendSuccess(w, message, nil)
-	} else {
-		s.sendError(w, message, http.StatusBadRequest)
+	// 调用云盘接口校验凭证并获取账号信息
+	info, err := verifier.Verify()
+	if err != nil {
+		s.sendError(w, "验证失败: "+err.Error(), http.StatusBadRequest)
+		return
 	}
+
+	s.sendSuccess(w, "验证成功", info)
 }
 
 // handleServiceStatus 处理服务状态
@@ -211,12 +300,9 @@ func (s *Server) handleServiceStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	status := map[string]interface{}{
-		"running": s.isRunning,
-		"watchDir": s.config.WatchDir,
+		"running":  s.engine.Running(),
+		"watchDir": s.engine.Config().WatchDir,
 	}
 
 	s.sendSuccess(w, "获取服务状态成功", status)
@@ -229,15 +315,19 @@ func (s *Server) handleStartService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.isRunning {
-		s.sendError(w, "服务已在运行", http.StatusConflict)
+	if err := s.engine.Start(); err != nil {
+		var invalid config.ValidationErrors
+		switch {
+		case errors.Is(err, engine.ErrRunning):
+			s.sendError(w, "服务已在运行", http.StatusConflict)
+		case errors.As(err, &invalid):
+			s.sendValidationError(w, err)
+		default:
+			s.sendError(w, "服务启动失败: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	s.isRunning = true
 	s.sendSuccess(w, "服务启动成功", nil)
 }
 
@@ -248,18 +338,67 @@ func (s *Server) handleStopService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !s.isRunning {
+	if err := s.engine.Stop(); err != nil {
 		s.sendError(w, "服务未运行", http.StatusConflict)
 		return
 	}
 
-	s.isRunning = false
 	s.sendSuccess(w, "服务停止成功", nil)
 }
 
+// handleConflicts 处理待处理冲突列表
+func (s *Server) handleConflicts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, err := s.engine.State()
+	if err != nil {
+		s.sendError(w, "加载同步状态失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.sendSuccess(w, "获取冲突列表成功", state.Conflicts())
+}
+
+// handleResolveConflict 处理手动解决冲突
+func (s *Server) handleResolveConflict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		Strategy string `json:"strategy"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		s.sendError(w, "解析请求失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	strategy, err := syncer.ParseStrategy(req.Strategy)
+	if err != nil || req.Strategy == "" {
+		s.sendError(w, "无效的冲突处理方式", http.StatusBadRequest)
+		return
+	}
+
+	// 通过同步引擎处理，运行中与文件事件的同步共用同一个同步器，避免两份状态互相覆盖
+	if err := s.engine.ResolveConflict(req.ID, strategy); err != nil {
+		if errors.Is(err, engine.ErrConflictNotFound) {
+			s.sendError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.sendError(w, "处理冲突失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.sendSuccess(w, "冲突已处理", nil)
+}
+
 // sendSuccess 发送成功响应
 func (s *Server) sendSuccess(w http.ResponseWriter, message string, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -280,6 +419,23 @@ func (s *Server) sendError(w http.ResponseWriter, message string, statusCode int
 	})
 }
 
+// sendValidationError 发送配置校验错误，Data 为 config.ValidationErrors
+func (s *Server) sendValidationError(w http.ResponseWriter, err error) {
+	var errs config.ValidationErrors
+	if !errors.As(err, &errs) {
+		s.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(Response{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("配置有 %d 处错误，请检查标出的字段", len(errs)),
+		Data:    errs,
+	})
+}
+
 // GetConfigDir 获取配置文件所在目录
 func (s *Server) GetConfigDir() string {
 	return filepath.Dir(s.configPath)
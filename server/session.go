@@ -0,0 +1,455 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"CloudFileSync/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// sessionCookie 登录会话 Cookie 名称
+	sessionCookie = "cfs_session"
+	// csrfHeader 非 GET 请求携带 CSRF 令牌的请求头
+	csrfHeader = "X-CSRF-Token"
+	// sessionTTL 登录会话有效期
+	sessionTTL = 24 * time.Hour
+	// maxSessions 会话数量上限。未启用登录时每个不带 Cookie 的请求都会创建会话，超过上限后淘汰最早创建的会话
+	maxSessions = 1000
+	// maxLoginFailures 同一来源在 loginFailureWindow 内允许的登录失败次数（密码和访问令牌合计），超过后拒绝登录直到窗口结束
+	maxLoginFailures   = 5
+	loginFailureWindow = 15 * time.Minute
+)
+
+// session 浏览器会话。未启用登录时也会创建，用于 CSRF 校验
+type session struct {
+	csrfToken string
+	authKey   string // 创建会话时的登录配置摘要，登录配置修改后旧会话失效
+	expiresAt time.Time
+}
+
+// loginFailure 来源地址的登录失败记录
+type loginFailure struct {
+	count   int
+	resetAt time.Time // 计数清零的时间
+}
+
+// credentials 解析引用后的登录配置
+type credentials struct {
+	username string
+	password string
+	token    string
+}
+
+// enabled 是否需要登录
+func (c credentials) enabled() bool {
+	return c.password != "" || c.token != ""
+}
+
+// key 登录配置摘要，未启用登录时为空
+func (c credentials) key() string {
+	if !c.enabled() {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(c.username + "\x00" + c.password + "\x00" + c.token))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkPassword 校验用户名和密码，密码为 bcrypt 哈希时按哈希比较
+func (c credentials) checkPassword(username, password string) bool {
+	if c.password == "" || !secureEqual(username, c.username) {
+		return false
+	}
+	if isBcryptHash(c.password) {
+		return bcrypt.CompareHashAndPassword([]byte(c.password), []byte(password)) == nil
+	}
+	return secureEqual(password, c.password)
+}
+
+// checkToken 校验访问令牌
+func (c credentials) checkToken(token string) bool {
+	return c.token != "" && secureEqual(token, c.token)
+}
+
+// isBcryptHash 是否为 bcrypt 哈希
+func isBcryptHash(s string) bool {
+	return strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$")
+}
+
+// secureEqual 以固定时间比较字符串
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// credentials 读取当前配置中的登录配置。引用无法解析时视为未设置该项，配置校验会报告错误
+func (s *Server) credentials() credentials {
+	web := s.engine.Config().Web
+	if web == nil {
+		return credentials{}
+	}
+
+	c := credentials{username: web.Username}
+	if v, err := config.ResolveToken(web.Password); err == nil {
+		c.password = v
+	} else {
+		log.Printf("解析 Web 登录密码失败: %v", err)
+	}
+	if v, err := config.ResolveToken(web.Token); err == nil {
+		c.token = v
+	} else {
+		log.Printf("解析 Web 访问令牌失败: %v", err)
+	}
+	return c
+}
+
+// newSessionToken 生成会话 ID 和 CSRF 令牌
+func newSessionToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newSession 创建会话并写入 Cookie
+func (s *Server) newSession(w http.ResponseWriter, r *http.Request, authKey string) (*session, error) {
+	id, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+	sess := &session{csrfToken: csrf, authKey: authKey, expiresAt: time.Now().Add(sessionTTL)}
+
+	s.sessionMu.Lock()
+	for id, old := range s.sessions {
+		if time.Now().After(old.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	for len(s.sessions) >= maxSessions {
+		s.evictOldestSession()
+	}
+	s.sessions[id] = sess
+	s.sessionMu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(sessionTTL / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// OAuth 回调是从授权页面跳转回来的，Strict 会丢失 Cookie
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sess, nil
+}
+
+// evictOldestSession 删除最早创建的会话，调用方需持有 sessionMu
+func (s *Server) evictOldestSession() {
+	var oldestID string
+	var oldest *session
+	for id, sess := range s.sessions {
+		if oldest == nil || sess.expiresAt.Before(oldest.expiresAt) {
+			oldestID, oldest = id, sess
+		}
+	}
+	delete(s.sessions, oldestID)
+}
+
+// lookupSession 查找请求对应的有效会话，登录配置修改后旧会话失效
+func (s *Server) lookupSession(r *http.Request, authKey string) *session {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	sess, ok := s.sessions[cookie.Value]
+	if !ok || sess.authKey != authKey || time.Now().After(sess.expiresAt) {
+		return nil
+	}
+	return sess
+}
+
+// dropSession 删除请求对应的会话
+func (s *Server) dropSession(r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		s.sessionMu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.sessionMu.Unlock()
+	}
+}
+
+// deleteSession 删除会话并清除 Cookie
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request) {
+	s.dropSession(r)
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+}
+
+// loginBlocked 请求来源的登录失败次数是否已达上限，返回剩余等待时间
+func (s *Server) loginBlocked(r *http.Request) (time.Duration, bool) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	f, ok := s.loginFailures[remoteHost(r)]
+	if !ok || f.count < maxLoginFailures {
+		return 0, false
+	}
+	wait := time.Until(f.resetAt)
+	return wait, wait > 0
+}
+
+// recordLoginFailure 记录一次登录失败，同时清理已过期的记录
+func (s *Server) recordLoginFailure(r *http.Request) {
+	host := remoteHost(r)
+	now := time.Now()
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	for h, f := range s.loginFailures {
+		if now.After(f.resetAt) {
+			delete(s.loginFailures, h)
+		}
+	}
+
+	f, ok := s.loginFailures[host]
+	if !ok {
+		f = &loginFailure{resetAt: now.Add(loginFailureWindow)}
+		s.loginFailures[host] = f
+	}
+	f.count++
+	if f.count == maxLoginFailures {
+		log.Printf("Web 登录失败次数过多，%s 内拒绝来自 %s 的登录", loginFailureWindow, host)
+	}
+}
+
+// resetLoginFailures 登录成功后清除来源地址的失败记录
+func (s *Server) resetLoginFailures(r *http.Request) {
+	s.sessionMu.Lock()
+	delete(s.loginFailures, remoteHost(r))
+	s.sessionMu.Unlock()
+}
+
+// sendLoginBlocked 返回登录失败次数过多的错误
+func (s *Server) sendLoginBlocked(w http.ResponseWriter, wait time.Duration) {
+	minutes := int(wait/time.Minute) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+	s.sendError(w, fmt.Sprintf("登录失败次数过多，请 %d 分钟后再试", minutes), http.StatusTooManyRequests)
+}
+
+// allowedHost 未启用登录时只接受以本机名称、IP 地址或监听地址访问的请求，
+// 防止 DNS 重绑定：其他网站把自己的域名解析到 127.0.0.1 后，浏览器会把页面中的请求发往本服务
+func (s *Server) allowedHost(r *http.Request) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+
+	// DNS 重绑定需要域名，直接使用 IP 地址访问是安全的
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || net.ParseIP(host) != nil {
+		return true
+	}
+	if listen, _, err := net.SplitHostPort(s.httpServer.Addr); err == nil && strings.EqualFold(host, listen) {
+		return true
+	}
+	if name, err := os.Hostname(); err == nil && strings.EqualFold(host, name) {
+		return true
+	}
+	return false
+}
+
+// bearerToken 读取 Authorization: Bearer 请求头中的令牌
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return header[len(prefix):]
+	}
+	return ""
+}
+
+// publicAPI 不需要登录的接口
+var publicAPI = map[string]bool{
+	"/api/session": true,
+	"/api/login":   true,
+}
+
+// protect 为 API 添加登录和 CSRF 校验。页面和静态文件不需要登录，登录页本身就是首页的一部分。
+// 带 Authorization: Bearer 访问令牌的请求不使用 Cookie，不需要 CSRF 令牌
+func (s *Server) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds := s.credentials()
+		if !creds.enabled() && !s.allowedHost(r) {
+			log.Printf("拒绝 Host 为 %s 的请求: %s", r.Host, remoteHost(r))
+			s.sendError(w, "未启用登录时只能通过本机地址访问", http.StatusForbidden)
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, "/api/") || publicAPI[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token := bearerToken(r); token != "" {
+			if wait, blocked := s.loginBlocked(r); blocked {
+				s.sendLoginBlocked(w, wait)
+				return
+			}
+			if !creds.checkToken(token) {
+				s.recordLoginFailure(r)
+				s.sendError(w, "访问令牌无效", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sess := s.lookupSession(r, creds.key())
+		if creds.enabled() && sess == nil {
+			s.sendError(w, "未登录或登录已过期", http.StatusUnauthorized)
+			return
+		}
+
+		// 未启用登录时同样校验 CSRF，防止其他网站借助浏览器修改本机配置
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if sess == nil || !secureEqual(r.Header.Get(csrfHeader), sess.csrfToken) {
+				s.sendError(w, "CSRF 校验失败，请刷新页面后重试", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionInfo 会话状态
+type sessionInfo struct {
+	AuthRequired  bool   `json:"auth_required"`
+	Authenticated bool   `json:"authenticated"`
+	CSRFToken     string `json:"csrf_token,omitempty"`
+}
+
+// handleSession 返回会话状态。未启用登录时直接创建会话，供页面获取 CSRF 令牌
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	creds := s.credentials()
+	sess := s.lookupSession(r, creds.key())
+	if sess == nil && !creds.enabled() {
+		var err error
+		if sess, err = s.newSession(w, r, ""); err != nil {
+			s.sendError(w, "创建会话失败: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	info := sessionInfo{AuthRequired: creds.enabled(), Authenticated: sess != nil}
+	if sess != nil {
+		info.CSRFToken = sess.csrfToken
+	}
+	s.sendSuccess(w, "获取会话成功", info)
+}
+
+// handleLogin 使用用户名和密码或访问令牌登录
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Token    string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, "解析请求失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	creds := s.credentials()
+	if creds.enabled() {
+		// 达到失败次数上限后不再校验，正确的密码也无法登录，避免逐个尝试
+		if wait, blocked := s.loginBlocked(r); blocked {
+			s.sendLoginBlocked(w, wait)
+			return
+		}
+		if !creds.checkToken(req.Token) && !creds.checkPassword(req.Username, req.Password) {
+			log.Printf("Web 登录失败: %s", remoteHost(r))
+			s.recordLoginFailure(r)
+			s.sendError(w, "用户名、密码或访问令牌错误", http.StatusUnauthorized)
+			return
+		}
+		s.resetLoginFailures(r)
+	}
+
+	// 登录后更换会话 ID
+	s.dropSession(r)
+	sess, err := s.newSession(w, r, creds.key())
+	if err != nil {
+		s.sendError(w, "创建会话失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.sendSuccess(w, "登录成功", sessionInfo{
+		AuthRequired:  creds.enabled(),
+		Authenticated: true,
+		CSRFToken:     sess.csrfToken,
+	})
+}
+
+// handleLogout 退出登录
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.deleteSession(w, r)
+	s.sendSuccess(w, "已退出登录", nil)
+}
+
+// remoteHost 请求来源地址（不含端口）
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isLoopback 监听地址是否仅限本机访问
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"CloudFileSync/config"
+	"CloudFileSync/engine"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testClient 保存 Cookie 和 CSRF 令牌的测试客户端
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+	cookies []*http.Cookie
+	csrf    string
+}
+
+// do 发送请求，返回状态码和响应
+func (c *testClient) do(method, path, body string, header http.Header) (int, Response) {
+	c.t.Helper()
+
+	// httptest 默认的 Host 为 example.com，未启用登录时会被拒绝
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = "localhost:8080"
+	if host := header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.csrf != "" {
+		req.Header.Set(csrfHeader, c.csrf)
+	}
+
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	if cookies := rec.Result().Cookies(); len(cookies) > 0 {
+		c.cookies = cookies
+	}
+
+	var resp Response
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if info, ok := resp.Data.(map[string]interface{}); ok {
+		if token, ok := info["csrf_token"].(string); ok {
+			c.csrf = token
+		}
+	}
+	return rec.Code, resp
+}
+
+func newTestServer(t *testing.T, web *config.WebConfig) (*Server, *testClient) {
+	cfg := &config.Config{WatchDir: t.TempDir(), Web: web}
+	s := NewServer(engine.New(cfg), t.TempDir()+"/config.json", "127.0.0.1:0")
+	return s, &testClient{t: t, handler: s.httpServer.Handler}
+}
+
+func TestCSRFWithoutLogin(t *testing.T) {
+	_, c := newTestServer(t, nil)
+
+	if code, _ := c.do("GET", "/api/config", "", nil); code != http.StatusOK {
+		t.Fatalf("未启用登录时 GET 返回 %d", code)
+	}
+	if code, _ := c.do("POST", "/api/service/stop", "", nil); code != http.StatusForbidden {
+		t.Fatalf("缺少 CSRF 令牌时返回 %d，期望 403", code)
+	}
+
+	code, resp := c.do("GET", "/api/session", "", nil)
+	if code != http.StatusOK || c.csrf == "" {
+		t.Fatalf("获取会话失败: %d %+v", code, resp)
+	}
+
+	// 带上 CSRF 令牌后通过校验，服务未运行返回 409
+	if code, resp := c.do("POST", "/api/service/stop", "", nil); code != http.StatusConflict {
+		t.Fatalf("带 CSRF 令牌时返回 %d %+v", code, resp)
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+	s, c := newTestServer(t, nil)
+	if code, _ := c.do("GET", "/api/session", "", nil); code != http.StatusOK {
+		t.Fatalf("获取会话失败: %d", code)
+	}
+
+	// 不带 Cookie 的请求每次都会创建会话，数量不超过上限
+	for i := 0; i < maxSessions+10; i++ {
+		other := &testClient{t: t, handler: c.handler}
+		if code, _ := other.do("GET", "/api/session", "", nil); code != http.StatusOK {
+			t.Fatalf("获取会话失败: %d", code)
+		}
+	}
+	if n := len(s.sessions); n != maxSessions {
+		t.Errorf("会话数量 %d，期望 %d", n, maxSessions)
+	}
+
+	// 最早的会话已被淘汰，需要重新获取 CSRF 令牌
+	if code, _ := c.do("POST", "/api/service/stop", "", nil); code != http.StatusForbidden {
+		t.Fatalf("会话淘汰后返回 %d，期望 403", code)
+	}
+	c.do("GET", "/api/session", "", nil)
+	if code, _ := c.do("POST", "/api/service/stop", "", nil); code != http.StatusConflict {
+		t.Fatalf("重新获取会话后返回 %d，期望 409", code)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	s, c := newTestServer(t, &config.WebConfig{Username: "admin", Password: string(hash), Token: "tok"})
+
+	if code, _ := c.do("GET", "/api/config", "", nil); code != http.StatusUnauthorized {
+		t.Fatalf("未登录时返回 %d，期望 401", code)
+	}
+	if code, _ := c.do("POST", "/api/login", `{"username":"admin","password":"wrong"}`, nil); code != http.StatusUnauthorized {
+		t.Fatalf("密码错误时返回 %d，期望 401", code)
+	}
+	if code, resp := c.do("POST", "/api/login", `{"username":"admin","password":"pass"}`, nil); code != http.StatusOK {
+		t.Fatalf("登录失败: %d %+v", code, resp)
+	}
+
+	code, resp := c.do("GET", "/api/config", "", nil)
+	if code != http.StatusOK {
+		t.Fatalf("登录后返回 %d", code)
+	}
+	if data, _ := json.Marshal(resp.Data); strings.Contains(string(data), `"web"`) {
+		t.Errorf("接口返回了登录配置: %s", data)
+	}
+
+	csrf := c.csrf
+	c.csrf = ""
+	if code, _ := c.do("POST", "/api/service/stop", "", nil); code != http.StatusForbidden {
+		t.Fatalf("缺少 CSRF 令牌时返回 %d，期望 403", code)
+	}
+	c.csrf = csrf
+
+	// 访问令牌不需要 Cookie 和 CSRF 令牌
+	bearer := &testClient{t: t, handler: c.handler}
+	if code, _ := bearer.do("POST", "/api/service/stop", "", http.Header{"Authorization": {"Bearer tok"}}); code != http.StatusConflict {
+		t.Fatalf("使用访问令牌时返回 %d", code)
+	}
+	if code, _ := bearer.do("GET", "/api/config", "", http.Header{"Authorization": {"Bearer bad"}}); code != http.StatusUnauthorized {
+		t.Fatalf("访问令牌错误时返回 %d，期望 401", code)
+	}
+
+	// 修改登录配置后旧会话失效
+	next := *s.engine.Config()
+	next.Web = &config.WebConfig{Username: "admin", Password: "new"}
+	s.engine.Apply(&next)
+	if code, _ := c.do("GET", "/api/config", "", nil); code != http.StatusUnauthorized {
+		t.Fatalf("修改登录配置后返回 %d，期望 401", code)
+	}
+}
+
+func TestHostCheck(t *testing.T) {
+	_, c := newTestServer(t, nil)
+
+	for _, host := range []string{"localhost:8080", "127.0.0.1:8080", "[::1]:8080", "192.168.1.2"} {
+		if code, _ := c.do("GET", "/api/config", "", http.Header{"Host": {host}}); code != http.StatusOK {
+			t.Errorf("Host 为 %s 时返回 %d", host, code)
+		}
+	}
+
+	// DNS 重绑定：其他域名解析到本机
+	if code, _ := c.do("GET", "/api/config", "", http.Header{"Host": {"evil.example.com:8080"}}); code != http.StatusForbidden {
+		t.Errorf("未启用登录时其他域名返回 %d，期望 403", code)
+	}
+	if code, _ := c.do("GET", "/", "", http.Header{"Host": {"evil.example.com"}}); code != http.StatusForbidden {
+		t.Errorf("未启用登录时其他域名访问首页返回 %d，期望 403", code)
+	}
+
+	// 启用登录后可以通过域名访问
+	_, c = newTestServer(t, &config.WebConfig{Token: "tok"})
+	if code, _ := c.do("GET", "/api/config", "", http.Header{"Host": {"nas.example.com"}, "Authorization": {"Bearer tok"}}); code != http.StatusOK {
+		t.Errorf("启用登录时通过域名访问返回 %d", code)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	_, c := newTestServer(t, &config.WebConfig{Username: "admin", Password: "pass", Token: "tok"})
+
+	for i := 0; i < maxLoginFailures-1; i++ {
+		if code, _ := c.do("POST", "/api/login", `{"username":"admin","password":"wrong"}`, nil); code != http.StatusUnauthorized {
+			t.Fatalf("第 %d 次密码错误时返回 %d", i+1, code)
+		}
+	}
+	// 错误的访问令牌同样计入失败次数
+	if code, _ := c.do("GET", "/api/config", "", http.Header{"Authorization": {"Bearer bad"}}); code != http.StatusUnauthorized {
+		t.Fatalf("访问令牌错误时返回 %d", code)
+	}
+
+	// 达到上限后正确的密码和令牌也被拒绝
+	if code, _ := c.do("POST", "/api/login", `{"username":"admin","password":"pass"}`, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("失败次数过多后登录返回 %d，期望 429", code)
+	}
+	if code, _ := c.do("GET", "/api/config", "", http.Header{"Authorization": {"Bearer tok"}}); code != http.StatusTooManyRequests {
+		t.Fatalf("失败次数过多后使用访问令牌返回 %d，期望 429", code)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"192.168.1.2:80": false,
+	}
+	for addr, want := range cases {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v，期望 %v", addr, got, want)
+		}
+	}
+}
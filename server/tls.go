@@ -0,0 +1,167 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// selfSignedCertFile、selfSignedKeyFile 自签名证书和私钥，保存在配置文件所在目录
+	selfSignedCertFile = "web-cert.pem"
+	selfSignedKeyFile  = "web-key.pem"
+
+	// selfSignedValidity 自签名证书有效期。部分系统不接受有效期超过 825 天的证书
+	selfSignedValidity = 825 * 24 * time.Hour
+	// selfSignedRenewBefore 自签名证书到期前多久重新生成
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// EnableTLS 使用证书和私钥文件（PEM 格式）启用 HTTPS
+func (s *Server) EnableTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+
+	s.httpServer.TLSConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	log.Printf("已启用 HTTPS，证书: %s", certFile)
+	return nil
+}
+
+// EnableSelfSignedTLS 使用自签名证书启用 HTTPS。证书保存在配置文件所在目录，
+// 首次运行或证书即将到期时重新生成，浏览器会提示证书不受信任，请核对打印的指纹
+func (s *Server) EnableSelfSignedTLS() error {
+	dir := s.GetConfigDir()
+	certFile := filepath.Join(dir, selfSignedCertFile)
+	keyFile := filepath.Join(dir, selfSignedKeyFile)
+
+	cert, err := loadCertificate(certFile)
+	if err != nil || time.Until(cert.NotAfter) < selfSignedRenewBefore {
+		if err := generateSelfSignedCert(certFile, keyFile); err != nil {
+			return fmt.Errorf("生成自签名证书失败: %w", err)
+		}
+		if cert, err = loadCertificate(certFile); err != nil {
+			return err
+		}
+		log.Printf("已生成自签名证书: %s", certFile)
+	}
+
+	if err := s.EnableTLS(certFile, keyFile); err != nil {
+		return err
+	}
+	log.Printf("自签名证书 SHA-256 指纹: %s", fingerprint(cert.Raw))
+	log.Printf("证书包含的地址: %s", strings.Join(certificateNames(cert), ", "))
+	return nil
+}
+
+// loadCertificate 读取 PEM 格式证书文件中的第一个证书
+func loadCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s 不是 PEM 格式的证书", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// generateSelfSignedCert 生成 ECDSA P-256 自签名证书，包含 localhost、主机名和本机所有 IP 地址
+func generateSelfSignedCert(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	dnsNames := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		dnsNames = append(dnsNames, hostname)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "CloudFileSync", Organization: []string{"CloudFileSync"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           localIPs(),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// 先写私钥，证书存在即表示私钥已就绪
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// localIPs 本机所有 IP 地址，包括回环地址
+func localIPs() []net.IP {
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	return ips
+}
+
+// certificateNames 证书包含的域名和 IP 地址
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// fingerprint 证书的 SHA-256 指纹，格式与浏览器中显示的一致
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
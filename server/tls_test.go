@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"CloudFileSync/config"
+	"CloudFileSync/engine"
+)
+
+func TestSelfSignedTLS(t *testing.T) {
+	dir := t.TempDir()
+	s := NewServer(engine.New(&config.Config{WatchDir: dir}), filepath.Join(dir, "config.json"), "127.0.0.1:0")
+
+	if err := s.EnableSelfSignedTLS(); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := loadCertificate(filepath.Join(dir, selfSignedCertFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("localhost"); err != nil {
+		t.Errorf("证书不包含 localhost: %v", err)
+	}
+	if err := cert.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("证书不包含 127.0.0.1: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, selfSignedKeyFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("私钥文件权限不是 0600: %v", err)
+	}
+
+	// 再次启用时沿用已有证书
+	if err := s.EnableSelfSignedTLS(); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := loadCertificate(filepath.Join(dir, selfSignedCertFile))
+	if !again.Equal(cert) {
+		t.Error("重新生成了未到期的证书")
+	}
+
+	// 使用生成的证书提供 HTTPS
+	ts := httptest.NewUnstartedServer(s.httpServer.Handler)
+	ts.TLS = s.httpServer.TLSConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get(ts.URL + "/api/session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	cookies := resp.Cookies()
+	if len(cookies) == 0 || !cookies[0].Secure {
+		t.Errorf("HTTPS 下的会话 Cookie 应设置 Secure: %v", cookies)
+	}
+}
+
+func TestEnableTLSMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := NewServer(engine.New(&config.Config{WatchDir: dir}), filepath.Join(dir, "config.json"), "127.0.0.1:0")
+
+	if err := s.EnableTLS(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")); err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if s.httpServer.TLSConfig != nil {
+		t.Error("加载证书失败时启用了 HTTPS")
+	}
+}
@@ -0,0 +1,63 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"CloudFileSync/config"
+)
+
+// Strategy 冲突处理策略
+type Strategy string
+
+const (
+	StrategyNewest   Strategy = "newest"    // 修改时间较新的一方获胜
+	StrategyLocal    Strategy = "local"     // 本地版本获胜
+	StrategyRemote   Strategy = "remote"    // 远程版本获胜
+	StrategyKeepBoth Strategy = "keep_both" // 两个版本都保留，本地版本重命名为冲突副本
+	StrategyManual   Strategy = "manual"    // 记录冲突，等待在 Web 界面中手动处理
+)
+
+// DefaultStrategy 默认冲突处理策略，不会丢失任何一方的修改
+const DefaultStrategy = StrategyKeepBoth
+
+// ParseStrategy 解析冲突处理策略，空字符串返回默认策略
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "":
+		return DefaultStrategy, nil
+	case StrategyNewest, StrategyLocal, StrategyRemote, StrategyKeepBoth, StrategyManual:
+		return Strategy(s), nil
+	default:
+		return "", fmt.Errorf("不支持的冲突处理策略: %s", s)
+	}
+}
+
+func init() {
+	config.RegisterValidator(func(c *config.Config) config.ValidationErrors {
+		var errs config.ValidationErrors
+		if _, err := ParseStrategy(c.ConflictStrategy); err != nil {
+			errs.Add("conflict_strategy", err.Error())
+		}
+		return errs
+	})
+}
+
+// ConflictName 生成冲突副本文件名，例如 report (conflict myhost 2006-01-02).docx
+func ConflictName(path, host string, t time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	return fmt.Sprintf("%s (conflict %s %s)%s", base, host, t.Format("2006-01-02"), ext)
+}
+
+// conflictPath 生成本地不存在的冲突副本路径，同一天已有冲突副本时附加具体时间
+func (s *Syncer) conflictPath(localPath string, t time.Time) string {
+	path := ConflictName(localPath, s.hostname, t)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	return ConflictName(localPath, s.hostname+" "+t.Format("150405"), t)
+}
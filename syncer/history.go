@@ -0,0 +1,46 @@
+package syncer
+
+import (
+	"log"
+	"time"
+
+	"CloudFileSync/events"
+	"CloudFileSync/history"
+)
+
+// addHistory 追加一条同步历史，写入失败只记录日志，不影响同步
+func (s *Syncer) addHistory(t Target, r history.Record) {
+	r.Time = time.Now()
+	r.Provider = t.Config.Name
+	if err := s.history.Append(r); err != nil {
+		log.Printf("[%s] 记录同步历史失败: %v", t.Provider.Name(), err)
+	}
+}
+
+// succeed 记录一次成功的同步并发布完成事件。先写入历史，收到事件的界面刷新时可以查到这条记录
+func (s *Syncer) succeed(t Target, action ActionType, rel string, start time.Time, bytes int64, message string) {
+	s.addHistory(t, history.Record{
+		Path:     rel,
+		Action:   string(action),
+		Bytes:    bytes,
+		Duration: time.Since(start).Milliseconds(),
+		Result:   history.ResultSuccess,
+	})
+	s.publish(t, events.KindDone, action, rel, message)
+}
+
+// fail err 不为 nil 时记录一次失败的同步并发布失败事件，返回 err
+func (s *Syncer) fail(t Target, action ActionType, rel string, start time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+	s.addHistory(t, history.Record{
+		Path:     rel,
+		Action:   string(action),
+		Duration: time.Since(start).Milliseconds(),
+		Result:   history.ResultFailed,
+		Error:    err.Error(),
+	})
+	s.publish(t, events.KindFailed, action, rel, err.Error())
+	return err
+}
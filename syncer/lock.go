@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"errors"
+	"sync"
+)
+
+// pathLocks 按本地路径加的读写锁，所有同步器共用。
+// 同一文件在各云盘上的同步持有读锁并行进行；冲突处理可能重命名或替换本地文件，需要持有写锁，
+// 避免其他云盘此时读取到被替换了一半的文件，或一个云盘同步到冲突副本而另一个同步到原文件
+var pathLocks = struct {
+	sync.Mutex
+	m map[string]*pathLock
+}{m: make(map[string]*pathLock)}
+
+// pathLock 一个路径的锁及其使用者数量，没有使用者时从 pathLocks 中删除
+type pathLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// errNeedExclusive 冲突处理需要修改本地文件，但当前只持有读锁
+var errNeedExclusive = errors.New("冲突处理需要独占本地文件")
+
+// acquirePath 获取路径的锁并增加使用者数量
+func acquirePath(path string) *pathLock {
+	pathLocks.Lock()
+	defer pathLocks.Unlock()
+
+	l := pathLocks.m[path]
+	if l == nil {
+		l = &pathLock{}
+		pathLocks.m[path] = l
+	}
+	l.refs++
+	return l
+}
+
+// releasePath 减少使用者数量
+func releasePath(path string, l *pathLock) {
+	pathLocks.Lock()
+	defer pathLocks.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(pathLocks.m, path)
+	}
+}
+
+// rlockPath 以共享方式锁定本地路径，返回解锁函数
+func rlockPath(path string) func() {
+	l := acquirePath(path)
+	l.RLock()
+	return func() {
+		l.RUnlock()
+		releasePath(path, l)
+	}
+}
+
+// lockPath 独占锁定本地路径，返回解锁函数
+func lockPath(path string) func() {
+	l := acquirePath(path)
+	l.Lock()
+	return func() {
+		l.Unlock()
+		releasePath(path, l)
+	}
+}
+
+// modifiesLocal 冲突处理策略是否可能修改本地文件
+func modifiesLocal(strategy Strategy) bool {
+	return strategy != StrategyManual && strategy != StrategyLocal
+}
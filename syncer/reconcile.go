@@ -0,0 +1,451 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"CloudFileSync/events"
+	"CloudFileSync/history"
+	"CloudFileSync/provider"
+)
+
+// ActionType 同步动作类型
+type ActionType string
+
+const (
+	ActionMkdir  ActionType = "mkdir"  // 创建远程目录
+	ActionUpload ActionType = "upload" // 上传新增或修改的文件
+	ActionMove   ActionType = "move"   // 本地移动（重命名）过的文件
+	ActionDelete ActionType = "delete" // 本地已删除的文件或目录
+
+	// ActionConflict 本地未修改但远程已被修改的文件，执行时按冲突处理策略处理
+	ActionConflict ActionType = "conflict"
+
+	// ActionDownload 下载远程文件，只在处理冲突时出现，不会出现在同步计划中
+	ActionDownload ActionType = "download"
+)
+
+// Action 计划执行的同步动作
+type Action struct {
+	Type   ActionType `json:"type"`
+	Path   string     `json:"path"`             // 相对于监听目录的路径
+	From   string     `json:"from,omitempty"`   // 移动前的相对路径
+	Reason string     `json:"reason,omitempty"` // 本地未修改但远程缺失或被修改时的说明
+}
+
+// String 返回动作的可读描述
+func (a Action) String() string {
+	desc := fmt.Sprintf("%-6s %s", a.Type, a.Path)
+	if a.Type == ActionMove {
+		desc = fmt.Sprintf("%-6s %s -> %s", a.Type, a.From, a.Path)
+	}
+	if a.Reason != "" {
+		desc += "（" + a.Reason + "）"
+	}
+	return desc
+}
+
+// Plan 单个云盘的同步计划
+type Plan struct {
+	Target  Target
+	Actions []Action
+}
+
+// Result 同步计划的执行结果
+type Result struct {
+	Done   map[ActionType]int // 各类动作成功数量
+	Errors []error            // 失败的动作
+}
+
+// localEntry 本地文件信息
+type localEntry struct {
+	isDir bool
+	hash  string
+}
+
+// Snapshot 监听目录的一次扫描结果，为多个云盘生成同步计划时共用，避免重复扫描和计算哈希
+type Snapshot struct {
+	entries map[string]localEntry
+}
+
+// Scan 扫描监听目录
+func (s *Syncer) Scan() (*Snapshot, error) {
+	entries, err := s.scanLocal()
+	if err != nil {
+		return nil, fmt.Errorf("扫描监听目录失败: %w", err)
+	}
+	return &Snapshot{entries: entries}, nil
+}
+
+// Plan 对比监听目录、同步记录和云盘上的实际文件，生成目标云盘的完整同步计划。
+// 云盘上缺失的文件即使本地未修改也会重新上传，被修改过的按冲突处理策略处理；提供商不支持列举和查询时只对比同步记录
+func (s *Syncer) Plan(t Target, snap *Snapshot) (*Plan, error) {
+	local := snap.entries
+	records := s.state.Files(t.Config.Name)
+	plan := &Plan{Target: t}
+
+	remote, err := s.scanRemote(t, records)
+	if err != nil {
+		return nil, fmt.Errorf("列举远程文件失败: %w", err)
+	}
+
+	// 新增或修改的文件
+	var added []string
+	for rel, entry := range local {
+		rec, synced := records[rel]
+		switch {
+		case entry.isDir:
+			if !synced || !rec.IsDir || remote.missingDir(rel) {
+				plan.Actions = append(plan.Actions, Action{Type: ActionMkdir, Path: rel})
+			}
+		case !synced:
+			added = append(added, rel)
+		case rec.LocalHash != entry.hash:
+			plan.Actions = append(plan.Actions, Action{Type: ActionUpload, Path: rel})
+		default:
+			action, err := s.remoteDrift(t, remote, rel, rec)
+			if err != nil {
+				return nil, err
+			}
+			if action != nil {
+				plan.Actions = append(plan.Actions, *action)
+			}
+		}
+	}
+
+	// 本地已不存在的记录
+	removed := make(map[string]FileState)
+	var removedPaths []string
+	for rel, rec := range records {
+		if _, ok := local[rel]; !ok {
+			removed[rel] = rec
+			removedPaths = append(removedPaths, rel)
+		}
+	}
+	sort.Strings(removedPaths)
+
+	// 内容相同的删除与新增视为移动，有多个候选时按路径顺序选择，远程已不存在的文件无法移动
+	sort.Strings(added)
+	for _, rel := range added {
+		from := ""
+		for _, old := range removedPaths {
+			rec, ok := removed[old]
+			if ok && !rec.IsDir && rec.LocalHash == local[rel].hash && !remote.missing(old) {
+				from = old
+				break
+			}
+		}
+
+		if from != "" {
+			delete(removed, from)
+			plan.Actions = append(plan.Actions, Action{Type: ActionMove, Path: rel, From: from})
+		} else {
+			plan.Actions = append(plan.Actions, Action{Type: ActionUpload, Path: rel})
+		}
+	}
+
+	// 父目录已被删除的条目会随父目录一起删除
+	for rel := range removed {
+		if _, ok := removed[path.Dir(rel)]; ok {
+			continue
+		}
+		plan.Actions = append(plan.Actions, Action{Type: ActionDelete, Path: rel})
+	}
+
+	sortActions(plan.Actions)
+	return plan, nil
+}
+
+// remoteFiles 云盘上的文件（相对路径 -> 文件信息），为 nil 表示提供商不支持列举和查询，无法得知远程状态
+type remoteFiles map[string]provider.FileInfo
+
+// missing 远程确定不存在该文件
+func (r remoteFiles) missing(rel string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r[rel]
+	return !ok
+}
+
+// missingDir 远程确定不存在该目录
+func (r remoteFiles) missingDir(rel string) bool {
+	if r == nil {
+		return false
+	}
+	info, ok := r[rel]
+	return !ok || !info.IsDir
+}
+
+// scanRemote 获取云盘上的文件：支持列举时递归列举目标目录，否则逐个查询同步记录中的路径
+func (s *Syncer) scanRemote(t Target, records map[string]FileState) (remoteFiles, error) {
+	if lister, ok := t.Provider.(provider.Lister); ok {
+		remote := make(remoteFiles)
+		root := "/" + strings.Trim(t.Config.Target, "/")
+		return remote, listRemote(lister, root, "", remote)
+	}
+
+	if _, ok := t.Provider.(provider.Stater); !ok {
+		return nil, nil
+	}
+
+	remote := make(remoteFiles)
+	for rel := range records {
+		localPath := filepath.Join(s.watchDir, filepath.FromSlash(rel))
+		info, err := s.stat(t, RemotePath(localPath, s.watchDir, t.Config.Target))
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			remote[rel] = *info
+		}
+	}
+	return remote, nil
+}
+
+// listRemote 递归列举远程目录，跳过隐藏文件和目录（与扫描本地时保持一致）
+func listRemote(lister provider.Lister, dir, rel string, remote remoteFiles) error {
+	items, err := lister.List(dir)
+	if errors.Is(err, provider.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if strings.HasPrefix(item.Name, ".") {
+			continue
+		}
+		itemRel := path.Join(rel, item.Name)
+		remote[itemRel] = item
+		if item.IsDir {
+			if err := listRemote(lister, path.Join(dir, item.Name), itemRel, remote); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// remoteDrift 检查本地未修改的文件在云盘上是否缺失或被修改：缺失时重新上传，被修改时按冲突处理，未变化时返回 nil。
+// 列举得到的信息与查询得到的可能不一致（如修改时间精度），指纹不同时再查询一次确认
+func (s *Syncer) remoteDrift(t Target, remote remoteFiles, rel string, rec FileState) (*Action, error) {
+	if remote == nil {
+		return nil, nil
+	}
+
+	info, ok := remote[rel]
+	if !ok {
+		return &Action{Type: ActionUpload, Path: rel, Reason: "远程文件已删除"}, nil
+	}
+	if info.IsDir {
+		return &Action{Type: ActionUpload, Path: rel, Reason: "远程为同名目录"}, nil
+	}
+	if rec.RemoteHash == "" || fingerprint(&info) == rec.RemoteHash {
+		return nil, nil
+	}
+
+	localPath := filepath.Join(s.watchDir, filepath.FromSlash(rel))
+	current, err := s.stat(t, RemotePath(localPath, s.watchDir, t.Config.Target))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case current == nil:
+		return &Action{Type: ActionUpload, Path: rel, Reason: "远程文件已删除"}, nil
+	case fingerprint(current) != rec.RemoteHash:
+		return &Action{Type: ActionConflict, Path: rel, Reason: "远程文件已被修改"}, nil
+	}
+	return nil, nil
+}
+
+// Execute 执行同步计划
+func (s *Syncer) Execute(plan *Plan) *Result {
+	result := &Result{Done: make(map[ActionType]int)}
+	t := plan.Target
+
+	for _, action := range plan.Actions {
+		localPath := filepath.Join(s.watchDir, filepath.FromSlash(action.Path))
+
+		var err error
+		switch action.Type {
+		case ActionMkdir, ActionUpload:
+			// 本地未修改时 SyncFile 会根据同步记录跳过上传，需要强制上传
+			if action.Reason != "" {
+				err = s.reupload(t, action.Path)
+			} else {
+				err = s.SyncFile(t, localPath, false)
+			}
+		case ActionConflict:
+			err = s.resolveDrift(t, action.Path)
+		case ActionDelete:
+			err = s.SyncFile(t, localPath, true)
+		case ActionMove:
+			err = s.move(t, action.From, action.Path)
+		}
+
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", action, err))
+			continue
+		}
+		result.Done[action.Type]++
+	}
+
+	return result
+}
+
+// reupload 重新上传本地未修改但远程缺失的文件，同步记录在上传成功后才会更新
+func (s *Syncer) reupload(t Target, rel string) error {
+	localPath := filepath.Join(s.watchDir, filepath.FromSlash(rel))
+	remotePath := RemotePath(localPath, s.watchDir, t.Config.Target)
+	start := time.Now()
+
+	unlock := rlockPath(localPath)
+	defer unlock()
+
+	localHash, err := hashFile(localPath)
+	if err != nil {
+		return s.fail(t, ActionUpload, rel, start, fmt.Errorf("计算文件哈希失败: %w", err))
+	}
+	return s.fail(t, ActionUpload, rel, start, s.upload(t, localPath, rel, remotePath, localHash))
+}
+
+// resolveDrift 按冲突处理策略处理本地未修改但远程已被修改的文件，远程已不存在时直接上传
+func (s *Syncer) resolveDrift(t Target, rel string) error {
+	localPath := filepath.Join(s.watchDir, filepath.FromSlash(rel))
+	remotePath := RemotePath(localPath, s.watchDir, t.Config.Target)
+	start := time.Now()
+
+	lock := rlockPath
+	if modifiesLocal(s.strategy) {
+		lock = lockPath
+	}
+	defer lock(localPath)()
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return s.fail(t, ActionUpload, rel, start, fmt.Errorf("获取文件信息失败: %w", err))
+	}
+	localHash, err := hashFile(localPath)
+	if err != nil {
+		return s.fail(t, ActionUpload, rel, start, fmt.Errorf("计算文件哈希失败: %w", err))
+	}
+	remote, err := s.stat(t, remotePath)
+	if err != nil {
+		return s.fail(t, ActionUpload, rel, start, err)
+	}
+	if remote == nil {
+		return s.fail(t, ActionUpload, rel, start, s.upload(t, localPath, rel, remotePath, localHash))
+	}
+
+	c := newConflict(t, rel, localHash, info, remote)
+	return s.fail(t, ActionUpload, rel, start, s.handleConflict(t, c, s.strategy))
+}
+
+// move 同步本地移动，提供商不支持移动时退化为上传加删除
+func (s *Syncer) move(t Target, from, to string) error {
+	oldLocal := filepath.Join(s.watchDir, filepath.FromSlash(from))
+	newLocal := filepath.Join(s.watchDir, filepath.FromSlash(to))
+
+	mover, ok := t.Provider.(provider.Mover)
+	if !ok {
+		if err := s.SyncFile(t, newLocal, false); err != nil {
+			return err
+		}
+		return s.SyncFile(t, oldLocal, true)
+	}
+
+	oldRemote := RemotePath(oldLocal, s.watchDir, t.Config.Target)
+	newRemote := RemotePath(newLocal, s.watchDir, t.Config.Target)
+	start := time.Now()
+	if err := mover.MoveFile(oldRemote, newRemote); err != nil {
+		return s.fail(t, ActionMove, to, start, err)
+	}
+
+	rec, _ := s.state.Get(t.Config.Name, from)
+	rec.SyncedAt = time.Now()
+	s.state.Delete(t.Config.Name, from)
+	s.state.Set(t.Config.Name, to, rec)
+
+	if err := s.state.Save(); err != nil {
+		return s.fail(t, ActionMove, to, start, err)
+	}
+
+	log.Printf("[%s] 同步移动: %s -> %s", t.Provider.Name(), from, to)
+	s.addHistory(t, history.Record{
+		Path:     to,
+		From:     from,
+		Action:   string(ActionMove),
+		Bytes:    rec.Size,
+		Duration: time.Since(start).Milliseconds(),
+		Result:   history.ResultSuccess,
+	})
+	s.publish(t, events.KindDone, ActionMove, to, "已从 "+from+" 移动")
+	return nil
+}
+
+// scanLocal 扫描监听目录，跳过隐藏文件和目录（与监听器保持一致）
+func (s *Syncer) scanLocal() (map[string]localEntry, error) {
+	entries := make(map[string]localEntry)
+
+	err := filepath.Walk(s.watchDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == s.watchDir {
+			return nil
+		}
+
+		if strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := s.relPath(p)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			entries[rel] = localEntry{isDir: true}
+			return nil
+		}
+
+		hash, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		entries[rel] = localEntry{hash: hash}
+		return nil
+	})
+
+	return entries, err
+}
+
+// sortActions 排序动作：先建目录（父目录在前），再移动、上传、处理冲突，最后删除
+func sortActions(actions []Action) {
+	order := map[ActionType]int{
+		ActionMkdir:    0,
+		ActionMove:     1,
+		ActionUpload:   2,
+		ActionConflict: 3,
+		ActionDelete:   4,
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool {
+		if order[actions[i].Type] != order[actions[j].Type] {
+			return order[actions[i].Type] < order[actions[j].Type]
+		}
+		return actions[i].Path < actions[j].Path
+	})
+}
@@ -0,0 +1,214 @@
+package syncer_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"CloudFileSync/provider"
+	"CloudFileSync/syncer"
+)
+
+// failingUpload 上传总是失败的云盘
+type failingUpload struct {
+	provider.Provider
+	provider.Stater
+	provider.Lister
+}
+
+func (failingUpload) UploadFile(localPath, remotePath string) error {
+	return errors.New("上传失败")
+}
+
+// mustScan 扫描监听目录
+func mustScan(t *testing.T, f *fixture) *syncer.Snapshot {
+	t.Helper()
+	snap, err := f.syncer.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snap
+}
+
+// plan 扫描监听目录并生成同步计划
+func (f *fixture) plan(t *testing.T) *syncer.Plan {
+	t.Helper()
+	plan, err := f.syncer.Plan(f.target, mustScan(t, f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+// execute 执行同步计划，要求全部成功
+func (f *fixture) execute(t *testing.T, plan *syncer.Plan) {
+	t.Helper()
+	if result := f.syncer.Execute(plan); len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+}
+
+// assertActions 检查同步计划的动作
+func assertActions(t *testing.T, plan *syncer.Plan, want ...syncer.Action) {
+	t.Helper()
+	if len(plan.Actions) != len(want) {
+		t.Fatalf("计划动作 %v，期望 %v", plan.Actions, want)
+	}
+	for i, action := range plan.Actions {
+		if action != want[i] {
+			t.Errorf("第 %d 个动作为 %v，期望 %v", i, action, want[i])
+		}
+	}
+}
+
+// modifyRemote 修改远程文件并推后修改时间，确保指纹变化
+func (f *fixture) modifyRemote(t *testing.T, rel, content string) {
+	t.Helper()
+	writeFile(t, f.remote(rel), content)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(f.remote(rel), later, later)
+}
+
+func TestPlanInitialSync(t *testing.T) {
+	f := newFixture(t, syncer.StrategyManual, nil)
+	writeFile(t, f.local("dir/a.txt"), "a")
+	writeFile(t, f.local("b.txt"), "b")
+
+	plan := f.plan(t)
+	assertActions(t, plan,
+		syncer.Action{Type: syncer.ActionMkdir, Path: "dir"},
+		syncer.Action{Type: syncer.ActionUpload, Path: "b.txt"},
+		syncer.Action{Type: syncer.ActionUpload, Path: "dir/a.txt"},
+	)
+
+	f.execute(t, plan)
+	assertFile(t, f.remote("dir/a.txt"), "a")
+	assertFile(t, f.remote("b.txt"), "b")
+	assertActions(t, f.plan(t))
+}
+
+func TestPlanDryRun(t *testing.T) {
+	f := newFixture(t, syncer.StrategyKeepBoth, nil)
+	writeFile(t, f.local("a.txt"), "a")
+	writeFile(t, f.local("b.txt"), "b")
+	f.execute(t, f.plan(t))
+
+	writeFile(t, f.local("c.txt"), "c")
+	os.Remove(f.local("b.txt"))
+	f.modifyRemote(t, "a.txt", "remote a")
+
+	// 只生成计划不会修改本地、远程或同步记录
+	before := f.syncer.State().Files("local")
+	for i := 0; i < 2; i++ {
+		assertActions(t, f.plan(t),
+			syncer.Action{Type: syncer.ActionUpload, Path: "c.txt"},
+			syncer.Action{Type: syncer.ActionConflict, Path: "a.txt", Reason: "远程文件已被修改"},
+			syncer.Action{Type: syncer.ActionDelete, Path: "b.txt"},
+		)
+	}
+
+	assertFile(t, f.local("a.txt"), "a")
+	assertFile(t, f.remote("a.txt"), "remote a")
+	assertFile(t, f.remote("b.txt"), "b")
+	if _, err := os.Stat(f.remote("c.txt")); !os.IsNotExist(err) {
+		t.Errorf("c.txt 不应被上传: %v", err)
+	}
+	if after := f.syncer.State().Files("local"); len(after) != len(before) {
+		t.Errorf("同步记录 %v，期望不变 %v", after, before)
+	}
+}
+
+func TestPlanRemoteDeleted(t *testing.T) {
+	f := newFixture(t, syncer.StrategyManual, nil)
+	writeFile(t, f.local("a.txt"), "a")
+	f.execute(t, f.plan(t))
+
+	os.Remove(f.remote("a.txt"))
+	plan := f.plan(t)
+	assertActions(t, plan, syncer.Action{Type: syncer.ActionUpload, Path: "a.txt", Reason: "远程文件已删除"})
+
+	f.execute(t, plan)
+	assertFile(t, f.remote("a.txt"), "a")
+	assertActions(t, f.plan(t))
+}
+
+func TestPlanRemoteDeletedUploadFails(t *testing.T) {
+	f := newFixture(t, syncer.StrategyManual, nil)
+	writeFile(t, f.local("a.txt"), "a")
+	f.execute(t, f.plan(t))
+
+	os.Remove(f.remote("a.txt"))
+	failing := f.target
+	failing.Provider = failingUpload{f.target.Provider, f.target.Provider.(provider.Stater), f.target.Provider.(provider.Lister)}
+	plan, err := f.syncer.Plan(failing, mustScan(t, f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := f.syncer.Execute(plan); len(result.Errors) != 1 {
+		t.Fatalf("失败 %v，期望 1 个", result.Errors)
+	}
+
+	// 上传失败时保留同步记录，下次仍会重新上传
+	if _, ok := f.syncer.State().Get("local", "a.txt"); !ok {
+		t.Error("上传失败后同步记录被删除")
+	}
+	assertActions(t, f.plan(t), syncer.Action{Type: syncer.ActionUpload, Path: "a.txt", Reason: "远程文件已删除"})
+}
+
+func TestPlanRemoteModified(t *testing.T) {
+	f := newFixture(t, syncer.StrategyManual, nil)
+	writeFile(t, f.local("a.txt"), "a")
+	f.execute(t, f.plan(t))
+
+	f.modifyRemote(t, "a.txt", "remote a")
+	plan := f.plan(t)
+	assertActions(t, plan, syncer.Action{Type: syncer.ActionConflict, Path: "a.txt", Reason: "远程文件已被修改"})
+
+	// 按 manual 策略记录冲突，远程版本不会被覆盖
+	f.execute(t, plan)
+	assertFile(t, f.remote("a.txt"), "remote a")
+	assertFile(t, f.local("a.txt"), "a")
+	if conflicts := f.syncer.State().Conflicts(); len(conflicts) != 1 || conflicts[0].Path != "a.txt" {
+		t.Errorf("待处理冲突 %+v，期望 a.txt", conflicts)
+	}
+}
+
+func TestPlanRemoteModifiedRemoteWins(t *testing.T) {
+	f := newFixture(t, syncer.StrategyRemote, nil)
+	writeFile(t, f.local("a.txt"), "a")
+	f.execute(t, f.plan(t))
+
+	f.modifyRemote(t, "a.txt", "remote a")
+	f.execute(t, f.plan(t))
+
+	assertFile(t, f.local("a.txt"), "remote a")
+	assertActions(t, f.plan(t))
+}
+
+func TestPlanMove(t *testing.T) {
+	f := newFixture(t, syncer.StrategyManual, nil)
+	writeFile(t, f.local("a.txt"), "same")
+	writeFile(t, f.local("gone.txt"), "gone")
+	f.execute(t, f.plan(t))
+
+	// 内容不变的改名视为移动；远程已删除的文件无法移动，只能重新上传
+	os.Rename(f.local("a.txt"), f.local("b.txt"))
+	os.Rename(f.local("gone.txt"), f.local("back.txt"))
+	os.Remove(f.remote("gone.txt"))
+
+	plan := f.plan(t)
+	assertActions(t, plan,
+		syncer.Action{Type: syncer.ActionMove, Path: "b.txt", From: "a.txt"},
+		syncer.Action{Type: syncer.ActionUpload, Path: "back.txt"},
+		syncer.Action{Type: syncer.ActionDelete, Path: "gone.txt"},
+	)
+
+	f.execute(t, plan)
+	assertFile(t, f.remote("b.txt"), "same")
+	assertFile(t, f.remote("back.txt"), "gone")
+	if _, err := os.Stat(f.remote("a.txt")); !os.IsNotExist(err) {
+		t.Errorf("a.txt 应已移走: %v", err)
+	}
+	assertActions(t, f.plan(t))
+}
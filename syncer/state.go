@@ -0,0 +1,176 @@
+package syncer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileState 文件最近一次成功同步时的记录
+type FileState struct {
+	LocalHash  string    `json:"local_hash"`  // 本地文件 SHA1
+	RemoteHash string    `json:"remote_hash"` // 远程文件指纹
+	Size       int64     `json:"size"`        // 文件大小
+	IsDir      bool      `json:"is_dir"`      // 是否为目录
+	SyncedAt   time.Time `json:"synced_at"`   // 同步时间
+}
+
+// Conflict 待处理的冲突
+type Conflict struct {
+	ID            string    `json:"id"`
+	Provider      string    `json:"provider"`        // 云盘配置名称
+	Path          string    `json:"path"`            // 相对于监听目录的路径
+	LocalHash     string    `json:"local_hash"`      // 冲突发生时的本地哈希
+	RemoteHash    string    `json:"remote_hash"`     // 冲突发生时的远程指纹
+	LocalModTime  time.Time `json:"local_mod_time"`  // 本地修改时间
+	RemoteModTime time.Time `json:"remote_mod_time"` // 远程修改时间
+	DetectedAt    time.Time `json:"detected_at"`     // 发现时间
+}
+
+// State 同步状态存储
+type State struct {
+	path   string
+	mu     sync.Mutex
+	saveMu sync.Mutex // 保证同一时间只有一个写入者
+	data   stateData
+}
+
+// stateData 状态文件内容
+type stateData struct {
+	Files     map[string]map[string]FileState `json:"files"` // 云盘名称 -> 相对路径 -> 同步记录
+	Conflicts []Conflict                      `json:"conflicts"`
+}
+
+// LoadState 从文件加载同步状态，文件不存在时返回空状态
+func LoadState(path string) (*State, error) {
+	s := &State{
+		path: path,
+		data: stateData{Files: make(map[string]map[string]FileState)},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Files == nil {
+		s.data.Files = make(map[string]map[string]FileState)
+	}
+
+	return s, nil
+}
+
+// Save 保存同步状态
+func (s *State) Save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// Get 获取文件的同步记录
+func (s *State) Get(provider, relPath string) (FileState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fs, ok := s.data.Files[provider][relPath]
+	return fs, ok
+}
+
+// Set 更新文件的同步记录
+func (s *State) Set(provider, relPath string, fs FileState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, ok := s.data.Files[provider]
+	if !ok {
+		files = make(map[string]FileState)
+		s.data.Files[provider] = files
+	}
+	files[relPath] = fs
+}
+
+// Delete 删除文件的同步记录
+func (s *State) Delete(provider, relPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data.Files[provider], relPath)
+}
+
+// Files 返回某个云盘的全部同步记录副本
+func (s *State) Files(provider string) map[string]FileState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := make(map[string]FileState, len(s.data.Files[provider]))
+	for k, v := range s.data.Files[provider] {
+		files[k] = v
+	}
+	return files
+}
+
+// AddConflict 记录冲突，同一云盘同一路径只保留最新一条
+func (s *State) AddConflict(c Conflict) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.data.Conflicts {
+		if existing.Provider == c.Provider && existing.Path == c.Path {
+			s.data.Conflicts[i] = c
+			return
+		}
+	}
+	s.data.Conflicts = append(s.data.Conflicts, c)
+}
+
+// Conflicts 返回全部待处理冲突，按发现时间排序
+func (s *State) Conflicts() []Conflict {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conflicts := make([]Conflict, len(s.data.Conflicts))
+	copy(conflicts, s.data.Conflicts)
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].DetectedAt.Before(conflicts[j].DetectedAt)
+	})
+	return conflicts
+}
+
+// RemoveConflict 移除冲突记录
+func (s *State) RemoveConflict(id string) (Conflict, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.data.Conflicts {
+		if c.ID == id {
+			s.data.Conflicts = append(s.data.Conflicts[:i], s.data.Conflicts[i+1:]...)
+			return c, true
+		}
+	}
+	return Conflict{}, false
+}
@@ -0,0 +1,442 @@
+package syncer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"CloudFileSync/config"
+	"CloudFileSync/events"
+	"CloudFileSync/history"
+	"CloudFileSync/provider"
+)
+
+// Target 同步目标：云盘提供商及其配置
+type Target struct {
+	Config   config.ProviderConfig
+	Provider provider.Provider
+}
+
+// Syncer 负责本地文件与云盘之间的同步，并基于上次同步记录检测冲突
+type Syncer struct {
+	watchDir string
+	strategy Strategy
+	state    *State
+	history  *history.Store
+	hostname string
+}
+
+// New 创建同步器
+func New(cfg *config.Config, state *State) (*Syncer, error) {
+	strategy, err := ParseStrategy(cfg.ConflictStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+
+	return &Syncer{
+		watchDir: cfg.WatchDir,
+		strategy: strategy,
+		state:    state,
+		history:  history.New(cfg.GetHistoryFile()),
+		hostname: hostname,
+	}, nil
+}
+
+// State 返回同步状态存储
+func (s *Syncer) State() *State {
+	return s.state
+}
+
+// SyncFile 将本地文件的变化同步到目标云盘，removed 表示本地文件已被删除。
+// 可与其他云盘对同一文件的同步并行调用；冲突处理需要修改本地文件时，等其他云盘的同步完成后再进行
+func (s *Syncer) SyncFile(t Target, localPath string, removed bool) error {
+	unlock := rlockPath(localPath)
+	err := s.syncFile(t, localPath, removed, false)
+	unlock()
+	if err != errNeedExclusive {
+		return err
+	}
+
+	unlock = lockPath(localPath)
+	defer unlock()
+	return s.syncFile(t, localPath, removed, true)
+}
+
+// syncFile 同步一个文件，exclusive 表示已独占该路径
+func (s *Syncer) syncFile(t Target, localPath string, removed, exclusive bool) error {
+	rel, err := s.relPath(localPath)
+	if err != nil {
+		return err
+	}
+	remotePath := RemotePath(localPath, s.watchDir, t.Config.Target)
+	start := time.Now()
+
+	info, err := os.Stat(localPath)
+	if removed || os.IsNotExist(err) {
+		return s.fail(t, ActionDelete, rel, start, s.syncRemoval(t, rel, remotePath))
+	}
+	if err != nil {
+		return s.fail(t, ActionUpload, rel, start, fmt.Errorf("获取文件信息失败: %w", err))
+	}
+
+	if info.IsDir() {
+		return s.fail(t, ActionMkdir, rel, start, s.syncDir(t, rel, remotePath))
+	}
+	err = s.syncContent(t, localPath, rel, remotePath, info, exclusive)
+	if err == errNeedExclusive {
+		return err
+	}
+	return s.fail(t, ActionUpload, rel, start, err)
+}
+
+// syncDir 在云盘上创建目录
+func (s *Syncer) syncDir(t Target, rel, remotePath string) error {
+	start := time.Now()
+	if err := t.Provider.CreateDir(remotePath); err != nil {
+		return err
+	}
+	s.state.Set(t.Config.Name, rel, FileState{IsDir: true, SyncedAt: time.Now()})
+	if err := s.state.Save(); err != nil {
+		return err
+	}
+	s.succeed(t, ActionMkdir, rel, start, 0, "已创建目录")
+	return nil
+}
+
+// syncContent 同步文件内容：内容未变化时跳过，本地和远程都已修改时按策略处理冲突，否则上传
+func (s *Syncer) syncContent(t Target, localPath, rel, remotePath string, info os.FileInfo, exclusive bool) error {
+	localHash, err := hashFile(localPath)
+	if err != nil {
+		return fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+
+	prev, synced := s.state.Get(t.Config.Name, rel)
+	if synced && prev.LocalHash == localHash {
+		return nil
+	}
+
+	remote, err := s.stat(t, remotePath)
+	if err != nil {
+		return err
+	}
+
+	// 本地和远程自上次同步后都发生了变化
+	if synced && remote != nil && prev.RemoteHash != "" && fingerprint(remote) != prev.RemoteHash {
+		if !exclusive && modifiesLocal(s.strategy) {
+			return errNeedExclusive
+		}
+		return s.handleConflict(t, newConflict(t, rel, localHash, info, remote), s.strategy)
+	}
+
+	return s.upload(t, localPath, rel, remotePath, localHash)
+}
+
+// Resolve 按指定策略处理一条待处理冲突
+func (s *Syncer) Resolve(t Target, conflictID string, strategy Strategy) error {
+	if strategy == StrategyManual {
+		return fmt.Errorf("请选择具体的冲突处理方式")
+	}
+
+	var conflict *Conflict
+	for _, c := range s.state.Conflicts() {
+		if c.ID == conflictID {
+			conflict = &c
+			break
+		}
+	}
+	if conflict == nil {
+		return fmt.Errorf("冲突不存在: %s", conflictID)
+	}
+	if conflict.Provider != t.Config.Name {
+		return fmt.Errorf("冲突不属于云盘: %s", t.Config.Name)
+	}
+	if _, ok := t.Provider.(provider.Downloader); !ok && strategy != StrategyLocal {
+		return fmt.Errorf("[%s] 不支持下载，只能保留本地版本", t.Provider.Name())
+	}
+
+	unlock := lockPath(filepath.Join(s.watchDir, filepath.FromSlash(conflict.Path)))
+	defer unlock()
+
+	if err := s.handleConflict(t, *conflict, strategy); err != nil {
+		return err
+	}
+
+	s.state.RemoveConflict(conflictID)
+	return s.state.Save()
+}
+
+// newConflict 根据本地和远程文件信息生成冲突记录
+func newConflict(t Target, rel, localHash string, info os.FileInfo, remote *provider.FileInfo) Conflict {
+	return Conflict{
+		ID:            conflictID(t.Config.Name, rel),
+		Provider:      t.Config.Name,
+		Path:          rel,
+		LocalHash:     localHash,
+		RemoteHash:    fingerprint(remote),
+		LocalModTime:  info.ModTime(),
+		RemoteModTime: remote.ModTime,
+		DetectedAt:    time.Now(),
+	}
+}
+
+// handleConflict 按策略处理冲突
+func (s *Syncer) handleConflict(t Target, c Conflict, strategy Strategy) error {
+	localPath := filepath.Join(s.watchDir, filepath.FromSlash(c.Path))
+	remotePath := RemotePath(localPath, s.watchDir, t.Config.Target)
+
+	log.Printf("[%s] 检测到冲突: %s (策略: %s)", t.Provider.Name(), c.Path, strategy)
+	s.publish(t, events.KindConflict, "", c.Path, fmt.Sprintf("本地和远程都已修改，处理策略: %s", strategy))
+
+	if strategy == StrategyNewest {
+		strategy = StrategyLocal
+		if c.RemoteModTime.After(c.LocalModTime) {
+			strategy = StrategyRemote
+		}
+	}
+
+	// 不支持下载的云盘无法取回远程版本，改为等待手动处理
+	if _, ok := t.Provider.(provider.Downloader); !ok && (strategy == StrategyRemote || strategy == StrategyKeepBoth) {
+		log.Printf("[%s] 不支持下载，冲突改为手动处理: %s", t.Provider.Name(), c.Path)
+		strategy = StrategyManual
+	}
+
+	switch strategy {
+	case StrategyManual:
+		s.state.AddConflict(c)
+		if err := s.state.Save(); err != nil {
+			return err
+		}
+		s.addHistory(t, history.Record{
+			Path:   c.Path,
+			Action: string(ActionUpload),
+			Result: history.ResultSkipped,
+			Error:  "本地和远程都已修改，等待手动处理",
+		})
+		return nil
+
+	case StrategyLocal:
+		localHash, err := hashFile(localPath)
+		if err != nil {
+			return err
+		}
+		return s.upload(t, localPath, c.Path, remotePath, localHash)
+
+	case StrategyRemote:
+		return s.download(t, localPath, c.Path, remotePath)
+
+	case StrategyKeepBoth:
+		// 本地版本改名为冲突副本，原路径换成远程版本
+		copyPath := s.conflictPath(localPath, time.Now())
+		if err := os.Rename(localPath, copyPath); err != nil {
+			return fmt.Errorf("重命名冲突副本失败: %w", err)
+		}
+		if err := s.download(t, localPath, c.Path, remotePath); err != nil {
+			os.Rename(copyPath, localPath)
+			return err
+		}
+
+		log.Printf("[%s] 已保留冲突副本: %s", t.Provider.Name(), copyPath)
+		return s.SyncFile(t, copyPath, false)
+
+	default:
+		return fmt.Errorf("不支持的冲突处理策略: %s", strategy)
+	}
+}
+
+// syncRemoval 同步本地删除
+func (s *Syncer) syncRemoval(t Target, rel, remotePath string) error {
+	prev, synced := s.state.Get(t.Config.Name, rel)
+
+	// 远程文件自上次同步后被修改过，保留远程版本
+	if synced && !prev.IsDir && prev.RemoteHash != "" {
+		remote, err := s.stat(t, remotePath)
+		if err != nil {
+			return err
+		}
+		if remote != nil && fingerprint(remote) != prev.RemoteHash {
+			log.Printf("[%s] 远程文件已被修改，跳过删除: %s", t.Provider.Name(), remotePath)
+			s.publish(t, events.KindSkipped, ActionDelete, rel, "远程文件已被修改，跳过删除")
+			s.addHistory(t, history.Record{
+				Path:   rel,
+				Action: string(ActionDelete),
+				Result: history.ResultSkipped,
+				Error:  "远程文件已被修改，跳过删除",
+			})
+			s.state.Delete(t.Config.Name, rel)
+			return s.state.Save()
+		}
+	}
+
+	start := time.Now()
+	if err := t.Provider.DeleteFile(remotePath); err != nil {
+		return err
+	}
+
+	for path := range s.state.Files(t.Config.Name) {
+		if path == rel || strings.HasPrefix(path, rel+"/") {
+			s.state.Delete(t.Config.Name, path)
+		}
+	}
+	if err := s.state.Save(); err != nil {
+		return err
+	}
+	s.succeed(t, ActionDelete, rel, start, 0, "已删除")
+	return nil
+}
+
+// upload 上传文件并记录同步状态
+func (s *Syncer) upload(t Target, localPath, rel, remotePath, localHash string) error {
+	s.publish(t, events.KindUploading, ActionUpload, rel, "开始上传")
+	start := time.Now()
+	if err := t.Provider.UploadFile(localPath, remotePath); err != nil {
+		return err
+	}
+	fs, err := s.record(t, localPath, rel, remotePath, localHash)
+	if err != nil {
+		return err
+	}
+	s.succeed(t, ActionUpload, rel, start, fs.Size, "已上传")
+	return nil
+}
+
+// download 下载远程文件覆盖本地文件并记录同步状态
+func (s *Syncer) download(t Target, localPath, rel, remotePath string) error {
+	downloader, ok := t.Provider.(provider.Downloader)
+	if !ok {
+		return fmt.Errorf("[%s] 不支持下载", t.Provider.Name())
+	}
+
+	start := time.Now()
+	if err := downloader.DownloadFile(remotePath, localPath); err != nil {
+		return err
+	}
+
+	localHash, err := hashFile(localPath)
+	if err != nil {
+		return err
+	}
+	fs, err := s.record(t, localPath, rel, remotePath, localHash)
+	if err != nil {
+		return err
+	}
+	s.succeed(t, ActionDownload, rel, start, fs.Size, "已下载远程版本")
+	return nil
+}
+
+// record 记录一次成功同步，返回保存的同步状态
+func (s *Syncer) record(t Target, localPath, rel, remotePath, localHash string) (FileState, error) {
+	fs := FileState{
+		LocalHash: localHash,
+		SyncedAt:  time.Now(),
+	}
+
+	if info, err := os.Stat(localPath); err == nil {
+		fs.Size = info.Size()
+	}
+
+	remote, err := s.stat(t, remotePath)
+	if err != nil {
+		log.Printf("[%s] 获取远程文件信息失败: %v", t.Provider.Name(), err)
+	} else if remote != nil {
+		fs.RemoteHash = fingerprint(remote)
+	}
+
+	s.state.Set(t.Config.Name, rel, fs)
+	return fs, s.state.Save()
+}
+
+// stat 获取远程文件信息，提供商不支持或文件不存在时返回 nil
+func (s *Syncer) stat(t Target, remotePath string) (*provider.FileInfo, error) {
+	stater, ok := t.Provider.(provider.Stater)
+	if !ok {
+		return nil, nil
+	}
+
+	info, err := stater.Stat(remotePath)
+	if errors.Is(err, provider.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取远程文件信息失败: %w", err)
+	}
+	return info, nil
+}
+
+// publish 发布同步事件
+func (s *Syncer) publish(t Target, kind events.Kind, action ActionType, rel, message string) {
+	events.Publish(events.Event{
+		Kind:     kind,
+		Provider: t.Config.Name,
+		Path:     rel,
+		Action:   string(action),
+		Message:  message,
+	})
+}
+
+// relPath 获取相对于监听目录的路径（统一使用 / 分隔）
+func (s *Syncer) relPath(localPath string) (string, error) {
+	rel, err := filepath.Rel(s.watchDir, localPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// RemotePath 获取远程路径
+func RemotePath(localPath, watchDir, targetDir string) string {
+	// 获取相对路径
+	relPath, err := filepath.Rel(watchDir, localPath)
+	if err != nil {
+		return filepath.Join(targetDir, filepath.Base(localPath))
+	}
+
+	// 确保目标目录以 / 结尾
+	targetDir = strings.TrimSuffix(targetDir, "/")
+	if targetDir != "" && !strings.HasSuffix(targetDir, "/") {
+		targetDir += "/"
+	}
+
+	return targetDir + filepath.ToSlash(relPath)
+}
+
+// fingerprint 计算远程文件指纹，优先使用云盘提供的内容哈希
+func fingerprint(info *provider.FileInfo) string {
+	if info.Hash != "" {
+		return info.Hash
+	}
+	return fmt.Sprintf("%d:%d", info.Size, info.ModTime.Unix())
+}
+
+// conflictID 生成冲突 ID
+func conflictID(providerName, rel string) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%d", providerName, rel, time.Now().UnixNano())))
+	return hex.EncodeToString(sum[:6])
+}
+
+// hashFile 计算文件 SHA1
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
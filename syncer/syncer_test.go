@@ -0,0 +1,210 @@
+package syncer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"CloudFileSync/config"
+	"CloudFileSync/provider"
+	"CloudFileSync/syncer"
+)
+
+// uploadOnly 不支持下载的云盘，只保留上传和查询能力
+type uploadOnly struct {
+	provider.Provider
+	provider.Stater
+}
+
+// fixture 同步到本地目录的测试环境
+type fixture struct {
+	syncer   *syncer.Syncer
+	target   syncer.Target
+	watchDir string
+	root     string
+}
+
+// newFixture 创建使用指定冲突策略的同步器，wrap 可替换云盘实现
+func newFixture(t *testing.T, strategy syncer.Strategy, wrap func(provider.Provider) provider.Provider) *fixture {
+	t.Helper()
+
+	f := &fixture{watchDir: t.TempDir(), root: t.TempDir()}
+	pc := config.ProviderConfig{
+		Type:   "local",
+		Name:   "local",
+		Enable: true,
+		Tokens: map[string]string{"root": f.root},
+		Target: "/",
+	}
+	p, err := provider.NewProvider(pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrap != nil {
+		p = wrap(p)
+	}
+	f.target = syncer.Target{Config: pc, Provider: p}
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	state, err := syncer.LoadState(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.syncer, err = syncer.New(&config.Config{
+		WatchDir:         f.watchDir,
+		StateFile:        stateFile,
+		ConflictStrategy: string(strategy),
+	}, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// local 返回监听目录中的文件路径
+func (f *fixture) local(rel string) string {
+	return filepath.Join(f.watchDir, filepath.FromSlash(rel))
+}
+
+// remote 返回云盘目录中的文件路径
+func (f *fixture) remote(rel string) string {
+	return filepath.Join(f.root, filepath.FromSlash(rel))
+}
+
+// sync 同步一个文件
+func (f *fixture) sync(t *testing.T, rel string) {
+	t.Helper()
+	if err := f.syncer.SyncFile(f.target, f.local(rel), false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// conflict 同步 a.txt 后分别修改本地和远程，再次同步产生冲突
+func (f *fixture) conflict(t *testing.T) {
+	t.Helper()
+
+	writeFile(t, f.local("a.txt"), "v1")
+	f.sync(t, "a.txt")
+
+	writeFile(t, f.remote("a.txt"), "remote v2")
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(f.remote("a.txt"), later, later)
+	writeFile(t, f.local("a.txt"), "local v2")
+
+	f.sync(t, "a.txt")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s 内容为 %q，期望 %q", path, data, want)
+	}
+}
+
+func TestConflictLocal(t *testing.T) {
+	f := newFixture(t, syncer.StrategyLocal, nil)
+	f.conflict(t)
+
+	assertFile(t, f.local("a.txt"), "local v2")
+	assertFile(t, f.remote("a.txt"), "local v2")
+	if n := len(f.syncer.State().Conflicts()); n != 0 {
+		t.Errorf("待处理冲突 %d 条，期望 0", n)
+	}
+}
+
+func TestConflictRemote(t *testing.T) {
+	f := newFixture(t, syncer.StrategyRemote, nil)
+	f.conflict(t)
+
+	assertFile(t, f.local("a.txt"), "remote v2")
+	assertFile(t, f.remote("a.txt"), "remote v2")
+}
+
+func TestConflictNewest(t *testing.T) {
+	f := newFixture(t, syncer.StrategyNewest, nil)
+	f.conflict(t)
+
+	// 远程修改时间更晚
+	assertFile(t, f.local("a.txt"), "remote v2")
+}
+
+func TestConflictKeepBoth(t *testing.T) {
+	f := newFixture(t, syncer.StrategyKeepBoth, nil)
+	f.conflict(t)
+
+	assertFile(t, f.local("a.txt"), "remote v2")
+	assertFile(t, f.remote("a.txt"), "remote v2")
+
+	copies, _ := filepath.Glob(filepath.Join(f.watchDir, "a (conflict *).txt"))
+	if len(copies) != 1 {
+		t.Fatalf("冲突副本 %v，期望 1 个", copies)
+	}
+	assertFile(t, copies[0], "local v2")
+	// 冲突副本也同步到了云盘
+	assertFile(t, f.remote(filepath.Base(copies[0])), "local v2")
+}
+
+func TestConflictManual(t *testing.T) {
+	f := newFixture(t, syncer.StrategyManual, nil)
+	f.conflict(t)
+
+	assertFile(t, f.local("a.txt"), "local v2")
+	assertFile(t, f.remote("a.txt"), "remote v2")
+
+	conflicts := f.syncer.State().Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Path != "a.txt" {
+		t.Fatalf("待处理冲突 %+v，期望 a.txt", conflicts)
+	}
+
+	if err := f.syncer.Resolve(f.target, conflicts[0].ID, syncer.StrategyManual); err == nil {
+		t.Error("Resolve 不应接受 manual")
+	}
+	if err := f.syncer.Resolve(f.target, conflicts[0].ID, syncer.StrategyRemote); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, f.local("a.txt"), "remote v2")
+	if n := len(f.syncer.State().Conflicts()); n != 0 {
+		t.Errorf("处理后仍有 %d 条冲突", n)
+	}
+}
+
+func TestConflictWithoutDownloader(t *testing.T) {
+	f := newFixture(t, syncer.StrategyKeepBoth, func(p provider.Provider) provider.Provider {
+		return uploadOnly{p, p.(provider.Stater)}
+	})
+	f.conflict(t)
+
+	// 无法下载远程版本，改为等待手动处理，两边都保持原样
+	assertFile(t, f.local("a.txt"), "local v2")
+	assertFile(t, f.remote("a.txt"), "remote v2")
+
+	conflicts := f.syncer.State().Conflicts()
+	if len(conflicts) != 1 {
+		t.Fatalf("待处理冲突 %d 条，期望 1", len(conflicts))
+	}
+
+	err := f.syncer.Resolve(f.target, conflicts[0].ID, syncer.StrategyKeepBoth)
+	if err == nil || !strings.Contains(err.Error(), "不支持下载") {
+		t.Errorf("Resolve(keep_both) = %v，期望不支持下载", err)
+	}
+	if err := f.syncer.Resolve(f.target, conflicts[0].ID, syncer.StrategyLocal); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, f.remote("a.txt"), "local v2")
+}
@@ -4,9 +4,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
+	"CloudFileSync/events"
+
 	"github.com/fsnotify/fsnotify"
 )
 
@@ -22,11 +25,18 @@ type Watcher struct {
 	fsWatcher *fsnotify.Watcher
 	watchDir  string
 	eventChan chan FileEvent
+	delayMu   sync.RWMutex
 	delay     time.Duration
-	timerMap  sync.Map // map[string]*time.Timer
+	timerMap  sync.Map // map[string]*pendingEvent
 	stopChan  chan struct{}
 }
 
+// pendingEvent 防抖等待中的事件
+type pendingEvent struct {
+	timer *time.Timer
+	event FileEvent
+}
+
 // NewWatcher 创建新的文件监听器
 func NewWatcher(watchDir string, delay time.Duration) (*Watcher, error) {
 	fsWatcher, err := fsnotify.NewWatcher()
@@ -109,6 +119,8 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	}
 
 	log.Printf("检测到文件变化: %s [%s]", event.Name, event.Op)
+	rel := w.relPath(event.Name)
+	events.Publish(events.Event{Kind: events.KindDetected, Path: rel, Message: event.Op.String()})
 
 	// 如果是创建目录，则监听新目录
 	if event.Op&fsnotify.Create == fsnotify.Create {
@@ -126,17 +138,42 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	}
 
 	// 取消之前的定时器
-	if timer, exists := w.timerMap.Load(event.Name); exists {
-		timer.(*time.Timer).Stop()
+	if prev, exists := w.timerMap.Load(event.Name); exists {
+		prev.(*pendingEvent).timer.Stop()
 	}
 
-	// 创建新的延迟定时器
-	timer := time.AfterFunc(w.delay, func() {
+	// 创建新的延迟定时器，只删除自己对应的记录，不影响之后的事件
+	pending := &pendingEvent{event: fileEvent}
+	pending.timer = time.AfterFunc(w.Delay(), func() {
+		events.Publish(events.Event{Kind: events.KindQueued, Path: rel})
 		w.eventChan <- fileEvent
-		w.timerMap.Delete(event.Name)
+		w.timerMap.CompareAndDelete(event.Name, pending)
 	})
 
-	w.timerMap.Store(event.Name, timer)
+	w.timerMap.Store(event.Name, pending)
+}
+
+// relPath 返回相对于监听目录的路径，使用 / 分隔
+func (w *Watcher) relPath(path string) string {
+	rel, err := filepath.Rel(w.watchDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Delay 返回防抖延迟
+func (w *Watcher) Delay() time.Duration {
+	w.delayMu.RLock()
+	defer w.delayMu.RUnlock()
+	return w.delay
+}
+
+// SetDelay 修改防抖延迟，对之后的文件变化生效，已在等待中的事件按原延迟发出
+func (w *Watcher) SetDelay(delay time.Duration) {
+	w.delayMu.Lock()
+	w.delay = delay
+	w.delayMu.Unlock()
 }
 
 // Events 返回事件通道
@@ -144,17 +181,24 @@ func (w *Watcher) Events() <-chan FileEvent {
 	return w.eventChan
 }
 
-// Stop 停止监听
-func (w *Watcher) Stop() {
+// Stop 停止监听，返回防抖等待中尚未发出的事件（按发生时间排序），调用方自行处理以免丢失。
+// 已经发出的事件仍在 Events 通道中
+func (w *Watcher) Stop() []FileEvent {
 	close(w.stopChan)
 	w.fsWatcher.Close()
 
-	// 停止所有定时器
+	// 停止所有定时器，未触发的事件直接返回
+	var pending []FileEvent
 	w.timerMap.Range(func(key, value interface{}) bool {
-		if timer, ok := value.(*time.Timer); ok {
-			timer.Stop()
+		p := value.(*pendingEvent)
+		if p.timer.Stop() {
+			events.Publish(events.Event{Kind: events.KindQueued, Path: w.relPath(p.event.Path)})
+			pending = append(pending, p.event)
 		}
 		w.timerMap.Delete(key)
 		return true
 	})
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Timestamp.Before(pending[j].Timestamp) })
+	return pending
 }
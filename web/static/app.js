@@ -11,6 +11,72 @@ let currentConfig = {
 
 let serviceRunning = false;
 let editingProviderIndex = null;
+let editingProviderTokens = null;
+let providerTypes = {};
+// 当前会话的 CSRF 令牌，POST 请求放在 X-CSRF-Token 请求头中
+let csrfToken = '';
+// 同步事件流及最后收到的事件 ID，重新连接时从该 ID 之后继续
+let eventSource = null;
+let lastEventId = 0;
+
+// 日志最多保留的条数
+const maxLogItems = 500;
+
+// 同步历史的当前页码、每页条数，以及搜索和自动刷新的防抖定时器
+let historyPage = 1;
+const historyPageSize = 20;
+let historySearchTimer = null;
+let historyRefreshTimer = null;
+
+// 同步事件类型的显示名称
+const eventKindLabels = {
+    detected: '检测到变化',
+    queued: '进入队列',
+    uploading: '上传中',
+    done: '完成',
+    skipped: '跳过',
+    conflict: '冲突',
+    failed: '失败'
+};
+
+// 同步动作和结果的显示名称
+const historyActionLabels = {
+    upload: '上传',
+    download: '下载',
+    delete: '删除',
+    mkdir: '创建目录',
+    move: '移动'
+};
+const historyResultLabels = {
+    success: '成功',
+    failed: '失败',
+    skipped: '跳过'
+};
+
+// 界面日志类型对应的级别，与同步事件的级别一起筛选
+const logTypeLevels = {
+    info: 'info',
+    success: 'info',
+    warning: 'warn',
+    error: 'error'
+};
+// 保存时服务端返回的云盘字段错误（云盘配置 -> [{field, message}]），编辑或删除后随对象一起失效
+let providerErrors = new WeakMap();
+
+// 配置字段路径与页面输入框的对应关系
+const configFieldInputs = {
+    watch_dir: 'watchDirInput',
+    delay_time: 'delayTime',
+    conflict_strategy: 'conflictStrategy'
+};
+
+// 云盘字段路径（去掉 providers[i]. 前缀）与编辑表单输入框的对应关系，tokens.<key> 对应 token_<key>
+const providerFieldInputs = {
+    type: 'providerType',
+    name: 'providerName',
+    target: 'providerTarget',
+    tokens: 'providerFieldsBody'
+};
 
 // 初始化
 document.addEventListener('DOMContentLoaded', function() {
@@ -21,13 +87,141 @@ document.addEventListener('DOMContentLoaded', function() {
         document.body.style.opacity = '1';
     }, 100);
 
-    loadConfig();
-    loadServiceStatus();
     setupEventListeners();
     setupKeyboardShortcuts();
     setupFormValidation();
+    initSession();
 });
 
+// 加载页面数据
+function loadAll() {
+    loadProviderTypes();
+    loadConfig();
+    loadServiceStatus();
+    loadConflicts();
+    loadHistory();
+    connectEvents();
+}
+
+// 订阅服务端的同步事件流。网络中断时浏览器自动重连；连接被关闭（如登录过期）时稍后重新连接
+function connectEvents() {
+    if (eventSource || !csrfToken) return;
+
+    eventSource = new EventSource('/api/events/stream?since=' + lastEventId);
+    eventSource.onmessage = function(e) {
+        const event = JSON.parse(e.data);
+        lastEventId = event.id;
+        addEventLog(event);
+    };
+    eventSource.onerror = function() {
+        if (eventSource.readyState === EventSource.CLOSED) {
+            eventSource = null;
+            setTimeout(connectEvents, 5000);
+        }
+    };
+}
+
+// 断开同步事件流
+function disconnectEvents() {
+    if (eventSource) {
+        eventSource.close();
+        eventSource = null;
+    }
+}
+
+// 获取会话状态，已登录（或未启用登录）时加载页面数据，否则显示登录框
+async function initSession() {
+    try {
+        const response = await fetch('/api/session');
+        const result = await response.json();
+        if (result.code !== 0) {
+            throw new Error(result.message);
+        }
+
+        document.getElementById('btnLogout').style.display = result.data.auth_required ? '' : 'none';
+        if (result.data.authenticated) {
+            csrfToken = result.data.csrf_token;
+            loadAll();
+        } else {
+            showLoginModal();
+        }
+    } catch (error) {
+        addLog('获取会话失败: ' + error.message, 'error');
+        showToast('获取会话失败', 'error');
+    }
+}
+
+// 请求 API：非 GET 请求带上 CSRF 令牌，未登录或登录过期时显示登录框
+async function apiFetch(url, options = {}) {
+    const method = (options.method || 'GET').toUpperCase();
+    if (method !== 'GET' && method !== 'HEAD') {
+        options.headers = Object.assign({}, options.headers, { 'X-CSRF-Token': csrfToken });
+    }
+
+    const response = await fetch(url, options);
+    if (response.status === 401) {
+        showLoginModal();
+    }
+    return response;
+}
+
+// 显示登录框
+function showLoginModal() {
+    const modal = document.getElementById('loginModal');
+    if (modal.classList.contains('show')) return;
+
+    document.getElementById('loginForm').reset();
+    document.getElementById('loginError').textContent = '';
+    modal.classList.add('show');
+    document.body.style.overflow = 'hidden';
+    document.getElementById('loginUsername').focus();
+}
+
+// 登录
+async function login() {
+    const errorText = document.getElementById('loginError');
+    errorText.textContent = '';
+
+    try {
+        const response = await fetch('/api/login', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({
+                username: document.getElementById('loginUsername').value.trim(),
+                password: document.getElementById('loginPassword').value,
+                token: document.getElementById('loginToken').value.trim()
+            })
+        });
+        const result = await response.json();
+
+        if (result.code !== 0) {
+            errorText.textContent = result.message;
+            return;
+        }
+
+        csrfToken = result.data.csrf_token;
+        document.getElementById('loginModal').classList.remove('show');
+        document.body.style.overflow = '';
+        addLog('登录成功', 'success');
+        loadAll();
+    } catch (error) {
+        errorText.textContent = '登录失败: ' + error.message;
+    }
+}
+
+// 退出登录
+async function logout() {
+    try {
+        await apiFetch('/api/logout', { method: 'POST' });
+    } finally {
+        csrfToken = '';
+        disconnectEvents();
+        showLoginModal();
+    }
+}
+
 // 设置事件监听
 function setupEventListeners() {
     // 添加云盘按钮
@@ -51,6 +245,32 @@ function setupEventListeners() {
     // 清空日志
     document.getElementById('btnClearLog').addEventListener('click', clearLog);
 
+    // 日志筛选
+    document.getElementById('logProviderFilter').addEventListener('change', applyLogFilter);
+    document.getElementById('logLevelFilter').addEventListener('change', applyLogFilter);
+
+    // 登录、退出登录
+    document.getElementById('loginForm').addEventListener('submit', function(e) {
+        e.preventDefault();
+        login();
+    });
+    document.getElementById('btnLogout').addEventListener('click', logout);
+
+    // 刷新冲突列表
+    document.getElementById('btnRefreshConflicts').addEventListener('click', loadConflicts);
+
+    // 同步历史：筛选条件变化时回到第一页，搜索输入停顿后再查询
+    document.getElementById('btnRefreshHistory').addEventListener('click', () => loadHistory());
+    ['historyProviderFilter', 'historyActionFilter', 'historyResultFilter'].forEach(id => {
+        document.getElementById(id).addEventListener('change', () => loadHistory(1));
+    });
+    document.getElementById('historySearch').addEventListener('input', function() {
+        clearTimeout(historySearchTimer);
+        historySearchTimer = setTimeout(() => loadHistory(1), 300);
+    });
+    document.getElementById('btnHistoryPrev').addEventListener('click', () => loadHistory(historyPage - 1));
+    document.getElementById('btnHistoryNext').addEventListener('click', () => loadHistory(historyPage + 1));
+
     // 模态框关闭
     document.querySelector('.modal-close').addEventListener('click', closeProviderModal);
 
@@ -64,20 +284,12 @@ function setupEventListeners() {
 
     // 云盘类型选择
     document.getElementById('providerType').addEventListener('change', function() {
-        const type = this.value;
-        const configs = ['aliyun', 'baidu', '115', 'onedrive'];
-
-        configs.forEach(configType => {
-            const configElement = document.getElementById(configType + 'Config');
-            if (configElement) {
-                configElement.style.display = type === configType ? 'block' : 'none';
-            }
-        });
+        renderProviderFields(this.value, {});
 
         // 添加动画效果
-        const activeConfig = document.getElementById(type + 'Config');
-        if (activeConfig && type) {
-            activeConfig.style.animation = 'fadeIn 0.3s ease';
+        const fields = document.getElementById('providerFields');
+        if (this.value) {
+            fields.style.animation = 'fadeIn 0.3s ease';
         }
     });
 
@@ -234,11 +446,12 @@ async function loadConfig() {
     try {
         addLog('正在加载配置...', 'info');
 
-        const response = await fetch('/api/config');
+        const response = await apiFetch('/api/config');
         const result = await response.json();
 
         if (result.code === 0) {
             currentConfig = result.data;
+            currentConfig.providers.forEach(p => addLogProviderOption(p.name));
             updateUI();
             renderProviders();
             addLog('配置加载成功', 'success');
@@ -255,10 +468,12 @@ async function loadConfig() {
 function updateUI() {
     const watchDirInput = document.getElementById('watchDirInput');
     const delayTimeInput = document.getElementById('delayTime');
+    const conflictStrategyInput = document.getElementById('conflictStrategy');
 
     // 添加淡入动画
     animateValue(watchDirInput, currentConfig.watch_dir || '');
     animateValue(delayTimeInput, currentConfig.delay_time || 5);
+    animateValue(conflictStrategyInput, currentConfig.conflict_strategy || 'keep_both');
 }
 
 // 数值/文本变化动画
@@ -300,26 +515,14 @@ function renderProviders() {
 
 // 创建云盘列表项
 function createProviderItem(provider, index) {
+    const errors = providerErrors.get(provider) || [];
     const div = document.createElement('div');
-    div.className = 'provider-item' + (provider.enable ? '' : ' disabled');
+    div.className = 'provider-item' + (provider.enable ? '' : ' disabled') + (errors.length ? ' has-error' : '');
     div.style.animation = `fadeInUp 0.4s ease ${index * 0.1}s backwards`;
 
-    const icons = {
-        aliyun: '☁️',
-        baidu: '📦',
-        '115': '💎',
-        onedrive: '🌐'
-    };
-
-    const typeNames = {
-        aliyun: '阿里云盘',
-        baidu: '百度网盘',
-        '115': '115网盘',
-        onedrive: 'OneDrive'
-    };
-
-    const icon = icons[provider.type] || '☁️';
-    const typeName = typeNames[provider.type] || provider.type;
+    const schema = providerTypes[provider.type] || {};
+    const icon = schema.icon || '☁️';
+    const typeName = schema.display_name || provider.type;
 
     div.innerHTML = `
         <div class="provider-header">
@@ -334,6 +537,11 @@ function createProviderItem(provider, index) {
                         '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><rect x="6" y="4" width="4" height="16"></rect><rect x="14" y="4" width="4" height="16"></rect></svg> 禁用' :
                         '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><polygon points="5 3 19 12 5 21 5 3"></polygon></svg> 启用'}
                 </button>
+                ${schema.oauth ? `
+                <button class="btn btn-secondary btn-small" onclick="authorizeProvider(${index})">
+                    <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><rect x="3" y="11" width="18" height="11" rx="2" ry="2"></rect><path d="M7 11V7a5 5 0 0 1 10 0v4"></path></svg>
+                    授权
+                </button>` : ''}
                 <button class="btn btn-secondary btn-small" onclick="editProvider(${index})">
                     <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M11 4H4a2 2 0 0 0-2 2v14a2 2 0 0 0 2 2h14a2 2 0 0 0 2-2v-7"></path><path d="M18.5 2.5a2.121 2.121 0 0 1 3 3L12 15l-4 1 1-4 9.5-9.5z"></path></svg>
                     编辑
@@ -355,14 +563,34 @@ function createProviderItem(provider, index) {
             </div>
             <div class="info-item">
                 <span class="info-label">Token</span>
-                <span class="info-value">${maskToken(provider.tokens?.access_token || '')}</span>
+                <span class="info-value">${providerSummary(provider, schema)}</span>
             </div>
         </div>
+        ${errors.length ? `
+        <ul class="provider-errors">
+            ${errors.map(err => `<li>${escapeHtml(err.message)}</li>`).join('')}
+        </ul>` : ''}
     `;
 
     return div;
 }
 
+// 云盘列表中显示的摘要：第一个必填的非密码字段，否则显示掩码后的 access_token
+function providerSummary(provider, schema) {
+    const field = (schema.fields || []).find(f => f.required && f.type !== 'password');
+    if (field) {
+        return escapeHtml(provider.tokens?.[field.key] || '-');
+    }
+    return maskToken(provider.tokens?.access_token || '');
+}
+
+// 转义 HTML
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
 // 掩码 Token
 function maskToken(token) {
     if (!token || token.length < 8) return '***';
@@ -396,9 +624,10 @@ async function saveConfig() {
 
     currentConfig.watch_dir = watchDir;
     currentConfig.delay_time = delayTime;
+    currentConfig.conflict_strategy = document.getElementById('conflictStrategy').value;
 
     try {
-        const response = await fetch('/api/config/save', {
+        const response = await apiFetch('/api/config/save', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'
@@ -415,6 +644,7 @@ async function saveConfig() {
         } else {
             showToast('保存失败: ' + result.message, 'error');
         }
+        applyValidationErrors(Array.isArray(result.data) ? result.data : []);
     } catch (error) {
         showToast('保存失败: ' + error.message, 'error');
         addLog('保存配置失败: ' + error.message, 'error');
@@ -423,6 +653,50 @@ async function saveConfig() {
     }
 }
 
+// 在页面中标出服务端返回的字段错误，errors 为空时清除已有的标记
+function applyValidationErrors(errors) {
+    Object.values(configFieldInputs).forEach(id => clearInputError(document.getElementById(id)));
+    providerErrors = new WeakMap();
+
+    errors.forEach(err => {
+        const match = /^providers\[(\d+)\]\.(.+)$/.exec(err.field);
+        if (match) {
+            const provider = currentConfig.providers[parseInt(match[1])];
+            if (!provider) return;
+            const list = providerErrors.get(provider) || [];
+            list.push({ field: match[2], message: err.message });
+            providerErrors.set(provider, list);
+            addLog(`云盘「${provider.name}」${err.message}`, 'error');
+        } else if (configFieldInputs[err.field]) {
+            showInputError(document.getElementById(configFieldInputs[err.field]), err.message);
+            addLog(err.message, 'error');
+        }
+    });
+
+    renderProviders();
+}
+
+// 在编辑表单中标出云盘的字段错误
+function showProviderErrors(errors) {
+    (errors || []).forEach(err => {
+        const id = err.field.startsWith('tokens.')
+            ? 'token_' + err.field.substring('tokens.'.length)
+            : providerFieldInputs[err.field];
+        const input = id && document.getElementById(id);
+        if (input) {
+            showInputError(input, err.message);
+        }
+    });
+}
+
+// 清除编辑表单中的错误标记
+function clearProviderErrors() {
+    document.querySelectorAll('#providerModal .input-error').forEach(el => el.remove());
+    document.querySelectorAll('#providerModal input, #providerModal select').forEach(input => {
+        input.style.borderColor = '';
+    });
+}
+
 // 重置保存按钮
 function resetSaveButton(button, originalText) {
     setTimeout(() => {
@@ -434,7 +708,7 @@ function resetSaveButton(button, originalText) {
 // 加载服务状态
 async function loadServiceStatus() {
     try {
-        const response = await fetch('/api/service/status');
+        const response = await apiFetch('/api/service/status');
         const result = await response.json();
 
         if (result.code === 0) {
@@ -475,7 +749,7 @@ async function startService() {
     btnStart.innerHTML = '<span class="loading-spinner"></span> 启动中...';
 
     try {
-        const response = await fetch('/api/service/start', {
+        const response = await apiFetch('/api/service/start', {
             method: 'POST'
         });
 
@@ -487,6 +761,9 @@ async function startService() {
             loadServiceStatus();
         } else {
             showToast('启动失败: ' + result.message, 'error');
+            if (Array.isArray(result.data)) {
+                applyValidationErrors(result.data);
+            }
         }
     } catch (error) {
         showToast('启动失败: ' + error.message, 'error');
@@ -503,7 +780,7 @@ async function stopService() {
     btnStop.innerHTML = '<span class="loading-spinner"></span> 停止中...';
 
     try {
-        const response = await fetch('/api/service/stop', {
+        const response = await apiFetch('/api/service/stop', {
             method: 'POST'
         });
 
@@ -524,18 +801,234 @@ async function stopService() {
     }
 }
 
+// 加载待处理冲突
+async function loadConflicts() {
+    try {
+        const response = await apiFetch('/api/conflicts');
+        const result = await response.json();
+
+        if (result.code === 0) {
+            renderConflicts(result.data || []);
+        } else {
+            throw new Error(result.message);
+        }
+    } catch (error) {
+        addLog('获取冲突列表失败: ' + error.message, 'error');
+    }
+}
+
+// 渲染冲突列表
+function renderConflicts(conflicts) {
+    const container = document.getElementById('conflictsList');
+    container.innerHTML = '';
+
+    if (conflicts.length === 0) {
+        container.innerHTML = `
+            <div class="empty-state">
+                <div class="empty-state-icon">✅</div>
+                <div class="empty-state-text">没有待处理的冲突</div>
+            </div>
+        `;
+        return;
+    }
+
+    conflicts.forEach((conflict, index) => {
+        const div = document.createElement('div');
+        div.className = 'provider-item';
+        div.style.animation = `fadeInUp 0.4s ease ${index * 0.1}s backwards`;
+
+        // 路径、云盘名称来自文件名和配置，只通过 textContent 写入
+        div.innerHTML = `
+            <div class="provider-header">
+                <div class="provider-title">
+                    <span class="provider-icon">⚠️</span>
+                    <span class="conflict-path"></span>
+                    <span class="provider-badge" style="background: var(--warning-color);"></span>
+                </div>
+                <div class="provider-actions"></div>
+            </div>
+            <div class="provider-info"></div>
+        `;
+        div.querySelector('.conflict-path').textContent = conflict.path;
+        div.querySelector('.provider-badge').textContent = conflict.provider;
+
+        const actions = div.querySelector('.provider-actions');
+        [
+            ['local', '保留本地', 'btn-secondary'],
+            ['remote', '保留云端', 'btn-secondary'],
+            ['keep_both', '保留两者', 'btn-primary']
+        ].forEach(([strategy, label, style]) => {
+            const button = document.createElement('button');
+            button.className = `btn ${style} btn-small`;
+            button.textContent = label;
+            button.addEventListener('click', () => resolveConflict(conflict.id, strategy));
+            actions.appendChild(button);
+        });
+
+        const info = div.querySelector('.provider-info');
+        [
+            ['本地修改时间', conflict.local_mod_time],
+            ['云端修改时间', conflict.remote_mod_time],
+            ['发现时间', conflict.detected_at]
+        ].forEach(([label, time]) => {
+            const item = document.createElement('div');
+            item.className = 'info-item';
+            const labelSpan = document.createElement('span');
+            labelSpan.className = 'info-label';
+            labelSpan.textContent = label;
+            const valueSpan = document.createElement('span');
+            valueSpan.className = 'info-value';
+            valueSpan.textContent = formatTime(time);
+            item.appendChild(labelSpan);
+            item.appendChild(valueSpan);
+            info.appendChild(item);
+        });
+
+        container.appendChild(div);
+    });
+}
+
+// 处理冲突
+async function resolveConflict(id, strategy) {
+    try {
+        const response = await apiFetch('/api/conflicts/resolve', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({
+                id: id,
+                strategy: strategy
+            })
+        });
+
+        const result = await response.json();
+
+        if (result.code === 0) {
+            showToast('冲突已处理', 'success');
+            addLog('冲突已处理', 'success');
+            loadConflicts();
+        } else {
+            showToast('处理失败: ' + result.message, 'error');
+        }
+    } catch (error) {
+        showToast('处理失败: ' + error.message, 'error');
+    }
+}
+
+// 加载同步历史，page 为空时刷新当前页
+async function loadHistory(page) {
+    if (page) historyPage = page;
+
+    const params = new URLSearchParams({ page: historyPage, page_size: historyPageSize });
+    const filters = {
+        q: document.getElementById('historySearch').value.trim(),
+        provider: document.getElementById('historyProviderFilter').value,
+        action: document.getElementById('historyActionFilter').value,
+        result: document.getElementById('historyResultFilter').value
+    };
+    Object.entries(filters).forEach(([key, value]) => {
+        if (value) params.set(key, value);
+    });
+
+    try {
+        const response = await apiFetch('/api/history?' + params);
+        const result = await response.json();
+
+        if (result.code === 0) {
+            renderHistory(result.data);
+        } else {
+            throw new Error(result.message);
+        }
+    } catch (error) {
+        addLog('获取同步历史失败: ' + error.message, 'error');
+    }
+}
+
+// 渲染同步历史表格和分页
+function renderHistory(data) {
+    const body = document.getElementById('historyBody');
+    body.innerHTML = '';
+
+    if (data.records.length === 0) {
+        const row = body.insertRow();
+        const cell = row.insertCell();
+        cell.colSpan = 7;
+        cell.className = 'history-empty';
+        cell.textContent = '没有同步记录';
+    }
+
+    data.records.forEach(record => {
+        const row = body.insertRow();
+        const path = record.from ? record.from + ' → ' + record.path : record.path;
+        const cells = [
+            formatTime(record.time),
+            record.provider,
+            historyActionLabels[record.action] || record.action,
+            path,
+            record.bytes ? formatBytes(record.bytes) : '-',
+            formatDuration(record.duration_ms),
+            historyResultLabels[record.result] || record.result
+        ];
+        cells.forEach(text => {
+            row.insertCell().textContent = text;
+        });
+
+        row.cells[3].className = 'history-path';
+        row.cells[3].title = path;
+        const resultCell = row.cells[6];
+        resultCell.className = 'history-result history-' + record.result;
+        if (record.error) resultCell.title = record.error;
+
+        addLogProviderOption(record.provider);
+    });
+
+    const pages = Math.max(1, Math.ceil(data.total / data.page_size));
+    historyPage = data.page;
+    document.getElementById('historyPageInfo').textContent = `第 ${data.page} / ${pages} 页，共 ${data.total} 条`;
+    document.getElementById('btnHistoryPrev').disabled = data.page <= 1;
+    document.getElementById('btnHistoryNext').disabled = data.page >= pages;
+}
+
+// 格式化耗时
+function formatDuration(ms) {
+    if (!ms) return '-';
+    if (ms < 1000) return ms + 'ms';
+    return (ms / 1000).toFixed(1) + 's';
+}
+
+// 格式化文件大小
+function formatBytes(size) {
+    if (!size) return '0B';
+    const units = ['B', 'KB', 'MB', 'GB', 'TB', 'PB'];
+    let i = 0;
+    while (size >= 1024 && i < units.length - 1) {
+        size /= 1024;
+        i++;
+    }
+    return (i === 0 ? size : size.toFixed(1)) + units[i];
+}
+
+// 格式化时间
+function formatTime(value) {
+    if (!value) return '-';
+    const date = new Date(value);
+    if (isNaN(date.getTime()) || date.getFullYear() <= 1970) return '-';
+    return date.toLocaleString('zh-CN', { hour12: false });
+}
+
 // 打开添加云盘模态框
 function openProviderModal() {
     editingProviderIndex = null;
+    editingProviderTokens = null;
     const modal = document.getElementById('providerModal');
     const modalTitle = modal.querySelector('h3');
 
     modalTitle.innerHTML = '添加云盘';
 
     document.getElementById('providerForm').reset();
-    document.querySelectorAll('.provider-config').forEach(config => {
-        config.style.display = 'none';
-    });
+    clearProviderErrors();
+    renderProviderFields('', {});
     modal.classList.add('show');
     document.body.style.overflow = 'hidden';
 
@@ -563,34 +1056,8 @@ async function verifyProvider() {
         return;
     }
 
-    let tokens = {};
-
-    if (type === 'aliyun') {
-        const accessToken = document.getElementById('aliyunAccessToken').value.trim();
-        const driveId = document.getElementById('aliyunDriveId').value.trim();
-
-        if (!accessToken || !driveId) {
-            showToast('请填写完整的阿里云盘配置', 'error');
-            return;
-        }
-
-        tokens = {
-            access_token: accessToken,
-            drive_id: driveId
-        };
-    } else if (type === 'baidu') {
-        const accessToken = document.getElementById('baiduAccessToken').value.trim();
-
-        if (!accessToken) {
-            showToast('请填写百度网盘 Access Token', 'error');
-            return;
-        }
-
-        tokens = {
-            access_token: accessToken
-        };
-    } else {
-        showToast('暂不支持此云盘类型的验证', 'info');
+    const tokens = collectTokens(type);
+    if (!tokens) {
         return;
     }
 
@@ -599,13 +1066,15 @@ async function verifyProvider() {
     btnVerify.innerHTML = '<span class="loading-spinner"></span> 验证中...';
 
     try {
-        const response = await fetch('/api/provider/verify', {
+        const response = await apiFetch('/api/provider/verify', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'
             },
             body: JSON.stringify({
                 type: type,
+                // 编辑已有云盘时服务端据此换回隐藏的令牌
+                name: editingProviderTokens?.name || '',
                 tokens: tokens
             })
         });
@@ -613,8 +1082,12 @@ async function verifyProvider() {
         const result = await response.json();
 
         if (result.code === 0) {
-            showToast('验证成功', 'success');
-            addLog('云盘验证成功', 'success');
+            const info = result.data || {};
+            if (info.drive_ids) {
+                setDriveOptions(info.drive_ids, tokens.drive_id || '');
+            }
+            showToast('验证成功: ' + (info.user_name || ''), 'success');
+            addLog(`云盘验证成功，账号: ${info.user_name || '-'}，已用空间: ${formatBytes(info.used_space)} / ${formatBytes(info.total_space)}`, 'success');
         } else {
             showToast('验证失败: ' + result.message, 'error');
         }
@@ -626,6 +1099,192 @@ async function verifyProvider() {
     }
 }
 
+// 加载已注册的云盘类型
+async function loadProviderTypes() {
+    try {
+        const response = await apiFetch('/api/provider/types');
+        const result = await response.json();
+
+        if (result.code !== 0) {
+            showToast('加载云盘类型失败: ' + result.message, 'error');
+            return;
+        }
+
+        const select = document.getElementById('providerType');
+        providerTypes = {};
+        (result.data || []).forEach(item => {
+            providerTypes[item.type] = item;
+
+            // 只能在配置文件中设置的类型不能在界面中选择，已有的此类云盘仍可正常显示
+            const option = document.createElement('option');
+            option.value = item.type;
+            option.textContent = item.display_name || item.type;
+            option.disabled = !!item.config_file_only;
+            select.appendChild(option);
+        });
+
+        renderProviders();
+    } catch (error) {
+        showToast('加载云盘类型失败: ' + error.message, 'error');
+    }
+}
+
+// 根据配置描述生成云盘配置表单
+function renderProviderFields(type, tokens) {
+    const container = document.getElementById('providerFields');
+    const body = document.getElementById('providerFieldsBody');
+    const schema = providerTypes[type];
+
+    body.innerHTML = '';
+    if (!schema) {
+        container.style.display = 'none';
+        return;
+    }
+
+    document.getElementById('providerFieldsTitle').textContent = (schema.display_name || type) + '配置';
+
+    (schema.fields || []).forEach(field => {
+        const group = document.createElement('div');
+        group.className = 'form-group';
+
+        const id = 'token_' + field.key;
+        const value = tokens[field.key] || '';
+        let input;
+
+        if (field.type === 'checkbox') {
+            const label = document.createElement('label');
+            label.className = 'checkbox-label';
+            input = document.createElement('input');
+            input.type = 'checkbox';
+            input.checked = value === 'true';
+            const span = document.createElement('span');
+            span.textContent = field.label;
+            label.appendChild(input);
+            label.appendChild(span);
+            group.appendChild(label);
+        } else {
+            const label = document.createElement('label');
+            label.htmlFor = id;
+            label.textContent = field.label + (field.required ? ' *' : '');
+            group.appendChild(label);
+
+            if (field.type === 'select') {
+                input = document.createElement('select');
+                (field.options || []).forEach(opt => {
+                    const option = document.createElement('option');
+                    option.value = opt.value;
+                    option.textContent = opt.label;
+                    input.appendChild(option);
+                });
+                // 保留已配置但不在选项中的值
+                if (value && !(field.options || []).some(opt => opt.value === value)) {
+                    const option = document.createElement('option');
+                    option.value = value;
+                    option.textContent = value;
+                    input.appendChild(option);
+                }
+            } else {
+                input = document.createElement('input');
+                input.type = field.type === 'password' || field.type === 'number' ? field.type : 'text';
+                input.placeholder = field.placeholder || '';
+                input.autocomplete = 'off';
+            }
+            input.value = value;
+            group.appendChild(input);
+        }
+
+        input.id = id;
+        input.name = id;
+        input.dataset.key = field.key;
+        input.addEventListener('focus', () => group.classList.add('focused'));
+        input.addEventListener('blur', () => group.classList.remove('focused'));
+
+        if (field.help) {
+            const help = document.createElement('small');
+            help.className = 'help-text';
+            help.textContent = field.help;
+            group.appendChild(help);
+        }
+
+        body.appendChild(group);
+    });
+
+    container.style.display = 'block';
+}
+
+// 从表单收集 Tokens 并按配置描述检查必填项，不通过时返回 null
+function collectTokens(type) {
+    const schema = providerTypes[type];
+    if (!schema) {
+        showToast('不支持的云盘类型: ' + type, 'error');
+        return null;
+    }
+
+    const tokens = {};
+    (schema.fields || []).forEach(field => {
+        const input = document.getElementById('token_' + field.key);
+        if (!input) return;
+
+        let value;
+        if (field.type === 'checkbox') {
+            value = input.checked ? 'true' : '';
+        } else if (field.type === 'password') {
+            value = input.value;
+        } else {
+            value = input.value.trim();
+        }
+        if (value) {
+            tokens[field.key] = value;
+        }
+    });
+
+    const missing = (schema.fields || []).find(f => f.required && !tokens[f.key]);
+    if (missing) {
+        showToast('请填写' + missing.label, 'error');
+        return null;
+    }
+
+    if (schema.any_of && schema.any_of.length > 0 && !schema.any_of.some(key => tokens[key])) {
+        const labels = schema.any_of.map(key => {
+            const field = (schema.fields || []).find(f => f.key === key);
+            return field ? field.label : key;
+        });
+        showToast('请至少填写以下一项: ' + labels.join('、'), 'error');
+        return null;
+    }
+
+    return tokens;
+}
+
+// 设置网盘下拉列表（验证后返回的 drive_ids）
+function setDriveOptions(driveIds, selected) {
+    const select = document.getElementById('token_drive_id');
+    if (!select || select.tagName !== 'SELECT') return;
+
+    const names = {
+        default_drive_id: '默认盘',
+        resource_drive_id: '资源库',
+        backup_drive_id: '备份盘'
+    };
+
+    select.innerHTML = '<option value="">自动获取（默认盘）</option>';
+    Object.keys(driveIds).forEach(key => {
+        const option = document.createElement('option');
+        option.value = driveIds[key];
+        option.textContent = `${names[key] || key} (${driveIds[key]})`;
+        select.appendChild(option);
+    });
+
+    // 保留已配置但不在列表中的 drive_id
+    if (selected && !Object.values(driveIds).includes(selected)) {
+        const option = document.createElement('option');
+        option.value = selected;
+        option.textContent = selected;
+        select.appendChild(option);
+    }
+    select.value = selected;
+}
+
 // 添加云盘
 function addProvider() {
     const type = document.getElementById('providerType').value;
@@ -643,56 +1302,23 @@ function addProvider() {
         return;
     }
 
-    let tokens = {};
-
-    if (type === 'aliyun') {
-        const accessToken = document.getElementById('aliyunAccessToken').value.trim();
-        const driveId = document.getElementById('aliyunDriveId').value.trim();
-
-        if (!accessToken || !driveId) {
-            showToast('请填写完整的阿里云盘配置', 'error');
-            return;
-        }
-
-        tokens = {
-            access_token: accessToken,
-            drive_id: driveId
-        };
-    } else if (type === 'baidu') {
-        const accessToken = document.getElementById('baiduAccessToken').value.trim();
-
-        if (!accessToken) {
-            showToast('请填写百度网盘 Access Token', 'error');
-            return;
-        }
-
-        tokens = {
-            access_token: accessToken
-        };
-    } else if (type === '115') {
-        const accessToken = document.getElementById('115AccessToken').value.trim();
-
-        if (!accessToken) {
-            showToast('请填写115网盘 Access Token', 'error');
-            return;
-        }
-
-        tokens = {
-            access_token: accessToken
-        };
-    } else if (type === 'onedrive') {
-        const accessToken = document.getElementById('onedriveAccessToken').value.trim();
-
-        if (!accessToken) {
-            showToast('请填写OneDrive Access Token', 'error');
-            return;
-        }
+    let tokens = collectTokens(type);
+    if (!tokens) {
+        return;
+    }
 
-        tokens = {
-            access_token: accessToken,
-            refresh_token: document.getElementById('onedriveRefreshToken').value.trim()
-        };
+    // 编辑时保留表单中没有的令牌（如 refresh_token）
+    if (editingProviderTokens && editingProviderTokens.type === type) {
+        const formKeys = (providerTypes[type].fields || []).map(f => f.key);
+        const kept = {};
+        Object.keys(editingProviderTokens.tokens).forEach(key => {
+            if (!formKeys.includes(key)) {
+                kept[key] = editingProviderTokens.tokens[key];
+            }
+        });
+        tokens = Object.assign(kept, tokens);
     }
+    editingProviderTokens = null;
 
     const provider = {
         type: type,
@@ -715,6 +1341,19 @@ function addProvider() {
     showToast('云盘添加成功', 'success');
 }
 
+// 发起 OAuth 授权
+function authorizeProvider(index) {
+    const provider = currentConfig.providers[index];
+
+    if (!provider.tokens?.client_id) {
+        showToast('请先填写 Client ID 并保存配置', 'error');
+        return;
+    }
+
+    window.open(`/api/oauth/${provider.type}/start?name=${encodeURIComponent(provider.name)}`, '_blank');
+    addLog('已打开授权页面: ' + provider.name + '，授权完成后请点击「重置」重新加载配置', 'info');
+}
+
 // 切换云盘启用状态
 function toggleProvider(index) {
     currentConfig.providers[index].enable = !currentConfig.providers[index].enable;
@@ -727,6 +1366,7 @@ function toggleProvider(index) {
 function editProvider(index) {
     editingProviderIndex = index;
     const provider = currentConfig.providers[index];
+    editingProviderTokens = { type: provider.type, name: provider.name, tokens: Object.assign({}, provider.tokens) };
 
     const modal = document.getElementById('providerModal');
     const modalTitle = modal.querySelector('h3');
@@ -738,20 +1378,10 @@ function editProvider(index) {
     document.getElementById('providerTarget').value = provider.target;
     document.getElementById('providerEnable').checked = provider.enable;
 
-    // 触发change事件以显示正确的配置
-    document.getElementById('providerType').dispatchEvent(new Event('change'));
-
-    if (provider.type === 'aliyun') {
-        document.getElementById('aliyunAccessToken').value = provider.tokens.access_token || '';
-        document.getElementById('aliyunDriveId').value = provider.tokens.drive_id || '';
-    } else if (provider.type === 'baidu') {
-        document.getElementById('baiduAccessToken').value = provider.tokens.access_token || '';
-    } else if (provider.type === '115') {
-        document.getElementById('115AccessToken').value = provider.tokens.access_token || '';
-    } else if (provider.type === 'onedrive') {
-        document.getElementById('onedriveAccessToken').value = provider.tokens.access_token || '';
-        document.getElementById('onedriveRefreshToken').value = provider.tokens.refresh_token || '';
-    }
+    // 根据配置描述生成表单并填入已有的令牌
+    renderProviderFields(provider.type, provider.tokens || {});
+    clearProviderErrors();
+    showProviderErrors(providerErrors.get(provider));
 
     // 删除旧配置
     currentConfig.providers.splice(index, 1);
@@ -787,20 +1417,107 @@ function addLog(message, type = 'info') {
 
     const logItem = document.createElement('div');
     logItem.className = 'log-item';
+    logItem.dataset.level = logTypeLevels[type] || 'info';
     logItem.innerHTML = `
         <span class="log-time">[${time}]</span>
         <span class="log-message log-${type}">${message}</span>
     `;
 
+    appendLogItem(logItem);
+}
+
+// 添加服务端推送的同步事件到日志，文件名等内容按文本显示
+function addEventLog(event) {
+    const logItem = document.createElement('div');
+    logItem.className = 'log-item';
+    logItem.dataset.level = event.level;
+    logItem.dataset.provider = event.provider || '';
+
+    const time = document.createElement('span');
+    time.className = 'log-time';
+    time.textContent = '[' + new Date(event.time).toLocaleTimeString('zh-CN', { hour12: false }) + ']';
+
+    const parts = [];
+    if (event.provider) parts.push('[' + event.provider + ']');
+    parts.push(eventKindLabels[event.kind] || event.kind);
+    parts.push(event.path);
+    if (event.message) parts.push('- ' + event.message);
+
+    const message = document.createElement('span');
+    message.className = 'log-message';
+    if (event.level === 'error') {
+        message.classList.add('log-error');
+    } else if (event.level === 'warn') {
+        message.classList.add('log-warning');
+    } else if (event.kind === 'done') {
+        message.classList.add('log-success');
+    }
+    message.textContent = parts.join(' ');
+
+    logItem.appendChild(time);
+    logItem.appendChild(message);
+
+    if (event.provider) addLogProviderOption(event.provider);
+    appendLogItem(logItem);
+
+    // 同步完成、跳过或失败后稍后刷新同步历史，连续的事件只刷新一次
+    if (['done', 'skipped', 'conflict', 'failed'].includes(event.kind)) {
+        clearTimeout(historyRefreshTimer);
+        historyRefreshTimer = setTimeout(() => loadHistory(), 1000);
+    }
+}
+
+// 追加日志条目，按当前筛选条件显示，并限制日志数量
+function appendLogItem(logItem) {
+    const container = document.getElementById('logContainer');
+    logItem.hidden = !matchLogFilter(logItem);
+
+    // 只有原本在底部时才自动滚动，方便翻看历史日志
+    const atBottom = container.scrollTop + container.clientHeight >= container.scrollHeight - 20;
     container.appendChild(logItem);
-    container.scrollTop = container.scrollHeight;
+    if (atBottom) {
+        container.scrollTop = container.scrollHeight;
+    }
 
-    // 限制日志数量
-    while (container.children.length > 100) {
+    while (container.children.length > maxLogItems) {
         container.removeChild(container.firstChild);
     }
 }
 
+// 日志条目是否符合筛选条件。界面自身的日志不属于任何云盘，只在「全部云盘」下显示
+function matchLogFilter(logItem) {
+    const provider = document.getElementById('logProviderFilter').value;
+    const level = document.getElementById('logLevelFilter').value;
+
+    if (provider && logItem.dataset.provider !== provider) return false;
+    if (level && (logItem.dataset.level || 'info') !== level) return false;
+    return true;
+}
+
+// 按筛选条件显示或隐藏日志
+function applyLogFilter() {
+    const container = document.getElementById('logContainer');
+    Array.from(container.children).forEach(item => {
+        item.hidden = !matchLogFilter(item);
+    });
+    container.scrollTop = container.scrollHeight;
+}
+
+// 添加云盘筛选选项（日志和同步历史共用）
+function addLogProviderOption(name) {
+    if (!name) return;
+
+    ['logProviderFilter', 'historyProviderFilter'].forEach(id => {
+        const select = document.getElementById(id);
+        if (Array.from(select.options).some(option => option.value === name)) return;
+
+        const option = document.createElement('option');
+        option.value = name;
+        option.textContent = name;
+        select.appendChild(option);
+    });
+}
+
 // 清空日志
 function clearLog() {
     const container = document.getElementById('logContainer');